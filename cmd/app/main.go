@@ -1,31 +1,202 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	httpDelivery "github.com/imbafff/product-warehouse-api/internal/delivery/http"
 	"github.com/imbafff/product-warehouse-api/internal/delivery/http/handler"
 	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
 	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/health"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/metrics"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/server"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/tracing"
 	productRepo "github.com/imbafff/product-warehouse-api/internal/repository/product"
+	"github.com/imbafff/product-warehouse-api/internal/repository/product/memory"
+	warehouseRepo "github.com/imbafff/product-warehouse-api/internal/repository/warehouse"
 	productUC "github.com/imbafff/product-warehouse-api/internal/usecase/product"
+	warehouseUC "github.com/imbafff/product-warehouse-api/internal/usecase/warehouse"
+	"github.com/imbafff/product-warehouse-api/migrations"
+	sqliteMigrations "github.com/imbafff/product-warehouse-api/migrations/sqlite"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	appLogger, err := logger.New(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(appLogger)
+	appLogger.Info("config loaded", "config", cfg.String())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(cfg.Args) > 0 && cfg.Args[0] == "migrate" {
+		if err := migrate(ctx, cfg, appLogger, cfg.Args[1:]); err != nil {
+			fatal(appLogger, "migrate failed", err)
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TraceExporter)
+	if err != nil {
+		fatal(appLogger, "failed to set up tracing", err)
+	}
+
+	appMetrics := metrics.New()
+	checker := health.New(cfg.HealthTimeout)
 
-	database, err := db.NewPostgresDB(cfg)
+	store, err := openStorage(ctx, cfg, appLogger, appMetrics, checker)
 	if err != nil {
-		log.Fatal("failed to connect to db:", err)
+		fatal(appLogger, "failed to open storage", err)
 	}
 
-	repo := productRepo.NewPostgresRepository(database)
-	usecase := productUC.New(repo)
-	h := handler.NewProductHandler(usecase)
+	usecase := productUC.New(store.products,
+		productUC.WithLogger(appLogger),
+		productUC.WithRecorder(appMetrics),
+		productUC.WithMaxBatchSize(cfg.BatchMaxSize))
 
-	r := httpDelivery.NewRouter(h)
+	sweeperDone := make(chan struct{})
+	if cfg.ReservationSweepInterval > 0 {
+		go func() {
+			defer close(sweeperDone)
+			usecase.SweepReservations(ctx, cfg.ReservationSweepInterval)
+		}()
+	} else {
+		close(sweeperDone)
+	}
+	h := handler.NewProductHandler(usecase, handler.WithRequireIfMatch(cfg.RequireIfMatch))
+
+	wh := handler.NewWarehouseHandler(warehouseUC.New(store.warehouses))
+
+	r := httpDelivery.NewRouter(h, wh,
+		httpDelivery.WithLogger(appLogger),
+		httpDelivery.WithRequestTimeout(cfg.RequestTimeout),
+		httpDelivery.WithMetrics(appMetrics),
+		httpDelivery.WithHealth(handler.NewHealthHandler(checker)),
+	)
+
+	srv := server.New(cfg, r, server.WithDrain(func() {
+		appLogger.Info("shutting down, readiness now failing", "drain_delay", cfg.ShutdownDrainDelay)
+		checker.Drain()
+	}))
+	appLogger.Info("listening", "addr", srv.Addr())
+
+	// A failure to listen or serve still releases what was opened, then
+	// exits non-zero so supervisors see the server did not run.
+	serveErr := srv.ListenAndServe(ctx)
+	stop()
+	<-sweeperDone
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	if err := shutdownTracing(flushCtx); err != nil {
+		appLogger.Error("failed to flush spans", "error", err)
+	}
+	cancel()
+
+	if err := store.close(); err != nil {
+		appLogger.Error("failed to close storage", "error", err)
+	}
 
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal("failed to run server:", err)
+	if serveErr != nil {
+		fatal(appLogger, "server failed", serveErr)
 	}
+	appLogger.Info("shut down")
+}
+
+type storage struct {
+	products   productUC.Repository
+	warehouses warehouseUC.Repository
+	close      func() error
+}
+
+// openStorage builds the repositories selected by STORAGE, along with the
+// metrics and readiness checks that only make sense for a database.
+func openStorage(ctx context.Context, cfg *config.Config, l *slog.Logger, m *metrics.Metrics, checker *health.Checker) (*storage, error) {
+	if cfg.Storage == "memory" {
+		repo, err := memory.New(memory.WithSnapshot(cfg.StorageSnapshot))
+		if err != nil {
+			return nil, fmt.Errorf("load snapshot: %w", err)
+		}
+		l.Warn("using in-memory storage, data is not shared between instances", "snapshot", cfg.StorageSnapshot)
+		return &storage{products: repo, warehouses: repo.Warehouses(), close: func() error { return nil }}, nil
+	}
+
+	database, migrator, err := openDB(ctx, cfg, l)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MigrateOnStart {
+		if err := migrator.Up(ctx); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+	}
+
+	m.RegisterDB(database)
+
+	checker.Add("database", health.Ping(database))
+	checker.Add("migrations", health.Migrations(migrator))
+	checker.Add("pool", health.Pool(database))
+
+	if cfg.Storage == "sqlite" {
+		return &storage{
+			products:   productRepo.NewSQLiteRepository(database, productRepo.WithLogger(l)),
+			warehouses: warehouseRepo.NewSQLiteRepository(database),
+			close:      database.Close,
+		}, nil
+	}
+
+	return &storage{
+		products:   productRepo.NewPostgresRepository(database, productRepo.WithLogger(l)),
+		warehouses: warehouseRepo.NewPostgresRepository(database),
+		close:      database.Close,
+	}, nil
+}
+
+// openDB connects to the database selected by STORAGE and loads the
+// migrations written for its dialect.
+func openDB(ctx context.Context, cfg *config.Config, l *slog.Logger) (*sql.DB, *db.Migrator, error) {
+	var (
+		database *sql.DB
+		err      error
+	)
+	fsys, dialect := fs.FS(migrations.FS), db.Postgres
+	if cfg.Storage == "sqlite" {
+		fsys, dialect = sqliteMigrations.FS, db.SQLite
+		database, err = db.NewSQLiteDB(ctx, cfg)
+	} else {
+		database, err = db.NewPostgresDB(ctx, cfg, db.WithLogger(l))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to db: %w", err)
+	}
+
+	migrator, err := db.NewMigrator(database, fsys, db.WithDialect(dialect))
+	if err != nil {
+		database.Close()
+		return nil, nil, fmt.Errorf("load migrations: %w", err)
+	}
+
+	return database, migrator, nil
+}
+
+func fatal(l *slog.Logger, msg string, err error) {
+	l.Error(msg, "error", err)
+	os.Exit(1)
 }
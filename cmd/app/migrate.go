@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+)
+
+const migrateUsage = "usage: app migrate up | down | status | goto N"
+
+// migrate connects to the database and runs a migrate subcommand.
+func migrate(ctx context.Context, cfg *config.Config, l *slog.Logger, args []string) error {
+	if cfg.Storage == "memory" {
+		return fmt.Errorf("migrate needs STORAGE=postgres or sqlite, got %q", cfg.Storage)
+	}
+
+	database, m, err := openDB(ctx, cfg, l)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	return runMigrate(ctx, m, args, os.Stdout)
+}
+
+// runMigrate handles `app migrate <command>`.
+func runMigrate(ctx context.Context, m *db.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		return m.Down(ctx)
+	case "goto":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return m.Goto(ctx, version)
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%06d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+)
+
+type batchRequest struct {
+	// Mode is atomic, the default, or best_effort.
+	Mode       string                  `json:"mode"`
+	Operations []batchOperationRequest `json:"operations"`
+}
+
+type batchOperationRequest struct {
+	Op      entity.BatchAction `json:"op"`
+	ID      int64              `json:"id"`
+	Version int64              `json:"version"`
+	Product *entity.Product    `json:"product"`
+}
+
+// batchResult reports one operation with the status it would have had as a
+// request of its own.
+type batchResult struct {
+	Status  int                 `json:"status"`
+	ID      int64               `json:"id,omitempty"`
+	Version int64               `json:"version,omitempty"`
+	Detail  string              `json:"detail,omitempty"`
+	Errors  []entity.FieldError `json:"errors,omitempty"`
+}
+
+// CustomMethod serves POST /products:<method>. Gin cannot register a literal
+// colon after a path segment, so the route captures everything after
+// /products and the method is picked here.
+func (h *ProductHandler) CustomMethod(c *gin.Context) {
+	switch c.Param("method") {
+	case ":batch":
+		h.Batch(c)
+	default:
+		writeProblem(c, http.StatusNotFound, "no such method")
+	}
+}
+
+func (h *ProductHandler) Batch(c *gin.Context) {
+	var input batchRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		writeProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var atomic bool
+	switch input.Mode {
+	case "", batchAtomic:
+		atomic = true
+	case batchBestEffort:
+	default:
+		writeError(c, entity.NewValidationError("mode", "unsupported", "mode must be atomic or best_effort"))
+		return
+	}
+
+	ops := make([]*entity.BatchOperation, len(input.Operations))
+	for i, op := range input.Operations {
+		ops[i] = &entity.BatchOperation{Action: op.Op, ID: op.ID, Version: op.Version, Product: op.Product}
+	}
+
+	if err := h.usecase.Batch(c.Request.Context(), ops, atomic); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	results := make([]batchResult, len(ops))
+	for i, op := range ops {
+		results[i] = newBatchResult(c, op)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+func newBatchResult(c *gin.Context, op *entity.BatchOperation) batchResult {
+	if op.Err != nil {
+		status, detail, fields := describeError(c, op.Err)
+		return batchResult{Status: status, ID: op.ID, Detail: detail, Errors: fields}
+	}
+
+	switch op.Action {
+	case entity.BatchCreate:
+		return batchResult{Status: http.StatusCreated, ID: op.Product.ID, Version: op.Product.Version}
+	case entity.BatchUpdate:
+		return batchResult{Status: http.StatusOK, ID: op.ID, Version: op.Product.Version}
+	default:
+		return batchResult{Status: http.StatusNoContent, ID: op.ID}
+	}
+}
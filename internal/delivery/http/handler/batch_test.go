@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+// Тесты для пакетных операций
+func TestBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name     string
+		method   string
+		body     string
+		status   int
+		statuses []int
+	}{
+		{
+			name:     "atomic",
+			method:   ":batch",
+			body:     `{"operations": [{"op": "create", "product": {"name": "New", "price": 5}}, {"op": "update", "id": 1, "version": 1, "product": {"name": "Renamed", "price": 5}}]}`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusCreated, http.StatusOK},
+		},
+		{
+			name:   "atomic failure",
+			method: ":batch",
+			body:   `{"operations": [{"op": "create", "product": {"name": "New", "price": 5}}, {"op": "delete", "id": 42}]}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:     "best effort",
+			method:   ":batch",
+			body:     `{"mode": "best_effort", "operations": [{"op": "delete", "id": 42}, {"op": "create", "product": {"price": 5}}, {"op": "delete", "id": 1}]}`,
+			status:   http.StatusOK,
+			statuses: []int{http.StatusNotFound, http.StatusBadRequest, http.StatusNoContent},
+		},
+		{name: "unknown mode", method: ":batch", body: `{"mode": "partial", "operations": []}`, status: http.StatusBadRequest},
+		{name: "invalid json", method: ":batch", body: `{"operations": {}}`, status: http.StatusBadRequest},
+		{name: "unknown method", method: ":purge", body: `{}`, status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUC := NewMockUseCase()
+			handler := NewProductHandler(mockUC)
+			mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099})
+
+			req, _ := http.NewRequest("POST", "/products"+tc.method, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = append(c.Params, gin.Param{Key: "method", Value: tc.method})
+
+			handler.CustomMethod(c)
+
+			if w.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.statuses == nil {
+				return
+			}
+
+			var response struct {
+				Results []batchResult `json:"results"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			if len(response.Results) != len(tc.statuses) {
+				t.Fatalf("Expected %d results, got %s", len(tc.statuses), w.Body.String())
+			}
+			for i, r := range response.Results {
+				if r.Status != tc.statuses[i] {
+					t.Errorf("Result %d: expected status %d, got %+v", i, tc.statuses[i], r)
+				}
+			}
+		})
+	}
+}
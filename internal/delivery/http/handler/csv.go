@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/usecase/product"
+
+	"github.com/gin-gonic/gin"
+)
+
+const csvContentType = "text/csv; charset=utf-8"
+
+const (
+	// exportFlushRows is how many rows an export writes between flushes.
+	exportFlushRows = 500
+	// exportWriteTimeout is how long the client gets to take each flush. It
+	// replaces the server's write timeout, which is sized for one ordinary
+	// response rather than a whole catalog.
+	exportWriteTimeout = time.Minute
+)
+
+// Export streams the products matching the filters and sort of GET
+// /products as CSV, one column per product.ImportFields, so the file can be
+// edited and imported back. The route runs without the request timeout.
+// The status goes out with the first row; a failure after that cuts the
+// connection, so the client cannot take a partial file for a complete one.
+func (h *ProductHandler) Export(c *gin.Context) {
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		writeError(c, entity.NewValidationError("format", "unsupported", "format must be csv"))
+		return
+	}
+
+	q, err := parseProductQuery(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rc := http.NewResponseController(c.Writer)
+	w := csv.NewWriter(c.Writer)
+	rows := 0
+	started := false
+
+	flush := func() error {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		// Not every writer has a deadline to extend, the test recorder has
+		// none.
+		_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		return rc.Flush()
+	}
+	start := func() error {
+		started = true
+		c.Header("Content-Type", csvContentType)
+		c.Header("Content-Disposition", `attachment; filename="products.csv"`)
+		c.Status(http.StatusOK)
+		_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		return w.Write(product.ImportFields)
+	}
+
+	err = h.usecase.Export(c.Request.Context(), q, func(p *entity.Product) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := w.Write(csvRecord(p)); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = flush()
+	}
+
+	switch {
+	case err == nil:
+	case !started:
+		writeError(c, err)
+	default:
+		_ = c.Error(err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func csvRecord(p *entity.Product) []string {
+	record := []string{
+		strconv.FormatInt(p.ID, 10),
+		p.SKU,
+		p.Name,
+		p.Description,
+		p.Price.String(),
+		p.Currency,
+		strconv.Itoa(p.Quantity),
+		strings.Join(p.Barcodes, product.BarcodeSeparator),
+	}
+	for i, cell := range record {
+		record[i] = escapeCSVCell(cell)
+	}
+	return record
+}
+
+// escapeCSVCell guards against CSV injection: a spreadsheet runs a cell
+// starting with one of "=+-@" as a formula, so such a cell is prefixed with
+// "'". A cell already starting with "'" before one of them, or before
+// another "'", gets one more, so unescapeCSVCell undoes exactly this.
+func escapeCSVCell(cell string) string {
+	if needsCSVEscape(cell) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// unescapeCSVCell strips the prefix added by escapeCSVCell.
+func unescapeCSVCell(cell string) string {
+	if rest, ok := strings.CutPrefix(cell, "'"); ok && needsCSVEscape(rest) {
+		return rest
+	}
+	return cell
+}
+
+func needsCSVEscape(cell string) bool {
+	if cell == "" {
+		return false
+	}
+	if strings.ContainsRune("=+-@", rune(cell[0])) {
+		return true
+	}
+	return len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune("=+-@'", rune(cell[1]))
+}
+
+type importResponse struct {
+	DryRun  bool             `json:"dry_run"`
+	Rows    int              `json:"rows"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Errors  []importRowError `json:"errors"`
+}
+
+type importRowError struct {
+	Row    int                 `json:"row"`
+	Status int                 `json:"status"`
+	Detail string              `json:"detail,omitempty"`
+	Errors []entity.FieldError `json:"errors,omitempty"`
+}
+
+// Import takes a multipart upload with the CSV in the file field. A column
+// sets the product field it is named after; the optional mapping field, a
+// JSON object from column to field, renames columns or, mapped to "",
+// ignores them. The key and dry_run query parameters become the
+// entity.ImportOptions.
+func (h *ProductHandler) Import(c *gin.Context) {
+	opts := entity.ImportOptions{Key: entity.ImportKey(c.DefaultQuery("key", string(entity.ImportBySKU)))}
+	if !opts.Key.Valid() {
+		writeError(c, entity.NewValidationError("key", "unsupported", "key must be sku or id"))
+		return
+	}
+	if raw := c.Query("dry_run"); raw != "" {
+		var err error
+		if opts.DryRun, err = strconv.ParseBool(raw); err != nil {
+			writeError(c, entity.NewValidationError("dry_run", "invalid", "invalid dry_run"))
+			return
+		}
+	}
+
+	var mapping map[string]string
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			writeError(c, entity.NewValidationError("mapping", "invalid", "mapping must be a JSON object from column to field"))
+			return
+		}
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		writeError(c, entity.NewValidationError("file", "required", "a CSV file is required"))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	defer file.Close()
+
+	src, err := newCSVSource(file, mapping, opts.Key)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	report, err := h.usecase.Import(c.Request.Context(), src, opts)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	response := importResponse{
+		DryRun:  opts.DryRun,
+		Rows:    report.Rows,
+		Created: report.Created,
+		Updated: report.Updated,
+		Failed:  report.Failed,
+		Errors:  make([]importRowError, len(report.Errors)),
+	}
+	for i, e := range report.Errors {
+		status, detail, fields := describeError(c, e.Err)
+		response.Errors[i] = importRowError{Row: e.Row, Status: status, Detail: detail, Errors: fields}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// csvSource reads the rows of an import file. It is a product.ImportSource.
+type csvSource struct {
+	r *csv.Reader
+	// fields holds the field each column sets, "" for the ignored ones.
+	fields []string
+	row    int
+}
+
+func newCSVSource(r io.Reader, mapping map[string]string, key entity.ImportKey) (*csvSource, error) {
+	known := make(map[string]bool, len(product.ImportFields))
+	for _, f := range product.ImportFields {
+		known[f] = true
+	}
+
+	// Headers are matched regardless of case and surrounding spaces.
+	renamed := make(map[string]string, len(mapping))
+	for column, field := range mapping {
+		if field != "" && !known[field] {
+			return nil, entity.NewValidationError("mapping", "unknown_field", fmt.Sprintf("unknown field %q", field))
+		}
+		renamed[strings.ToLower(strings.TrimSpace(column))] = field
+	}
+
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, entity.NewValidationError("file", "empty", "the file is empty")
+	}
+	if err != nil {
+		return nil, entity.NewValidationError("file", "invalid", err.Error())
+	}
+	// Spreadsheets often save UTF-8 with a byte order mark.
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	s := &csvSource{r: cr, fields: make([]string, len(header)), row: 1}
+	columns := make(map[string]string, len(header))
+	for i, column := range header {
+		name := strings.ToLower(strings.TrimSpace(column))
+		field, ok := renamed[name]
+		if ok {
+			delete(renamed, name)
+		} else if known[name] {
+			field = name
+		}
+		if field == "" {
+			continue
+		}
+
+		if other, taken := columns[field]; taken {
+			return nil, entity.NewValidationError("header", "duplicate_field", fmt.Sprintf("columns %q and %q both set %s", other, column, field))
+		}
+		columns[field] = column
+		s.fields[i] = field
+	}
+
+	if len(renamed) > 0 {
+		column := slices.Sorted(maps.Keys(renamed))[0]
+		return nil, entity.NewValidationError("mapping", "unknown_column", fmt.Sprintf("the file has no column %q", column))
+	}
+	if _, ok := columns[string(key)]; !ok && key == entity.ImportBySKU {
+		return nil, entity.NewValidationError("header", "missing_key", "the file has no sku column to match products by")
+	}
+
+	return s, nil
+}
+
+func (s *csvSource) Next() (*entity.ImportRow, error) {
+	record, err := s.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	s.row++
+
+	row := &entity.ImportRow{Number: s.row}
+	if errors.Is(err, csv.ErrFieldCount) {
+		row.Err = entity.NewValidationError("row", "field_count", fmt.Sprintf("the row has %d columns, the header %d", len(record), len(s.fields)))
+		return row, nil
+	}
+	if err != nil {
+		return nil, entity.NewValidationError("file", "invalid", err.Error())
+	}
+
+	row.Values = make(map[string]string, len(record))
+	for i, field := range s.fields {
+		if field != "" {
+			row.Values[field] = unescapeCSVCell(record[i])
+		}
+	}
+	return row, nil
+}
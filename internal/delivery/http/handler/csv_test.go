@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imbafff/product-warehouse-api/internal/delivery/http/middleware"
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
+)
+
+// Тесты для экспорта и импорта CSV
+func TestExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+	mockUC.Create(context.Background(), &entity.Product{SKU: "MUG-01", Name: "Mug, large", Price: 1099, Currency: "USD", Quantity: 5, Barcodes: []string{"4006381333931", "036000291452"}})
+	mockUC.Create(context.Background(), &entity.Product{Name: "Plate", Description: "=1+1", Price: 250, Currency: "EUR"})
+
+	req, _ := http.NewRequest("GET", "/products/export?format=csv&sort=-price&name=a", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Export(c)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != csvContentType {
+		t.Fatalf("Expected a CSV, got %d %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	want := "id,sku,name,description,price,currency,quantity,barcodes\n" +
+		"1,MUG-01,\"Mug, large\",,10.99,USD,5,4006381333931;036000291452\n" +
+		"2,,Plate,'=1+1,2.50,EUR,0,\n"
+	if w.Body.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, w.Body.String())
+	}
+	if mockUC.lastQuery.SortField != "price" || !mockUC.lastQuery.SortDesc || mockUC.lastQuery.Filter.NameContains != "a" {
+		t.Errorf("Expected the listing filters to be passed on, got %+v", mockUC.lastQuery)
+	}
+
+	req, _ = http.NewRequest("GET", "/products/export?format=xlsx", nil)
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Export(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown format, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestEscapeCSVCell(t *testing.T) {
+	tests := []struct {
+		cell    string
+		escaped string
+	}{
+		{"Mug", "Mug"},
+		{"", ""},
+		{`=HYPERLINK("http://x")`, `'=HYPERLINK("http://x")`},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"'=1", "''=1"},
+		{"''", "'''"},
+		{"'quoted", "'quoted"},
+		{"'", "'"},
+		{"a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeCSVCell(tt.cell); got != tt.escaped {
+			t.Errorf("escapeCSVCell(%q) = %q, want %q", tt.cell, got, tt.escaped)
+		}
+		if got := unescapeCSVCell(tt.escaped); got != tt.cell {
+			t.Errorf("unescapeCSVCell(%q) = %q, want %q", tt.escaped, got, tt.cell)
+		}
+	}
+}
+
+func TestExport_Failure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	mockUC.exportErr = errors.New("connection lost")
+
+	r := gin.New()
+	r.Use(middleware.Recovery(logger.Discard()))
+	r.GET("/products/export", NewProductHandler(mockUC).Export)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// Nothing sent yet: the failure is answered as usual.
+	resp, err := http.Get(srv.URL + "/products/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	// Once rows are written, the connection is cut instead.
+	mockUC.Create(context.Background(), &entity.Product{Name: "Mug", Price: 1099, Currency: "USD"})
+	resp, err = http.Get(srv.URL + "/products/export")
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("Expected a failed export to break the transfer")
+	}
+}
+
+func importRequest(t *testing.T, query, file, mapping string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if mapping != "" {
+		mw.WriteField("mapping", mapping)
+	}
+	if file != "" {
+		fw, _ := mw.CreateFormFile("file", "products.csv")
+		fw.Write([]byte(file))
+	}
+	mw.Close()
+
+	req, _ := http.NewRequest("POST", "/products/import"+query, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	file := "\ufeffItem,Cost,SKU,Notes,Color\n" +
+		"'=Mug,10.99,MUG-01,fragile,red\n" +
+		"Plate,2.50\n" +
+		"\"Bowl, deep\",4,BOWL-1,,blue\n"
+	req := importRequest(t, "?dry_run=true", file, `{"item": "name", "Cost": "price", "Notes": ""}`)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Import(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response importResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if !response.DryRun || response.Rows != 3 || response.Created != 2 || response.Failed != 1 {
+		t.Errorf("Unexpected response: %s", w.Body.String())
+	}
+	if len(response.Errors) != 1 || response.Errors[0].Row != 3 || response.Errors[0].Status != http.StatusBadRequest {
+		t.Errorf("Expected row 3 to fail with 400, got %+v", response.Errors)
+	}
+	if mockUC.lastImport != (entity.ImportOptions{Key: entity.ImportBySKU, DryRun: true}) {
+		t.Errorf("Unexpected options: %+v", mockUC.lastImport)
+	}
+
+	first := mockUC.imported[0]
+	if first.Number != 2 || len(first.Values) != 3 || first.Values["name"] != "=Mug" || first.Values["price"] != "10.99" || first.Values["sku"] != "MUG-01" {
+		t.Errorf("Unexpected first row: %+v", first)
+	}
+	if last := mockUC.imported[2]; last.Number != 4 || last.Values["name"] != "Bowl, deep" {
+		t.Errorf("Unexpected last row: %+v", last)
+	}
+}
+
+func TestImport_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name    string
+		query   string
+		file    string
+		mapping string
+		field   string
+	}{
+		{name: "no file", field: "file"},
+		{name: "empty file", file: "\n", field: "file"},
+		{name: "unknown key", query: "?key=name", file: "sku\nA\n", field: "key"},
+		{name: "invalid dry_run", query: "?dry_run=maybe", file: "sku\nA\n", field: "dry_run"},
+		{name: "invalid mapping", file: "sku\nA\n", mapping: `["sku"]`, field: "mapping"},
+		{name: "unknown field", file: "sku,color\nA,red\n", mapping: `{"color": "colour"}`, field: "mapping"},
+		{name: "unknown column", file: "sku\nA\n", mapping: `{"cost": "price"}`, field: "mapping"},
+		{name: "two columns for one field", file: "sku,price,cost\nA,1,2\n", mapping: `{"cost": "price"}`, field: "header"},
+		{name: "no sku column", file: "name\nMug\n", field: "header"},
+		{name: "broken quote", file: "sku,name\nA,\"Mug\n", field: "file"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewProductHandler(NewMockUseCase())
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = importRequest(t, tc.query, tc.file, tc.mapping)
+
+			handler.Import(c)
+
+			var p problem
+			json.Unmarshal(w.Body.Bytes(), &p)
+			if w.Code != http.StatusBadRequest || len(p.Errors) == 0 || p.Errors[0].Field != tc.field {
+				t.Errorf("Expected 400 on %s, got %d: %s", tc.field, w.Code, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+
+	// A file matched by id needs no key column.
+	mockUC := NewMockUseCase()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = importRequest(t, "?key=id", "name,price\nMug,1\n", "")
+
+	NewProductHandler(mockUC).Import(c)
+
+	if w.Code != http.StatusOK || mockUC.lastImport.Key != entity.ImportByID {
+		t.Errorf("Expected an import by id, got %d: %s", w.Code, w.Body.String())
+	}
+}
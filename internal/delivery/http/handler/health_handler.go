@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Live answers as long as the process can serve HTTP. It checks no
+// dependencies, so a database outage does not get the process restarted.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Ready reports each dependency check and answers 503 when any of them
+// fails or the service is shutting down.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == health.StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тесты для HealthHandler
+func TestHealthHandler_Ready(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	checker := health.New(time.Second)
+	checker.Add("database", func(context.Context) health.Result {
+		return health.Result{Status: health.StatusOK}
+	})
+
+	h := NewHealthHandler(checker)
+	r := gin.New()
+	r.GET("/healthz", h.Live)
+	r.GET("/readyz", h.Ready)
+
+	get := func(path string) (*httptest.ResponseRecorder, health.Report) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report health.Report
+		json.Unmarshal(w.Body.Bytes(), &report)
+		return w, report
+	}
+
+	w, report := get("/readyz")
+	if w.Code != http.StatusOK || report.Checks["database"].Status != health.StatusOK {
+		t.Errorf("Expected 200 with a database check, got %d %s", w.Code, w.Body.String())
+	}
+
+	checker.Drain()
+
+	w, report = get("/readyz")
+	if w.Code != http.StatusServiceUnavailable || report.Checks["shutdown"].Status != health.StatusFail {
+		t.Errorf("Expected 503 while shutting down, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w, _ := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("Expected liveness to stay 200 while shutting down, got %d", w.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+var errInvalidID = entity.NewValidationError("id", "invalid", "invalid id")
+
+// problem is an RFC 7807 problem document.
+type problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Errors   []entity.FieldError `json:"errors,omitempty"`
+	// RequestID lets a client quote the failing call when reporting it.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeError maps an error from the usecase layer to its HTTP status and
+// writes it as a problem document.
+func writeError(c *gin.Context, err error) {
+	status, detail, fields := describeError(c, err)
+	writeProblem(c, status, detail, fields...)
+}
+
+// describeError gives the HTTP status, detail and field errors err is
+// reported with. Errors of unknown kind are reported as 500 without leaking
+// their message. Once the request deadline has passed the driver error is
+// usually a cancelled statement rather than context.DeadlineExceeded, so the
+// request context is checked as well.
+func describeError(c *gin.Context, err error) (int, string, []entity.FieldError) {
+	var verr *entity.ValidationError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "request timed out", nil
+	case errors.As(err, &verr):
+		return http.StatusBadRequest, verr.Error(), verr.Fields
+	case errors.Is(err, entity.ErrNotFound):
+		return http.StatusNotFound, err.Error(), nil
+	case errors.Is(err, entity.ErrConflict):
+		return http.StatusConflict, err.Error(), nil
+	case errors.Is(err, entity.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, err.Error(), nil
+	default:
+		return http.StatusInternalServerError, "internal server error", nil
+	}
+}
+
+func writeProblem(c *gin.Context, status int, detail string, fields ...entity.FieldError) {
+	c.Header("Content-Type", problemContentType)
+	c.JSON(status, problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Errors:   fields,
+
+		RequestID: requestid.FromContext(c.Request.Context()),
+	})
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+)
+
+func TestWriteError_StatusMapping(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+	}{
+		{name: "validation", err: entity.NewValidationError("name", "required", "name is required"), status: http.StatusBadRequest, detail: "name is required"},
+		{name: "not found", err: entity.ErrProductNotFound, status: http.StatusNotFound, detail: "product not found"},
+		{name: "wrapped not found", err: fmt.Errorf("load: %w", entity.ErrLocationNotFound), status: http.StatusNotFound, detail: "load: location not found"},
+		{name: "conflict", err: entity.ErrInsufficientStock, status: http.StatusConflict, detail: "insufficient stock"},
+		{name: "duplicate sku", err: entity.ErrSKUTaken, status: http.StatusConflict, detail: "sku already exists"},
+		{name: "precondition", err: entity.ErrVersionConflict, status: http.StatusPreconditionFailed, detail: "version conflict"},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), status: http.StatusGatewayTimeout, detail: "request timed out"},
+		{name: "unknown", err: errors.New("pq: connection refused"), status: http.StatusInternalServerError, detail: "internal server error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest("GET", "/products/1", nil)
+
+			writeError(c, tc.err)
+
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, w.Code)
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+				t.Errorf("Expected content type %s, got %s", problemContentType, ct)
+			}
+
+			var p problem
+			json.Unmarshal(w.Body.Bytes(), &p)
+
+			if p.Status != tc.status || p.Detail != tc.detail || p.Instance != "/products/1" {
+				t.Errorf("Unexpected problem document: %+v", p)
+			}
+		})
+	}
+}
+
+func TestWriteError_ValidationFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/products?limit=x", nil)
+
+	writeError(c, entity.NewValidationError("limit", "invalid", "invalid limit"))
+
+	var p problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+
+	if len(p.Errors) != 1 || p.Errors[0].Field != "limit" {
+		t.Errorf("Expected field error for 'limit', got %+v", p.Errors)
+	}
+}
+
+func TestWriteError_AllViolations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/products", nil)
+
+	var v entity.Violations
+	v.Add("name", "required", "name is required")
+	v.Add("price", "too_many_decimals", "price must have at most 2 decimal places")
+
+	writeError(c, v.Err())
+
+	var body struct {
+		Errors []map[string]string `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	if len(body.Errors) != 2 {
+		t.Fatalf("Expected 2 field errors, got %+v", body.Errors)
+	}
+
+	if body.Errors[0]["code"] != "required" || body.Errors[1]["code"] != "too_many_decimals" {
+		t.Errorf("Expected codes in problem document, got %+v", body.Errors)
+	}
+}
+
+func TestWriteError_RequestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequestWithContext(ctx, "GET", "/products", nil)
+
+	// pq сообщает об отменённом запросе своей ошибкой, а не DeadlineExceeded
+	writeError(c, errors.New("pq: canceling statement due to user request"))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status %d, got %d", http.StatusGatewayTimeout, w.Code)
+	}
+}
+
+func TestWriteError_RequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	ctx := requestid.NewContext(context.Background(), "abc-123")
+	c.Request, _ = http.NewRequestWithContext(ctx, "GET", "/products/1", nil)
+
+	writeError(c, entity.ErrProductNotFound)
+
+	var p problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+
+	if p.RequestID != "abc-123" {
+		t.Errorf("Expected request_id in problem document, got %+v", p)
+	}
+}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/imbafff/product-warehouse-api/internal/entity"
 	"github.com/imbafff/product-warehouse-api/internal/usecase/product"
@@ -11,24 +14,39 @@ import (
 )
 
 type ProductHandler struct {
-	usecase product.UseCase
+	usecase        product.UseCase
+	requireIfMatch bool
 }
 
-func NewProductHandler(uc product.UseCase) *ProductHandler {
-	return &ProductHandler{usecase: uc}
+type Option func(*ProductHandler)
+
+// WithRequireIfMatch makes PUT and DELETE on a product fail with 428 unless
+// the request carries an If-Match header.
+func WithRequireIfMatch(required bool) Option {
+	return func(h *ProductHandler) {
+		h.requireIfMatch = required
+	}
+}
+
+func NewProductHandler(uc product.UseCase, opts ...Option) *ProductHandler {
+	h := &ProductHandler{usecase: uc}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *ProductHandler) Create(c *gin.Context) {
 	var input entity.Product
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeProblem(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	id, err := h.usecase.Create(&input)
+	id, err := h.usecase.Create(c.Request.Context(), &input)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
@@ -38,13 +56,47 @@ func (h *ProductHandler) Create(c *gin.Context) {
 func (h *ProductHandler) GetByID(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		writeError(c, errInvalidID)
 		return
 	}
 
-	product, err := h.usecase.GetByID(id)
+	product, err := h.usecase.GetByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		writeError(c, err)
+		return
+	}
+
+	writeProduct(c, product)
+}
+
+func (h *ProductHandler) GetBySKU(c *gin.Context) {
+	product, err := h.usecase.GetBySKU(c.Request.Context(), c.Param("sku"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeProduct(c, product)
+}
+
+func (h *ProductHandler) GetByBarcode(c *gin.Context) {
+	product, err := h.usecase.GetByBarcode(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeProduct(c, product)
+}
+
+// writeProduct responds with the product and its ETag, or with 304 when the
+// client already has this version.
+func writeProduct(c *gin.Context, product *entity.Product) {
+	etag := formatETag(product.Version)
+	c.Header("ETag", etag)
+
+	if noneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -54,45 +106,390 @@ func (h *ProductHandler) GetByID(c *gin.Context) {
 func (h *ProductHandler) Update(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		writeError(c, errInvalidID)
+		return
+	}
+
+	version, ok := h.precondition(c)
+	if !ok {
 		return
 	}
 
 	var input entity.Product
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeProblem(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	input.Version = version
 
-	if err := h.usecase.Update(id, &input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.usecase.Update(c.Request.Context(), id, &input); err != nil {
+		writeError(c, err)
 		return
 	}
 
+	c.Header("ETag", formatETag(input.Version))
 	c.Status(http.StatusOK)
 }
 
 func (h *ProductHandler) Delete(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		writeError(c, errInvalidID)
+		return
+	}
+
+	version, ok := h.precondition(c)
+	if !ok {
 		return
 	}
 
-	if err := h.usecase.Delete(id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	if err := h.usecase.Delete(c.Request.Context(), id, version); err != nil {
+		writeError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// precondition turns the If-Match header into the version the client expects
+// to change. Zero means "any version". It writes the error response itself
+// and returns false when the request must not proceed.
+func (h *ProductHandler) precondition(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		if h.requireIfMatch {
+			writeProblem(c, http.StatusPreconditionRequired, "If-Match header is required")
+			return 0, false
+		}
+		return 0, true
+	}
+
+	if header == "*" {
+		return 0, true
+	}
+
+	version, ok := parseETag(header)
+	if !ok {
+		writeError(c, entity.ErrVersionConflict)
+		return 0, false
+	}
+
+	return version, true
+}
+
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseETag accepts a single strong entity tag as produced by formatETag.
+func parseETag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// noneMatch reports whether an If-None-Match header matches etag using the
+// weak comparison required for GET.
+func noneMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *ProductHandler) GetAll(c *gin.Context) {
-	products, err := h.usecase.GetAll()
+	query, err := parseProductQuery(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	page, err := h.usecase.GetAll(c.Request.Context(), query)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	resp := gin.H{"items": page.Items}
+	if page.Next != nil {
+		resp["next_cursor"] = page.Next.Encode()
+	}
+	if page.Total != nil {
+		resp["total"] = *page.Total
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type movementRequest struct {
+	Type       entity.MovementType `json:"type"`
+	LocationID *int64              `json:"location_id"`
+	Delta      int                 `json:"delta"`
+	Reason     string              `json:"reason"`
+	Reference  string              `json:"reference"`
+}
+
+func (h *ProductHandler) AddMovement(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	var input movementRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		writeProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	movement := &entity.StockMovement{
+		LocationID: input.LocationID,
+		Type:       input.Type,
+		Delta:      input.Delta,
+		Reason:     input.Reason,
+		Reference:  input.Reference,
+	}
+
+	if _, err := h.usecase.AddMovement(c.Request.Context(), id, movement); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, movement)
+}
+
+type stockChangeRequest struct {
+	Quantity  int    `json:"quantity" binding:"required"`
+	Reason    string `json:"reason"`
+	Reference string `json:"reference"`
+}
+
+func (h *ProductHandler) IncreaseStock(c *gin.Context) {
+	h.changeStock(c, h.usecase.IncreaseStock)
+}
+
+func (h *ProductHandler) DecreaseStock(c *gin.Context) {
+	h.changeStock(c, h.usecase.DecreaseStock)
+}
+
+func (h *ProductHandler) changeStock(c *gin.Context, change func(context.Context, int64, int, string, string) (*entity.StockMovement, error)) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	var input stockChangeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		writeProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	movement, err := change(c.Request.Context(), id, input.Quantity, input.Reason, input.Reference)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, movement)
+}
+
+func (h *ProductHandler) GetMovements(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	movements, err := h.usecase.GetMovements(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, movements)
+}
+
+func (h *ProductHandler) GetStock(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	warehouseID, err := int64ParamPtr(c, "warehouse_id")
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	stock, err := h.usecase.GetStock(c.Request.Context(), id, warehouseID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stock)
+}
+
+type reservationRequest struct {
+	Quantity   int    `json:"quantity" binding:"required"`
+	TTLSeconds int    `json:"ttl_seconds" binding:"required"`
+	Reference  string `json:"reference"`
+}
+
+func (h *ProductHandler) Reserve(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	var input reservationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		writeProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ttl := time.Duration(input.TTLSeconds) * time.Second
+	reservation, err := h.usecase.Reserve(c.Request.Context(), id, input.Quantity, ttl, input.Reference)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+func (h *ProductHandler) GetReservation(c *gin.Context) {
+	h.reservation(c, h.usecase.GetReservation)
+}
+
+func (h *ProductHandler) ConfirmReservation(c *gin.Context) {
+	h.reservation(c, h.usecase.ConfirmReservation)
+}
+
+func (h *ProductHandler) ReleaseReservation(c *gin.Context) {
+	h.reservation(c, h.usecase.ReleaseReservation)
+}
+
+func (h *ProductHandler) reservation(c *gin.Context, call func(context.Context, int64) (*entity.Reservation, error)) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	reservation, err := call(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+func parseProductQuery(c *gin.Context) (entity.ProductQuery, error) {
+	var q entity.ProductQuery
+	var err error
+
+	if q.Limit, err = intParam(c, "limit"); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intParam(c, "offset"); err != nil {
+		return q, err
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		q.SortDesc = strings.HasPrefix(sort, "-")
+		q.SortField = strings.TrimPrefix(sort, "-")
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		if q.After, err = entity.DecodeCursor(cursor); err != nil {
+			return q, err
+		}
+	}
+
+	if total := c.Query("with_total"); total != "" {
+		if q.WithTotal, err = strconv.ParseBool(total); err != nil {
+			return q, entity.NewValidationError("with_total", "invalid", "invalid with_total")
+		}
+	}
+
+	q.Filter.NameContains = c.Query("name")
+	if q.Filter.MinPrice, err = moneyParamPtr(c, "min_price"); err != nil {
+		return q, err
+	}
+	if q.Filter.MaxPrice, err = moneyParamPtr(c, "max_price"); err != nil {
+		return q, err
+	}
+	if q.Filter.MinQuantity, err = intParamPtr(c, "min_quantity"); err != nil {
+		return q, err
+	}
+	if q.Filter.MaxQuantity, err = intParamPtr(c, "max_quantity"); err != nil {
+		return q, err
+	}
+	if q.Filter.WarehouseID, err = int64ParamPtr(c, "warehouse_id"); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+func intParam(c *gin.Context, name string) (int, error) {
+	v, err := intParamPtr(c, name)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return *v, nil
+}
+
+func intParamPtr(c *gin.Context, name string) (*int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, entity.NewValidationError(name, "invalid", "invalid "+name)
+	}
+	return &v, nil
+}
+
+func int64ParamPtr(c *gin.Context, name string) (*int64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, entity.NewValidationError(name, "invalid", "invalid "+name)
+	}
+	return &v, nil
+}
+
+func moneyParamPtr(c *gin.Context, name string) (*entity.Money, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	v, err := entity.ParseMoney(raw)
+	if err != nil {
+		return nil, entity.NewValidationError(name, "invalid", "invalid "+name)
+	}
+	return &v, nil
 }
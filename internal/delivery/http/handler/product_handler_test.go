@@ -2,99 +2,305 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/usecase/product"
 )
 
 // Mock UseCase для тестирования handler
 type MockUseCase struct {
-	products map[int64]*entity.Product
-	nextID   int64
+	products  map[int64]*entity.Product
+	nextID    int64
+	lastQuery entity.ProductQuery
+
+	lastWarehouseID *int64
+	lastTTL         time.Duration
+
+	reservations map[int64]*entity.Reservation
+
+	exportErr  error
+	lastImport entity.ImportOptions
+	imported   []*entity.ImportRow
 }
 
 func NewMockUseCase() *MockUseCase {
 	return &MockUseCase{
-		products: make(map[int64]*entity.Product),
-		nextID:   1,
+		products:     make(map[int64]*entity.Product),
+		nextID:       1,
+		reservations: make(map[int64]*entity.Reservation),
 	}
 }
 
-func (m *MockUseCase) Create(p *entity.Product) (int64, error) {
+func (m *MockUseCase) Create(ctx context.Context, p *entity.Product) (int64, error) {
 	if p.Name == "" {
-		return 0, errors.New("name is required")
+		return 0, entity.NewValidationError("name", "required", "name is required")
 	}
 	if p.Price <= 0 {
-		return 0, errors.New("price must be greater than zero")
+		return 0, entity.NewValidationError("price", "must_be_positive", "price must be greater than zero")
 	}
 	if p.Quantity < 0 {
-		return 0, errors.New("quantity must be non-negative")
+		return 0, entity.NewValidationError("quantity", "must_be_non_negative", "quantity must be non-negative")
 	}
 
 	id := m.nextID
 	p.ID = id
+	p.Version = 1
 	m.products[id] = p
 	m.nextID++
 	return id, nil
 }
 
-func (m *MockUseCase) GetByID(id int64) (*entity.Product, error) {
+func (m *MockUseCase) GetByID(ctx context.Context, id int64) (*entity.Product, error) {
 	if id <= 0 {
-		return nil, errors.New("invalid id")
+		return nil, entity.NewValidationError("id", "invalid", "invalid id")
 	}
 
 	if p, exists := m.products[id]; exists {
 		return p, nil
 	}
-	return nil, errors.New("product not found")
+	return nil, entity.ErrProductNotFound
 }
 
-func (m *MockUseCase) Update(id int64, p *entity.Product) error {
+func (m *MockUseCase) GetBySKU(ctx context.Context, sku string) (*entity.Product, error) {
+	for _, p := range m.products {
+		if p.SKU != "" && strings.EqualFold(p.SKU, sku) {
+			return p, nil
+		}
+	}
+	return nil, entity.ErrProductNotFound
+}
+
+func (m *MockUseCase) GetByBarcode(ctx context.Context, code string) (*entity.Product, error) {
+	if !entity.ValidBarcode(code) {
+		return nil, entity.NewValidationError("barcode", "invalid", "invalid barcode")
+	}
+
+	for _, p := range m.products {
+		for _, c := range p.Barcodes {
+			if entity.GTIN14(c) == entity.GTIN14(code) {
+				return p, nil
+			}
+		}
+	}
+	return nil, entity.ErrProductNotFound
+}
+
+func (m *MockUseCase) Update(ctx context.Context, id int64, p *entity.Product) error {
 	if id <= 0 {
-		return errors.New("invalid id")
+		return entity.NewValidationError("id", "invalid", "invalid id")
 	}
 	if p.Name == "" {
-		return errors.New("name is required")
+		return entity.NewValidationError("name", "required", "name is required")
 	}
 	if p.Price <= 0 {
-		return errors.New("price must be greater than zero")
+		return entity.NewValidationError("price", "must_be_positive", "price must be greater than zero")
 	}
 	if p.Quantity < 0 {
-		return errors.New("quantity must be non-negative")
+		return entity.NewValidationError("quantity", "must_be_non_negative", "quantity must be non-negative")
 	}
 
-	if _, exists := m.products[id]; !exists {
-		return errors.New("product not found")
+	current, exists := m.products[id]
+	if !exists {
+		return entity.ErrProductNotFound
+	}
+	if p.Version != 0 && p.Version != current.Version {
+		return entity.ErrVersionConflict
 	}
 	p.ID = id
+	p.Version = current.Version + 1
 	m.products[id] = p
 	return nil
 }
 
-func (m *MockUseCase) Delete(id int64) error {
+func (m *MockUseCase) Delete(ctx context.Context, id int64, version int64) error {
 	if id <= 0 {
-		return errors.New("invalid id")
+		return entity.NewValidationError("id", "invalid", "invalid id")
 	}
 
-	if _, exists := m.products[id]; !exists {
-		return errors.New("product not found")
+	current, exists := m.products[id]
+	if !exists {
+		return entity.ErrProductNotFound
+	}
+	if version != 0 && version != current.Version {
+		return entity.ErrVersionConflict
 	}
 	delete(m.products, id)
 	return nil
 }
 
-func (m *MockUseCase) GetAll() ([]*entity.Product, error) {
+func (m *MockUseCase) Batch(ctx context.Context, ops []*entity.BatchOperation, atomic bool) error {
+	for _, op := range ops {
+		switch op.Action {
+		case entity.BatchCreate:
+			_, op.Err = m.Create(ctx, op.Product)
+		case entity.BatchUpdate:
+			op.Product.Version = op.Version
+			op.Err = m.Update(ctx, op.ID, op.Product)
+		case entity.BatchDelete:
+			op.Err = m.Delete(ctx, op.ID, op.Version)
+		}
+		if op.Err != nil && atomic {
+			return op.Err
+		}
+	}
+	return nil
+}
+
+func (m *MockUseCase) GetAll(ctx context.Context, q entity.ProductQuery) (*entity.ProductPage, error) {
+	m.lastQuery = q
+
 	products := make([]*entity.Product, 0, len(m.products))
 	for _, p := range m.products {
 		products = append(products, p)
 	}
-	return products, nil
+	return &entity.ProductPage{Items: products}, nil
+}
+
+func (m *MockUseCase) Export(ctx context.Context, q entity.ProductQuery, fn func(*entity.Product) error) error {
+	m.lastQuery = q
+
+	for id := int64(1); id < m.nextID; id++ {
+		if p, exists := m.products[id]; exists {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+	}
+	return m.exportErr
+}
+
+func (m *MockUseCase) Import(ctx context.Context, src product.ImportSource, opts entity.ImportOptions) (*entity.ImportReport, error) {
+	m.lastImport = opts
+
+	report := &entity.ImportReport{}
+	for {
+		row, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m.imported = append(m.imported, row)
+		report.Rows++
+		if row.Err != nil {
+			report.Fail(row.Number, row.Err)
+			continue
+		}
+		report.Created++
+	}
+}
+
+func (m *MockUseCase) AddMovement(ctx context.Context, productID int64, mv *entity.StockMovement) (int64, error) {
+	p, exists := m.products[productID]
+	if !exists {
+		return 0, entity.ErrProductNotFound
+	}
+	if p.Quantity+mv.Delta < 0 {
+		return 0, entity.ErrInsufficientStock
+	}
+	p.Quantity += mv.Delta
+	mv.ID = 1
+	mv.ProductID = productID
+	mv.Balance = p.Quantity
+	return mv.ID, nil
+}
+
+func (m *MockUseCase) IncreaseStock(ctx context.Context, productID int64, quantity int, reason, reference string) (*entity.StockMovement, error) {
+	mv := &entity.StockMovement{Type: entity.MovementReceipt, Delta: quantity, Reason: reason, Reference: reference}
+	if _, err := m.AddMovement(ctx, productID, mv); err != nil {
+		return nil, err
+	}
+	return mv, nil
+}
+
+func (m *MockUseCase) DecreaseStock(ctx context.Context, productID int64, quantity int, reason, reference string) (*entity.StockMovement, error) {
+	mv := &entity.StockMovement{Type: entity.MovementIssue, Delta: -quantity, Reason: reason, Reference: reference}
+	if _, err := m.AddMovement(ctx, productID, mv); err != nil {
+		return nil, err
+	}
+	return mv, nil
+}
+
+func (m *MockUseCase) GetMovements(ctx context.Context, productID int64) ([]*entity.StockMovement, error) {
+	if _, exists := m.products[productID]; !exists {
+		return nil, entity.ErrProductNotFound
+	}
+	return []*entity.StockMovement{}, nil
+}
+
+func (m *MockUseCase) GetStock(ctx context.Context, productID int64, warehouseID *int64) (*entity.StockBreakdown, error) {
+	m.lastWarehouseID = warehouseID
+
+	p, exists := m.products[productID]
+	if !exists {
+		return nil, entity.ErrProductNotFound
+	}
+	return &entity.StockBreakdown{ProductID: productID, Quantity: p.Quantity, Unallocated: p.Quantity}, nil
+}
+
+func (m *MockUseCase) Reserve(ctx context.Context, productID int64, quantity int, ttl time.Duration, reference string) (*entity.Reservation, error) {
+	m.lastTTL = ttl
+
+	p, exists := m.products[productID]
+	if !exists {
+		return nil, entity.ErrProductNotFound
+	}
+	if quantity > p.Quantity {
+		return nil, entity.ErrInsufficientStock
+	}
+	r := &entity.Reservation{
+		ID:        int64(len(m.reservations) + 1),
+		ProductID: productID,
+		Quantity:  quantity,
+		Status:    entity.ReservationActive,
+		Reference: reference,
+	}
+	m.reservations[r.ID] = r
+	return r, nil
+}
+
+func (m *MockUseCase) GetReservation(ctx context.Context, id int64) (*entity.Reservation, error) {
+	if r, exists := m.reservations[id]; exists {
+		return r, nil
+	}
+	return nil, entity.ErrReservationNotFound
+}
+
+func (m *MockUseCase) ConfirmReservation(ctx context.Context, id int64) (*entity.Reservation, error) {
+	return m.closeReservation(id, entity.ReservationConfirmed)
+}
+
+func (m *MockUseCase) ReleaseReservation(ctx context.Context, id int64) (*entity.Reservation, error) {
+	return m.closeReservation(id, entity.ReservationReleased)
+}
+
+func (m *MockUseCase) ExpireReservations(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockUseCase) closeReservation(id int64, status entity.ReservationStatus) (*entity.Reservation, error) {
+	r, exists := m.reservations[id]
+	if !exists {
+		return nil, entity.ErrReservationNotFound
+	}
+	if r.Status != entity.ReservationActive {
+		return nil, entity.ErrReservationClosed
+	}
+	r.Status = status
+	return r, nil
 }
 
 // Тесты для Create
@@ -106,7 +312,7 @@ func TestCreate_Success(t *testing.T) {
 	product := entity.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       10.99,
+		Price:       1099,
 		Quantity:    5,
 	}
 
@@ -132,6 +338,44 @@ func TestCreate_Success(t *testing.T) {
 	}
 }
 
+func TestCreate_Price(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "number", body: `{"name": "Test", "price": 19.99, "quantity": 1}`, status: http.StatusCreated},
+		{name: "string", body: `{"name": "Test", "price": "19.99", "quantity": 1}`, status: http.StatusCreated},
+		{name: "too many decimals", body: `{"name": "Test", "price": 19.999, "quantity": 1}`, status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUC := NewMockUseCase()
+			handler := NewProductHandler(mockUC)
+
+			req, _ := http.NewRequest("POST", "/products", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.Create(c)
+
+			if w.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d", tc.status, w.Code)
+			}
+
+			if tc.status == http.StatusCreated && mockUC.products[1].Price != 1999 {
+				t.Errorf("Expected price 1999 cents, got %d", mockUC.products[1].Price)
+			}
+		})
+	}
+}
+
 func TestCreate_InvalidJSON(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockUC := NewMockUseCase()
@@ -158,7 +402,7 @@ func TestCreate_EmptyName(t *testing.T) {
 
 	product := entity.Product{
 		Name:     "",
-		Price:    10.99,
+		Price:    1099,
 		Quantity: 5,
 	}
 
@@ -187,10 +431,10 @@ func TestGetByID_Success(t *testing.T) {
 	product := &entity.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       10.99,
+		Price:       1099,
 		Quantity:    5,
 	}
-	mockUC.Create(product)
+	mockUC.Create(context.Background(), product)
 
 	req, _ := http.NewRequest("GET", "/products/1", nil)
 	w := httptest.NewRecorder()
@@ -210,6 +454,8 @@ func TestGetByID_Success(t *testing.T) {
 	if response.Name != "Test Product" {
 		t.Errorf("Expected name 'Test Product', got %s", response.Name)
 	}
+
+	assertJSONKeys(t, w.Body.Bytes(), "id", "name", "description", "price", "currency", "quantity")
 }
 
 func TestGetByID_NotFound(t *testing.T) {
@@ -257,14 +503,14 @@ func TestUpdate_Success(t *testing.T) {
 	// Создаем продукт
 	product := &entity.Product{
 		Name:     "Original",
-		Price:    10.99,
+		Price:    1099,
 		Quantity: 5,
 	}
-	mockUC.Create(product)
+	mockUC.Create(context.Background(), product)
 
 	updatedProduct := entity.Product{
 		Name:     "Updated",
-		Price:    20.99,
+		Price:    2099,
 		Quantity: 10,
 	}
 
@@ -291,7 +537,7 @@ func TestUpdate_NotFound(t *testing.T) {
 
 	updatedProduct := entity.Product{
 		Name:     "Updated",
-		Price:    20.99,
+		Price:    2099,
 		Quantity: 10,
 	}
 
@@ -306,8 +552,8 @@ func TestUpdate_NotFound(t *testing.T) {
 
 	handler.Update(c)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
 }
 
@@ -320,10 +566,10 @@ func TestDelete_Success(t *testing.T) {
 	// Создаем продукт
 	product := &entity.Product{
 		Name:     "Test",
-		Price:    10.99,
+		Price:    1099,
 		Quantity: 5,
 	}
-	mockUC.Create(product)
+	mockUC.Create(context.Background(), product)
 
 	req, _ := http.NewRequest("DELETE", "/products/1", nil)
 	w := httptest.NewRecorder()
@@ -385,10 +631,10 @@ func TestGetAll_MultipleProducts(t *testing.T) {
 	for i := 1; i <= 3; i++ {
 		product := &entity.Product{
 			Name:     fmt.Sprintf("Product %d", i),
-			Price:    float64(10*i) + 0.99,
+			Price:    entity.Money(1000*i + 99),
 			Quantity: i * 5,
 		}
-		mockUC.Create(product)
+		mockUC.Create(context.Background(), product)
 	}
 
 	req, _ := http.NewRequest("GET", "/products", nil)
@@ -402,10 +648,486 @@ func TestGetAll_MultipleProducts(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 
-	var response []*entity.Product
+	var response struct {
+		Items []*entity.Product `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Items) != 3 {
+		t.Errorf("Expected 3 products, got %d", len(response.Items))
+	}
+}
+
+func TestGetAll_QueryParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	cursor := entity.Cursor{Sort: "price", Desc: true, Value: "10.99", ID: 7}.Encode()
+	url := "/products?limit=20&sort=-price&name=lap&min_price=5&max_quantity=100&with_total=true&cursor=" + cursor
+
+	req, _ := http.NewRequest("GET", url, nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.GetAll(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	q := mockUC.lastQuery
+	if q.Limit != 20 || q.SortField != "price" || !q.SortDesc || !q.WithTotal {
+		t.Errorf("Unexpected paging params: %+v", q)
+	}
+	if q.Filter.NameContains != "lap" || q.Filter.MinPrice == nil || *q.Filter.MinPrice != 500 {
+		t.Errorf("Unexpected filter: %+v", q.Filter)
+	}
+	if q.Filter.MaxQuantity == nil || *q.Filter.MaxQuantity != 100 || q.Filter.MinQuantity != nil {
+		t.Errorf("Unexpected quantity filter: %+v", q.Filter)
+	}
+	if q.After == nil || q.After.ID != 7 || q.After.Value != "10.99" {
+		t.Errorf("Unexpected cursor: %+v", q.After)
+	}
+}
+
+func TestGetAll_InvalidQueryParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	for _, url := range []string{
+		"/products?limit=abc",
+		"/products?min_price=NaN",
+		"/products?cursor=not-a-cursor",
+		"/products?with_total=maybe",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+
+		handler.GetAll(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", url, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// Тесты для движений остатков
+func TestAddMovement_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	body := []byte(`{"type":"receipt","delta":10,"reason":"purchase","reference":"PO-42"}`)
+	req, _ := http.NewRequest("POST", "/products/1/movements", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+	handler.AddMovement(c)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var response entity.StockMovement
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.Balance != 15 || response.Reference != "PO-42" {
+		t.Errorf("Unexpected movement in response: %+v", response)
+	}
+
+	assertJSONKeys(t, w.Body.Bytes(), "id", "product_id", "type", "delta", "balance", "reason", "reference", "created_at")
+}
+
+func TestAddMovement_InsufficientStock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	body := []byte(`{"type":"issue","delta":-10,"reason":"sale"}`)
+	req, _ := http.NewRequest("POST", "/products/1/movements", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+	handler.AddMovement(c)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestGetMovements_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	req, _ := http.NewRequest("GET", "/products/999/movements", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "999"})
+
+	handler.GetMovements(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestGetStock_WarehouseFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	req, _ := http.NewRequest("GET", "/products/1/stock?warehouse_id=3", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+	handler.GetStock(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if mockUC.lastWarehouseID == nil || *mockUC.lastWarehouseID != 3 {
+		t.Errorf("Expected warehouse filter 3, got %v", mockUC.lastWarehouseID)
+	}
+
+	var response entity.StockBreakdown
 	json.Unmarshal(w.Body.Bytes(), &response)
 
-	if len(response) != 3 {
-		t.Errorf("Expected 3 products, got %d", len(response))
+	if response.Quantity != 5 {
+		t.Errorf("Expected quantity 5, got %d", response.Quantity)
+	}
+
+	assertJSONKeys(t, w.Body.Bytes(), "product_id", "quantity", "unallocated", "reserved", "available", "locations")
+}
+
+func TestChangeStock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name   string
+		action string
+		body   string
+		status int
+	}{
+		{name: "increase", action: "increase", body: `{"quantity": 3}`, status: http.StatusOK},
+		{name: "decrease", action: "decrease", body: `{"quantity": 5, "reason": "sale"}`, status: http.StatusOK},
+		{name: "decrease below zero", action: "decrease", body: `{"quantity": 6}`, status: http.StatusConflict},
+		{name: "missing quantity", action: "increase", body: `{}`, status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUC := NewMockUseCase()
+			handler := NewProductHandler(mockUC)
+			mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+			req, _ := http.NewRequest("POST", "/products/1/stock/"+tc.action, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+			if tc.action == "increase" {
+				handler.IncreaseStock(c)
+			} else {
+				handler.DecreaseStock(c)
+			}
+
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+// Тесты для резервов
+func TestReserve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "success", body: `{"quantity": 3, "ttl_seconds": 900, "reference": "SO-1"}`, status: http.StatusCreated},
+		{name: "more than on hand", body: `{"quantity": 6, "ttl_seconds": 900}`, status: http.StatusConflict},
+		{name: "missing ttl", body: `{"quantity": 3}`, status: http.StatusBadRequest},
+		{name: "missing quantity", body: `{"ttl_seconds": 900}`, status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUC := NewMockUseCase()
+			handler := NewProductHandler(mockUC)
+			mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+			req, _ := http.NewRequest("POST", "/products/1/reservations", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+			handler.Reserve(c)
+
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status == http.StatusCreated {
+				if mockUC.lastTTL != 15*time.Minute {
+					t.Errorf("Expected ttl of 15m, got %v", mockUC.lastTTL)
+				}
+				assertJSONKeys(t, w.Body.Bytes(), "id", "product_id", "quantity", "status", "reference", "expires_at", "created_at")
+			}
+		})
+	}
+}
+
+func TestConfirmAndReleaseReservation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+	mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+	mockUC.Reserve(context.Background(), 1, 2, time.Minute, "")
+
+	testCases := []struct {
+		name   string
+		call   gin.HandlerFunc
+		id     string
+		status int
+	}{
+		{name: "get", call: handler.GetReservation, id: "1", status: http.StatusOK},
+		{name: "confirm", call: handler.ConfirmReservation, id: "1", status: http.StatusOK},
+		{name: "release confirmed", call: handler.ReleaseReservation, id: "1", status: http.StatusConflict},
+		{name: "unknown", call: handler.ConfirmReservation, id: "42", status: http.StatusNotFound},
+		{name: "invalid id", call: handler.ReleaseReservation, id: "abc", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/reservations/"+tc.id, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: tc.id})
+
+			tc.call(c)
+
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// Тесты для ETag / If-Match
+func TestGetByID_ETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	testCases := []struct {
+		name        string
+		ifNoneMatch string
+		status      int
+	}{
+		{name: "no header", status: http.StatusOK},
+		{name: "current version", ifNoneMatch: `"1"`, status: http.StatusNotModified},
+		{name: "weak current version", ifNoneMatch: `"7", W/"1"`, status: http.StatusNotModified},
+		{name: "stale version", ifNoneMatch: `"0"`, status: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/products/1", nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+			handler.GetByID(c)
+			c.Writer.WriteHeaderNow()
+
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, w.Code)
+			}
+
+			if etag := w.Header().Get("ETag"); etag != `"1"` {
+				t.Errorf("Expected ETag '\"1\"', got %s", etag)
+			}
+		})
+	}
+}
+
+// Тесты поиска по SKU и штрихкоду
+func TestGetBySKUAndBarcode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC)
+
+	mockUC.Create(context.Background(), &entity.Product{SKU: "MUG-01", Name: "Mug", Price: 1099, Barcodes: []string{"4006381333931"}})
+
+	r := gin.New()
+	r.GET("/products/:id", handler.GetByID)
+	r.GET("/products/by-sku/:sku", handler.GetBySKU)
+	r.GET("/products/by-barcode/:code", handler.GetByBarcode)
+
+	testCases := []struct {
+		path   string
+		status int
+	}{
+		{path: "/products/by-sku/mug-01", status: http.StatusOK},
+		{path: "/products/by-sku/MUG-02", status: http.StatusNotFound},
+		{path: "/products/by-barcode/4006381333931", status: http.StatusOK},
+		{path: "/products/by-barcode/4006381333932", status: http.StatusBadRequest},
+		{path: "/products/by-barcode/036000291452", status: http.StatusNotFound},
+		{path: "/products/1", status: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if w.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+
+			if tc.status == http.StatusOK && w.Header().Get("ETag") != `"1"` {
+				t.Errorf("Expected ETag '\"1\"', got %s", w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestUpdate_IfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name     string
+		ifMatch  string
+		required bool
+		status   int
+	}{
+		{name: "current version", ifMatch: `"1"`, status: http.StatusOK},
+		{name: "any version", ifMatch: "*", status: http.StatusOK},
+		{name: "stale version", ifMatch: `"2"`, status: http.StatusPreconditionFailed},
+		{name: "malformed tag", ifMatch: "1", status: http.StatusPreconditionFailed},
+		{name: "optional header missing", status: http.StatusOK},
+		{name: "required header missing", required: true, status: http.StatusPreconditionRequired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUC := NewMockUseCase()
+			handler := NewProductHandler(mockUC, WithRequireIfMatch(tc.required))
+			mockUC.Create(context.Background(), &entity.Product{Name: "Original", Price: 1099, Quantity: 5})
+
+			body, _ := json.Marshal(entity.Product{Name: "Updated", Price: 2099, Quantity: 10})
+			req, _ := http.NewRequest("PUT", "/products/1", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+			handler.Update(c)
+			c.Writer.WriteHeaderNow()
+
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, w.Code)
+			}
+
+			if tc.status == http.StatusOK && w.Header().Get("ETag") != `"2"` {
+				t.Errorf("Expected new ETag '\"2\"', got %s", w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestDelete_IfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockUseCase()
+	handler := NewProductHandler(mockUC, WithRequireIfMatch(true))
+
+	mockUC.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	for _, tc := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{ifMatch: "", status: http.StatusPreconditionRequired},
+		{ifMatch: `"3"`, status: http.StatusPreconditionFailed},
+		{ifMatch: `"1"`, status: http.StatusNoContent},
+	} {
+		req, _ := http.NewRequest("DELETE", "/products/1", nil)
+		if tc.ifMatch != "" {
+			req.Header.Set("If-Match", tc.ifMatch)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+		handler.Delete(c)
+		c.Writer.WriteHeaderNow()
+
+		if w.Code != tc.status {
+			t.Errorf("If-Match %q: expected status %d, got %d", tc.ifMatch, tc.status, w.Code)
+		}
+	}
+}
+
+// assertJSONKeys checks that body is a JSON object with all of keys, so
+// responses keep their snake_case field names.
+func assertJSONKeys(t *testing.T, body []byte, keys ...string) {
+	t.Helper()
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("Expected a JSON object, got %s", body)
+	}
+	for _, key := range keys {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("Expected key %q in %s", key, body)
+		}
 	}
 }
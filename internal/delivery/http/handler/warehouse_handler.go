@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/usecase/warehouse"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WarehouseHandler struct {
+	usecase warehouse.UseCase
+}
+
+func NewWarehouseHandler(uc warehouse.UseCase) *WarehouseHandler {
+	return &WarehouseHandler{usecase: uc}
+}
+
+func (h *WarehouseHandler) Create(c *gin.Context) {
+	var input entity.Warehouse
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		writeProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := h.usecase.Create(c.Request.Context(), &input)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+func (h *WarehouseHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	w, err := h.usecase.GetByID(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, w)
+}
+
+func (h *WarehouseHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	var input entity.Warehouse
+	if err := c.ShouldBindJSON(&input); err != nil {
+		writeProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.usecase.Update(c.Request.Context(), id, &input); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *WarehouseHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	if err := h.usecase.Delete(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WarehouseHandler) GetAll(c *gin.Context) {
+	warehouses, err := h.usecase.GetAll(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouses)
+}
+
+func (h *WarehouseHandler) CreateLocation(c *gin.Context) {
+	warehouseID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	var input entity.Location
+	if err := c.ShouldBindJSON(&input); err != nil {
+		writeProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.WarehouseID = warehouseID
+
+	id, err := h.usecase.CreateLocation(c.Request.Context(), &input)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+func (h *WarehouseHandler) GetLocations(c *gin.Context) {
+	warehouseID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	locations, err := h.usecase.GetLocations(c.Request.Context(), warehouseID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, locations)
+}
+
+func (h *WarehouseHandler) GetLocation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	l, err := h.usecase.GetLocation(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, l)
+}
+
+func (h *WarehouseHandler) UpdateLocation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	var input entity.Location
+	if err := c.ShouldBindJSON(&input); err != nil {
+		writeProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.usecase.UpdateLocation(c.Request.Context(), id, &input); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *WarehouseHandler) DeleteLocation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeError(c, errInvalidID)
+		return
+	}
+
+	if err := h.usecase.DeleteLocation(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+// Mock UseCase складов для тестирования handler
+type MockWarehouseUseCase struct {
+	warehouses map[int64]*entity.Warehouse
+	locations  map[int64]*entity.Location
+	nextID     int64
+}
+
+func NewMockWarehouseUseCase() *MockWarehouseUseCase {
+	return &MockWarehouseUseCase{
+		warehouses: make(map[int64]*entity.Warehouse),
+		locations:  make(map[int64]*entity.Location),
+		nextID:     1,
+	}
+}
+
+func (m *MockWarehouseUseCase) Create(ctx context.Context, w *entity.Warehouse) (int64, error) {
+	if w.Code == "" {
+		return 0, entity.NewValidationError("code", "required", "code is required")
+	}
+	w.ID = m.nextID
+	m.warehouses[w.ID] = w
+	m.nextID++
+	return w.ID, nil
+}
+
+func (m *MockWarehouseUseCase) GetByID(ctx context.Context, id int64) (*entity.Warehouse, error) {
+	if w, exists := m.warehouses[id]; exists {
+		return w, nil
+	}
+	return nil, entity.ErrWarehouseNotFound
+}
+
+func (m *MockWarehouseUseCase) Update(ctx context.Context, id int64, w *entity.Warehouse) error {
+	if _, exists := m.warehouses[id]; !exists {
+		return entity.ErrWarehouseNotFound
+	}
+	w.ID = id
+	m.warehouses[id] = w
+	return nil
+}
+
+func (m *MockWarehouseUseCase) Delete(ctx context.Context, id int64) error {
+	if _, exists := m.warehouses[id]; !exists {
+		return entity.ErrWarehouseNotFound
+	}
+	delete(m.warehouses, id)
+	return nil
+}
+
+func (m *MockWarehouseUseCase) GetAll(ctx context.Context) ([]*entity.Warehouse, error) {
+	warehouses := make([]*entity.Warehouse, 0, len(m.warehouses))
+	for _, w := range m.warehouses {
+		warehouses = append(warehouses, w)
+	}
+	return warehouses, nil
+}
+
+func (m *MockWarehouseUseCase) CreateLocation(ctx context.Context, l *entity.Location) (int64, error) {
+	if _, exists := m.warehouses[l.WarehouseID]; !exists {
+		return 0, entity.ErrWarehouseNotFound
+	}
+	l.ID = m.nextID
+	m.locations[l.ID] = l
+	m.nextID++
+	return l.ID, nil
+}
+
+func (m *MockWarehouseUseCase) GetLocation(ctx context.Context, id int64) (*entity.Location, error) {
+	if l, exists := m.locations[id]; exists {
+		return l, nil
+	}
+	return nil, entity.ErrLocationNotFound
+}
+
+func (m *MockWarehouseUseCase) UpdateLocation(ctx context.Context, id int64, l *entity.Location) error {
+	if _, exists := m.locations[id]; !exists {
+		return entity.ErrLocationNotFound
+	}
+	l.ID = id
+	m.locations[id] = l
+	return nil
+}
+
+func (m *MockWarehouseUseCase) DeleteLocation(ctx context.Context, id int64) error {
+	if _, exists := m.locations[id]; !exists {
+		return entity.ErrLocationNotFound
+	}
+	delete(m.locations, id)
+	return nil
+}
+
+func (m *MockWarehouseUseCase) GetLocations(ctx context.Context, warehouseID int64) ([]*entity.Location, error) {
+	if _, exists := m.warehouses[warehouseID]; !exists {
+		return nil, entity.ErrWarehouseNotFound
+	}
+	locations := []*entity.Location{}
+	for _, l := range m.locations {
+		if l.WarehouseID == warehouseID {
+			locations = append(locations, l)
+		}
+	}
+	return locations, nil
+}
+
+func TestWarehouseCreate_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockWarehouseUseCase()
+	handler := NewWarehouseHandler(mockUC)
+
+	body, _ := json.Marshal(entity.Warehouse{Code: "WH-1", Name: "North"})
+	req, _ := http.NewRequest("POST", "/warehouses", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Create(c)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+}
+
+func TestWarehouseCreateLocation_UsesPathWarehouse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockWarehouseUseCase()
+	handler := NewWarehouseHandler(mockUC)
+
+	mockUC.Create(context.Background(), &entity.Warehouse{Code: "WH-1", Name: "North"})
+
+	body := []byte(`{"warehouse_id": 42, "code": "A-01-01", "aisle": "A", "bin": "01"}`)
+	req, _ := http.NewRequest("POST", "/warehouses/1/locations", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+	handler.CreateLocation(c)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	l, _ := mockUC.GetLocation(context.Background(), 2)
+	if l == nil || l.WarehouseID != 1 {
+		t.Errorf("Expected location in warehouse 1, got %+v", l)
+	}
+}
+
+func TestWarehouseGetByID_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockWarehouseUseCase()
+	handler := NewWarehouseHandler(mockUC)
+
+	mockUC.Create(context.Background(), &entity.Warehouse{Code: "WH-1", Name: "North", Address: "1 Dock Rd"})
+
+	req, _ := http.NewRequest("GET", "/warehouses/1", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+
+	handler.GetByID(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	assertJSONKeys(t, w.Body.Bytes(), "id", "code", "name", "address")
+}
+
+func TestWarehouseGetLocation_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockWarehouseUseCase()
+	handler := NewWarehouseHandler(mockUC)
+
+	mockUC.Create(context.Background(), &entity.Warehouse{Code: "WH-1", Name: "North"})
+	mockUC.CreateLocation(context.Background(), &entity.Location{WarehouseID: 1, Code: "A-01-01", Aisle: "A", Bin: "01"})
+
+	req, _ := http.NewRequest("GET", "/locations/2", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "2"})
+
+	handler.GetLocation(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	assertJSONKeys(t, w.Body.Bytes(), "id", "warehouse_id", "code", "aisle", "bin")
+}
+
+func TestWarehouseGetLocations_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUC := NewMockWarehouseUseCase()
+	handler := NewWarehouseHandler(mockUC)
+
+	req, _ := http.NewRequest("GET", "/warehouses/999/locations", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: "999"})
+
+	handler.GetLocations(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger writes one structured line per request. The route is the template
+// gin matched (/products/:id), so lines can be grouped without the ids. The
+// request ID comes from the context, see logger.New.
+func Logger(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		// Deferred, so a response aborted with http.ErrAbortHandler is logged
+		// too.
+		defer func() {
+			status := c.Writer.Status()
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError || len(c.Errors) > 0:
+				level = slog.LevelError
+			case status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", c.Request.Method),
+				slog.String("route", route(c)),
+				slog.String("path", c.Request.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("bytes", max(c.Writer.Size(), 0)),
+				slog.String("client_ip", c.ClientIP()),
+			}
+			if err := c.Errors.Last(); err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			log.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+		}()
+
+		c.Next()
+	}
+}
+
+// route returns the template gin matched, or "unmatched" for 404s, so raw
+// paths never end up as a label or log field.
+func route(c *gin.Context) string {
+	if r := c.FullPath(); r != "" {
+		return r
+	}
+	return "unmatched"
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тесты для Logger
+func TestLogger_Fields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	log, _ := logger.New(&buf, "json", "info")
+	r := gin.New()
+	r.Use(RequestID(), Logger(log))
+	r.GET("/products/:id", func(c *gin.Context) {
+		c.String(http.StatusNotFound, "missing")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/products/42", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q", buf.String())
+	}
+
+	expected := map[string]interface{}{
+		"level":      "WARN",
+		"method":     "GET",
+		"route":      "/products/:id",
+		"path":       "/products/42",
+		"status":     float64(404),
+		"bytes":      float64(len("missing")),
+		"request_id": "abc-123",
+	}
+	for k, v := range expected {
+		if line[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, line[k])
+		}
+	}
+
+	if _, ok := line["latency"]; !ok {
+		t.Error("Expected latency to be logged")
+	}
+}
+
+func TestLogger_UnmatchedRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	r := gin.New()
+	r.Use(Logger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	var line map[string]interface{}
+	json.Unmarshal(buf.Bytes(), &line)
+
+	if line["route"] != "unmatched" || line["status"] != float64(404) {
+		t.Errorf("Unexpected log line: %v", line)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts requests and their latency by method, route template and
+// status.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		defer func() {
+			m.ObserveRequest(method(c), route(c), c.Writer.Status(), time.Since(start))
+		}()
+
+		c.Next()
+	}
+}
+
+// method returns the request method, or "OTHER" for any the HTTP spec does
+// not define, so a client cannot add series by inventing methods.
+func method(c *gin.Context) string {
+	switch m := c.Request.Method; m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	}
+	return "OTHER"
+}
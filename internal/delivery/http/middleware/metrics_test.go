@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тесты для Metrics
+func TestMetrics_LabelsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := metrics.New()
+	r := gin.New()
+	r.Use(Metrics(m))
+	r.GET("/products/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/products/1", "/products/2", "/nope"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/products/1", nil))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/products/:id",status="204"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="OTHER",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/products/:id",status="204"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, body)
+		}
+	}
+
+	if strings.Contains(body, "/products/1") || strings.Contains(body, "PURGE") {
+		t.Error("Expected raw paths and methods not to be used as labels")
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery answers a panicking handler with 500 and logs the panic with its
+// stack. http.ErrAbortHandler is passed on to net/http, which then cuts the
+// connection: that is how a handler fails a response whose status is
+// already sent.
+func Recovery(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			log.ErrorContext(c.Request.Context(), "panic recovered",
+				"panic", p,
+				"stack", string(debug.Stack()))
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+
+		c.Next()
+	}
+}
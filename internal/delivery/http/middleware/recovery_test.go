@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тесты для Recovery
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	log, _ := logger.New(&buf, "json", "info")
+	r := gin.New()
+	r.Use(Recovery(log))
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+	r.GET("/abort", func(c *gin.Context) { panic(http.ErrAbortHandler) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if !strings.Contains(buf.String(), `"panic":"boom"`) || !strings.Contains(buf.String(), `"stack"`) {
+		t.Errorf("Expected the panic logged with its stack, got %q", buf.String())
+	}
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("Expected http.ErrAbortHandler to pass through, got %v", p)
+		}
+	}()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+}
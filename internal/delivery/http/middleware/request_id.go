@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID takes the caller's X-Request-ID, or generates one when it is
+// missing or unusable, stores it in the request context and echoes it back.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тесты для RequestID
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "accepts caller id", incoming: "abc-123", keep: true},
+		{name: "generates when missing", incoming: ""},
+		{name: "replaces unsafe id", incoming: "x */ DROP TABLE products"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var fromCtx string
+			r := gin.New()
+			r.Use(RequestID())
+			r.GET("/", func(c *gin.Context) {
+				fromCtx = requestid.FromContext(c.Request.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(requestid.Header, tc.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			echoed := w.Header().Get(requestid.Header)
+			if echoed == "" || echoed != fromCtx {
+				t.Fatalf("Expected echoed id %q to match context id %q", echoed, fromCtx)
+			}
+
+			if (echoed == tc.incoming) != tc.keep {
+				t.Errorf("Incoming %q, got %q", tc.incoming, echoed)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout puts a deadline on the request context. Handlers pass that context
+// down to the database, so a slow query is cancelled once the deadline passes
+// and the handler answers 504. A non-positive d disables the deadline. The
+// routes in skip, given as the templates gin matched, get no deadline: they
+// stream for as long as the client reads and bound their writes themselves.
+func Timeout(d time.Duration, skip ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 || slices.Contains(skip, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
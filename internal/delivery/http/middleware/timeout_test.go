@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тесты для Timeout
+func TestTimeout_SetsDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Timeout(time.Second))
+
+	var deadline time.Time
+	var ok bool
+	r.GET("/", func(c *gin.Context) {
+		deadline, ok = c.Request.Context().Deadline()
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !ok {
+		t.Fatal("Expected request context to have a deadline")
+	}
+
+	if remaining := time.Until(deadline); remaining > time.Second {
+		t.Errorf("Expected deadline within 1s, got %v", remaining)
+	}
+}
+
+func TestTimeout_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Timeout(0))
+
+	var ok bool
+	r.GET("/", func(c *gin.Context) {
+		_, ok = c.Request.Context().Deadline()
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if ok {
+		t.Error("Expected no deadline when timeout is disabled")
+	}
+}
+
+func TestTimeout_SkipsRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Timeout(time.Second, "/products/export"))
+
+	deadlines := map[string]bool{}
+	handler := func(c *gin.Context) {
+		_, deadlines[c.FullPath()] = c.Request.Context().Deadline()
+	}
+	r.GET("/products/export", handler)
+	r.GET("/products/:id", handler)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products/export", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products/1", nil))
+
+	if deadlines["/products/export"] || !deadlines["/products/:id"] {
+		t.Errorf("Expected a deadline on /products/:id only, got %v", deadlines)
+	}
+}
@@ -1,21 +1,132 @@
 package http
 
 import (
+	"log/slog"
+	"time"
+
 	"github.com/imbafff/product-warehouse-api/internal/delivery/http/handler"
+	"github.com/imbafff/product-warehouse-api/internal/delivery/http/middleware"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/metrics"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func NewRouter(h *handler.ProductHandler) *gin.Engine {
-	r := gin.Default()
+type routerOptions struct {
+	logger         *slog.Logger
+	requestTimeout time.Duration
+	metrics        *metrics.Metrics
+	tracerProvider trace.TracerProvider
+	health         *handler.HealthHandler
+}
+
+type Option func(*routerOptions)
+
+func WithLogger(l *slog.Logger) Option {
+	return func(o *routerOptions) {
+		o.logger = l
+	}
+}
+
+// WithRequestTimeout sets the per-request deadline. Zero disables it.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(o *routerOptions) {
+		o.requestTimeout = d
+	}
+}
+
+// WithMetrics records request metrics and serves them on /metrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *routerOptions) {
+		o.metrics = m
+	}
+}
+
+// WithHealth serves /healthz and /readyz.
+func WithHealth(hh *handler.HealthHandler) Option {
+	return func(o *routerOptions) {
+		o.health = hh
+	}
+}
+
+// WithTracerProvider replaces the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *routerOptions) {
+		o.tracerProvider = tp
+	}
+}
+
+func NewRouter(h *handler.ProductHandler, wh *handler.WarehouseHandler, opts ...Option) *gin.Engine {
+	o := routerOptions{logger: logger.Discard(), tracerProvider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.Tracing(o.tracerProvider), middleware.Logger(o.logger))
+	if o.metrics != nil {
+		r.Use(middleware.Metrics(o.metrics))
+	}
+	// The export streams the whole catalog and the import may write a whole
+	// file, see ProductHandler.Export and ProductHandler.Import.
+	r.Use(middleware.Recovery(o.logger), middleware.Timeout(o.requestTimeout, "/products/export", "/products/import"))
+
+	if o.metrics != nil {
+		r.GET("/metrics", gin.WrapH(o.metrics.Handler()))
+	}
+
+	if o.health != nil {
+		r.GET("/healthz", o.health.Live)
+		r.GET("/readyz", o.health.Ready)
+	}
+
+	// Custom methods such as /products:batch. See ProductHandler.CustomMethod.
+	r.POST("/products:method", h.CustomMethod)
 
 	products := r.Group("/products")
 	{
 		products.POST("", h.Create)
 		products.GET("", h.GetAll)
+		products.GET("/export", h.Export)
+		products.POST("/import", h.Import)
+		products.GET("/by-sku/:sku", h.GetBySKU)
+		products.GET("/by-barcode/:code", h.GetByBarcode)
 		products.GET("/:id", h.GetByID)
 		products.PUT("/:id", h.Update)
 		products.DELETE("/:id", h.Delete)
+		products.POST("/:id/movements", h.AddMovement)
+		products.GET("/:id/movements", h.GetMovements)
+		products.GET("/:id/stock", h.GetStock)
+		products.POST("/:id/stock/increase", h.IncreaseStock)
+		products.POST("/:id/stock/decrease", h.DecreaseStock)
+		products.POST("/:id/reservations", h.Reserve)
+	}
+
+	reservations := r.Group("/reservations")
+	{
+		reservations.GET("/:id", h.GetReservation)
+		reservations.POST("/:id/confirm", h.ConfirmReservation)
+		reservations.POST("/:id/release", h.ReleaseReservation)
+	}
+
+	warehouses := r.Group("/warehouses")
+	{
+		warehouses.POST("", wh.Create)
+		warehouses.GET("", wh.GetAll)
+		warehouses.GET("/:id", wh.GetByID)
+		warehouses.PUT("/:id", wh.Update)
+		warehouses.DELETE("/:id", wh.Delete)
+		warehouses.POST("/:id/locations", wh.CreateLocation)
+		warehouses.GET("/:id/locations", wh.GetLocations)
+	}
+
+	locations := r.Group("/locations")
+	{
+		locations.GET("/:id", wh.GetLocation)
+		locations.PUT("/:id", wh.UpdateLocation)
+		locations.DELETE("/:id", wh.DeleteLocation)
 	}
 
 	return r
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/delivery/http/handler"
+	"github.com/imbafff/product-warehouse-api/internal/repository/product/memory"
+	productUC "github.com/imbafff/product-warehouse-api/internal/usecase/product"
+	warehouseUC "github.com/imbafff/product-warehouse-api/internal/usecase/warehouse"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newMemoryRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	repo, err := memory.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(
+		handler.NewProductHandler(productUC.New(repo)),
+		handler.NewWarehouseHandler(warehouseUC.New(repo.Warehouses())),
+	)
+}
+
+// Custom methods share a path segment with the collection, so the routes
+// must not shadow each other.
+func TestRouter_CustomMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := newMemoryRouter(t)
+
+	testCases := []struct {
+		path   string
+		body   string
+		status int
+	}{
+		{"/products", `{"name": "Widget", "price": 5}`, http.StatusCreated},
+		{"/products:batch", `{"operations": [{"op": "create", "product": {"name": "Widget", "price": 5}}]}`, http.StatusOK},
+		{"/products:purge", `{}`, http.StatusNotFound},
+		{"/productsbatch", `{}`, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("POST %s: expected status %d, got %d: %s", tc.path, tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+// An exported file imports back as updates of the same products.
+func TestRouter_ExportImportRoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newMemoryRouter(t)
+
+	req := httptest.NewRequest("POST", "/products", strings.NewReader(`{"sku": "MUG-01", "name": "Mug", "price": 5, "barcodes": ["4006381333931"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/products/export?format=csv", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Export: expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "products.csv")
+	fw.Write(w.Body.Bytes())
+	mw.Close()
+
+	req = httptest.NewRequest("POST", "/products/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"updated":1`) || !strings.Contains(w.Body.String(), `"failed":0`) {
+		t.Errorf("Import: expected one update, got %d: %s", w.Code, w.Body.String())
+	}
+}
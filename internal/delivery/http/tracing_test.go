@@ -0,0 +1,152 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/delivery/http/handler"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/tracing"
+	productRepo "github.com/imbafff/product-warehouse-api/internal/repository/product"
+	productUC "github.com/imbafff/product-warehouse-api/internal/usecase/product"
+
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeConn отвечает на два запроса, которые делают Create и GetByID
+type fakeConn struct{}
+
+func (fakeConn) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConn) Driver() driver.Driver                        { return nil }
+func (fakeConn) Prepare(string) (driver.Stmt, error)          { return nil, driver.ErrSkip }
+func (fakeConn) Close() error                                 { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                    { return fakeConn{}, nil }
+func (fakeConn) Commit() error                                { return nil }
+func (fakeConn) Rollback() error                              { return nil }
+
+func (fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "INSERT INTO products") {
+		return &fakeRows{cols: []string{"id", "version"}, row: []driver.Value{int64(7), int64(1)}}, nil
+	}
+	return &fakeRows{
+		cols: []string{"id", "sku", "name", "description", "price", "currency", "quantity", "version", "barcodes"},
+		row:  []driver.Value{int64(7), "", "Widget", "", "19.99", "USD", int64(0), int64(1), nil},
+	}, nil
+}
+
+type fakeRows struct {
+	cols []string
+	row  []driver.Value
+	done bool
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+// Тесты для трассировки
+func TestTracing_CreateThenGet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	database := sql.OpenDB(fakeConn{})
+	defer database.Close()
+
+	repo := productRepo.NewPostgresRepository(database, productRepo.WithTracerProvider(tp))
+	uc := productUC.New(repo, productUC.WithTracerProvider(tp))
+	r := NewRouter(handler.NewProductHandler(uc), handler.NewWarehouseHandler(nil), WithTracerProvider(tp))
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(`{"name":"Widget","price":19.99}`))
+	req.Header.Set("traceparent", traceparent)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products/7", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+
+	// имя span -> имя родителя
+	tree := map[string]string{
+		"POST /products":          "",
+		"product.Service.Create":  "POST /products",
+		"INSERT":                  "product.Service.Create",
+		"GET /products/:id":       "",
+		"product.Service.GetByID": "GET /products/:id",
+		"SELECT":                  "product.Service.GetByID",
+	}
+	for name, parent := range tree {
+		s, ok := spans[name]
+		if !ok {
+			t.Errorf("Expected span %q, got %v", name, names(recorder.Ended()))
+			continue
+		}
+		if parent == "" {
+			continue
+		}
+		if p, ok := spans[parent]; !ok || s.Parent().SpanID() != p.SpanContext().SpanID() {
+			t.Errorf("Expected %q to be a child of %q", name, parent)
+		}
+	}
+
+	if id := spans["POST /products"].SpanContext().TraceID().String(); id != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected the caller's trace to be continued, got trace %s", id)
+	}
+	if spans["GET /products/:id"].Parent().IsValid() {
+		t.Error("Expected a request without traceparent to start a new trace")
+	}
+
+	for _, name := range []string{"product.Service.Create", "product.Service.GetByID", "SELECT"} {
+		if !hasAttr(spans[name], string(tracing.ProductIDKey), "7") {
+			t.Errorf("Expected %q to carry product.id=7, got %v", name, spans[name].Attributes())
+		}
+	}
+}
+
+func names(spans []sdktrace.ReadOnlySpan) []string {
+	var out []string
+	for _, s := range spans {
+		out = append(out, s.Name())
+	}
+	return out
+}
+
+func hasAttr(s sdktrace.ReadOnlySpan, key, value string) bool {
+	if s == nil {
+		return false
+	}
+	for _, a := range s.Attributes() {
+		if string(a.Key) == key && a.Value.Emit() == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package entity
+
+import "strings"
+
+// GTIN lengths accepted as barcodes: UPC-A, EAN-13 and GTIN-14.
+const (
+	upcLength    = 12
+	eanLength    = 13
+	gtin14Length = 14
+)
+
+// ValidBarcode reports whether code is a UPC-A, EAN-13 or GTIN-14 with a
+// correct check digit.
+func ValidBarcode(code string) bool {
+	if len(code) < upcLength || len(code) > gtin14Length || !isDigits(code) {
+		return false
+	}
+
+	// The check digit is the last one; the others are weighted 3, 1, 3, ...
+	// from the right.
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		d := int(code[i] - '0')
+		if (len(code)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
+
+// GTIN14 pads a valid barcode with zeros to 14 digits. A UPC-A, the EAN-13
+// with a leading zero and the GTIN-14 with two leading zeros are the same
+// article, so barcodes are compared in this form.
+func GTIN14(code string) string {
+	return strings.Repeat("0", max(gtin14Length-len(code), 0)) + code
+}
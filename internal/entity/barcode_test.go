@@ -0,0 +1,36 @@
+package entity
+
+import "testing"
+
+func TestValidBarcode(t *testing.T) {
+	testCases := []struct {
+		code string
+		want bool
+	}{
+		{"036000291452", true},     // UPC-A
+		{"4006381333931", true},    // EAN-13
+		{"10614141000415", true},   // GTIN-14
+		{"0036000291452", true},    // UPC-A as EAN-13
+		{"036000291453", false},    // wrong check digit
+		{"4006381333932", false},   // wrong check digit
+		{"40063813339", false},     // too short
+		{"400638133393100", false}, // too long
+		{"40063813339a1", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		if got := ValidBarcode(tc.code); got != tc.want {
+			t.Errorf("%q: expected %v, got %v", tc.code, tc.want, got)
+		}
+	}
+}
+
+func TestGTIN14(t *testing.T) {
+	if got := GTIN14("036000291452"); got != "00036000291452" {
+		t.Errorf("Expected UPC-A padded to 14 digits, got %q", got)
+	}
+	if GTIN14("0036000291452") != GTIN14("036000291452") {
+		t.Error("Expected EAN-13 with a leading zero to match its UPC-A")
+	}
+}
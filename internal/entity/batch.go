@@ -0,0 +1,29 @@
+package entity
+
+type BatchAction string
+
+const (
+	BatchCreate BatchAction = "create"
+	BatchUpdate BatchAction = "update"
+	BatchDelete BatchAction = "delete"
+)
+
+func (a BatchAction) Valid() bool {
+	switch a {
+	case BatchCreate, BatchUpdate, BatchDelete:
+		return true
+	}
+	return false
+}
+
+// BatchOperation is one item of a bulk request. Create and update take
+// Product, update and delete take ID; Version guards them like If-Match,
+// zero skips the check. Once the batch ran, Product holds the stored ID and
+// version, and Err why the operation failed, if it did.
+type BatchOperation struct {
+	Action  BatchAction
+	ID      int64
+	Version int64
+	Product *Product
+	Err     error
+}
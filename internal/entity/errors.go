@@ -0,0 +1,115 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error kinds. Every error returned by the usecase and repository layers
+// either is or wraps one of them, so callers can classify it with errors.Is
+// without comparing messages.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrValidation         = errors.New("validation failed")
+	ErrConflict           = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrInternal           = errors.New("internal error")
+)
+
+var (
+	ErrProductNotFound   = newError(ErrNotFound, "product not found")
+	ErrWarehouseNotFound = newError(ErrNotFound, "warehouse not found")
+	ErrLocationNotFound  = newError(ErrNotFound, "location not found")
+
+	ErrReservationNotFound = newError(ErrNotFound, "reservation not found")
+
+	ErrInsufficientStock  = newError(ErrConflict, "insufficient stock")
+	ErrSKUTaken           = newError(ErrConflict, "sku already exists")
+	ErrBarcodeTaken       = newError(ErrConflict, "barcode already belongs to another product")
+	ErrWarehouseCodeTaken = newError(ErrConflict, "warehouse code already exists")
+	ErrLocationCodeTaken  = newError(ErrConflict, "location code already exists")
+	ErrWarehouseNotEmpty  = newError(ErrConflict, "warehouse still has locations")
+	ErrLocationNotEmpty   = newError(ErrConflict, "location still holds stock")
+	ErrReservationClosed  = newError(ErrConflict, "reservation is no longer active")
+	ErrReservationExpired = newError(ErrConflict, "reservation has expired")
+
+	ErrVersionConflict = newError(ErrPreconditionFailed, "version conflict")
+)
+
+// IsDomainError reports whether err is an expected outcome, such as a missing
+// product or a failed validation, rather than a failure of the system.
+func IsDomainError(err error) bool {
+	return errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrValidation) ||
+		errors.Is(err, ErrConflict) ||
+		errors.Is(err, ErrPreconditionFailed)
+}
+
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func newError(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+// FieldError describes one violated rule. Code is a stable machine-readable
+// identifier of the rule, Message is meant for humans.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the fields of an input that failed validation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func NewValidationError(field, code, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidation }
+
+// Violations collects every failed rule of an input so that all of them can be
+// reported at once.
+type Violations struct {
+	fields []FieldError
+}
+
+func (v *Violations) Add(field, code, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Has reports whether a violation of field has been collected.
+func (v *Violations) Has(field string) bool {
+	for _, f := range v.fields {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns a *ValidationError with the collected violations, or nil if
+// there are none.
+func (v *Violations) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
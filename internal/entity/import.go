@@ -0,0 +1,54 @@
+package entity
+
+// ImportKey is the column an import matches rows with existing products by.
+type ImportKey string
+
+const (
+	ImportBySKU ImportKey = "sku"
+	ImportByID  ImportKey = "id"
+)
+
+func (k ImportKey) Valid() bool {
+	return k == ImportBySKU || k == ImportByID
+}
+
+type ImportOptions struct {
+	Key ImportKey
+	// DryRun validates every row without writing anything.
+	DryRun bool
+}
+
+// ImportRow is one data row of an import file. Values holds the cells by
+// product field, only for the columns the file has. Number is the row in the
+// file, the header being row 1. Err is set when the row could not be read.
+type ImportRow struct {
+	Number int
+	Values map[string]string
+	Err    error
+}
+
+// ImportReport sums up an import. In a dry run Created and Updated count the
+// rows that would have been. Errors lists the failed rows, at most
+// MaxImportErrors of them.
+type ImportReport struct {
+	Rows    int
+	Created int
+	Updated int
+	Failed  int
+	Errors  []ImportError
+}
+
+// MaxImportErrors bounds ImportReport.Errors, Failed keeps counting.
+const MaxImportErrors = 1000
+
+type ImportError struct {
+	Row int
+	Err error
+}
+
+func (r *ImportReport) Fail(row int, err error) {
+	r.Failed++
+	if len(r.Errors) < MaxImportErrors {
+		r.Errors = append(r.Errors, ImportError{Row: row, Err: err})
+	}
+}
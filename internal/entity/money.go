@@ -0,0 +1,137 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for products created without an explicit currency.
+const DefaultCurrency = "USD"
+
+var (
+	ErrInvalidMoney   = newError(ErrValidation, "invalid money amount")
+	ErrMoneyPrecision = newError(ErrValidation, "money amount must have at most 2 decimal places")
+)
+
+// Money is an exact amount in minor units (cents). Prices are stored as
+// NUMERIC(10,2), so two decimal places are all the precision there is; going
+// through float64 would turn 19.99 into 19.989999... Only currencies with
+// MoneyDigits minor digits are supported, which is why Money never needs to
+// know its currency to be parsed or printed.
+type Money int64
+
+// MoneyDigits is the number of minor digits of every Money amount.
+const MoneyDigits = 2
+
+const moneyScale = 100
+
+// ParseMoney parses a plain decimal such as "19.99", "-5" or "0.5". Exponents
+// and more than two significant decimal places are rejected rather than
+// rounded.
+func ParseMoney(s string) (Money, error) {
+	neg := strings.HasPrefix(s, "-")
+	if neg || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	whole, frac, dot := strings.Cut(s, ".")
+	if !isDigits(whole) || (dot && !isDigits(frac)) {
+		return 0, ErrInvalidMoney
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > MoneyDigits {
+		return 0, ErrMoneyPrecision
+	}
+	frac += strings.Repeat("0", MoneyDigits-len(frac))
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > math.MaxInt64/moneyScale-1 {
+		return 0, ErrInvalidMoney
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+
+	m := Money(units*moneyScale + cents)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) String() string {
+	sign := ""
+	abs := uint64(m)
+	if m < 0 {
+		sign = "-"
+		abs = uint64(-m)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, abs/moneyScale, abs%moneyScale)
+}
+
+// MarshalJSON writes the amount as an exact JSON number, e.g. 19.99.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts both a JSON number and a string holding one.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan reads a NUMERIC column. Postgres hands NUMERIC over as text, which is
+// parsed exactly; float64 is only accepted for drivers that have nothing else.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * moneyScale)
+		return nil
+	case float64:
+		*m = Money(math.Round(v * moneyScale))
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return fmt.Errorf("scan money %q: %w", s, err)
+	}
+	*m = v
+	return nil
+}
+
+// Value stores the amount as decimal text so the database never sees a float.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
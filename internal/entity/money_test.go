@@ -0,0 +1,118 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// Тесты для Money
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want Money
+		err  error
+	}{
+		{in: "19.99", want: 1999},
+		{in: "0.1", want: 10},
+		{in: "5", want: 500},
+		{in: "-5.50", want: -550},
+		{in: "10.000", want: 1000},
+		{in: "99999999.99", want: 9999999999},
+		{in: "10.001", err: ErrMoneyPrecision},
+		{in: "", err: ErrInvalidMoney},
+		{in: ".5", err: ErrInvalidMoney},
+		{in: "5.", err: ErrInvalidMoney},
+		{in: "1e3", err: ErrInvalidMoney},
+		{in: "NaN", err: ErrInvalidMoney},
+		{in: "99999999999999999999", err: ErrInvalidMoney},
+	}
+
+	for _, tc := range testCases {
+		got, err := ParseMoney(tc.in)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%q: expected error %v, got %v", tc.in, tc.err, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: expected %d, got %d", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	for m, want := range map[Money]string{0: "0.00", 5: "0.05", 1999: "19.99", -550: "-5.50"} {
+		if got := m.String(); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	var p struct{ Price Money }
+
+	for _, body := range []string{`{"Price": 19.99}`, `{"Price": "19.99"}`} {
+		if err := json.Unmarshal([]byte(body), &p); err != nil || p.Price != 1999 {
+			t.Errorf("%s: expected 1999, got %d (%v)", body, p.Price, err)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"Price": 19.999}`), &p); !errors.Is(err, ErrMoneyPrecision) {
+		t.Errorf("Expected ErrMoneyPrecision, got %v", err)
+	}
+
+	out, _ := json.Marshal(p)
+	if string(out) != `{"Price":19.99}` {
+		t.Errorf("Unexpected JSON: %s", out)
+	}
+}
+
+func TestMoney_Scan(t *testing.T) {
+	testCases := []struct {
+		src  interface{}
+		want Money
+	}{
+		{src: []byte("19.99"), want: 1999},
+		{src: "0.10", want: 10},
+		{src: int64(7), want: 700},
+		{src: 19.99, want: 1999},
+	}
+
+	for _, tc := range testCases {
+		var m Money
+		if err := m.Scan(tc.src); err != nil || m != tc.want {
+			t.Errorf("%v: expected %d, got %d (%v)", tc.src, tc.want, m, err)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(nil); err == nil {
+		t.Error("Expected error scanning NULL")
+	}
+
+	v, _ := Money(1999).Value()
+	if v != "19.99" {
+		t.Errorf("Expected driver value 19.99, got %v", v)
+	}
+}
+
+func TestCurrencyDigits(t *testing.T) {
+	testCases := []struct {
+		code   string
+		digits int
+		ok     bool
+	}{
+		{code: DefaultCurrency, digits: MoneyDigits, ok: true},
+		{code: "EUR", digits: 2, ok: true},
+		{code: "JPY", digits: 0, ok: true},
+		{code: "KWD", digits: 3, ok: true},
+		{code: "usd"},
+		{code: "XAU"},
+	}
+
+	for _, tc := range testCases {
+		if digits, ok := CurrencyDigits(tc.code); digits != tc.digits || ok != tc.ok {
+			t.Errorf("%s: expected %d, %t, got %d, %t", tc.code, tc.digits, tc.ok, digits, ok)
+		}
+	}
+}
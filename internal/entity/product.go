@@ -1,9 +1,19 @@
 package entity
 
 type Product struct {
-	ID          int64
-	Name        string
-	Description string
-	Price       float64
-	Quantity    int
+	ID int64 `json:"id"`
+	// SKU is the business identifier, unique regardless of case. Products
+	// created before SKUs existed may have none.
+	SKU string `json:"sku"`
+	// Barcodes are UPC-A, EAN-13 or GTIN-14 codes, each belonging to one
+	// product only.
+	Barcodes    []string `json:"barcodes"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Price       Money    `json:"price"`
+	// Currency is an ISO 4217 code such as USD or EUR.
+	Currency string `json:"currency"`
+	Quantity int    `json:"quantity"`
+	// Version is incremented on every change and backs optimistic locking.
+	Version int64 `json:"version"`
 }
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+var ErrInvalidCursor = NewValidationError("cursor", "invalid", "invalid cursor")
+
+type ProductFilter struct {
+	NameContains string
+	MinPrice     *Money
+	MaxPrice     *Money
+	MinQuantity  *int
+	MaxQuantity  *int
+	WarehouseID  *int64
+}
+
+// ProductQuery describes one page of a product listing. After and Offset are
+// mutually exclusive: After selects keyset pagination, Offset the classic one.
+type ProductQuery struct {
+	Filter    ProductFilter
+	SortField string
+	SortDesc  bool
+	Limit     int
+	Offset    int
+	After     *Cursor
+	WithTotal bool
+}
+
+type ProductPage struct {
+	Items []*Product
+	Next  *Cursor
+	Total *int64
+}
+
+// Cursor points at the last row of a page: the value of the sort column and
+// the id used as a tie breaker.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Sort == "" || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
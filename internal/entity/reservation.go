@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationConfirmed ReservationStatus = "confirmed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds Quantity units of a product for a pending order until
+// ExpiresAt. Holding does not change the product quantity; confirming the
+// reservation issues the units, releasing or expiring it gives them back to
+// the available stock. A reservation past ExpiresAt cannot be confirmed, but
+// it keeps counting as reserved until the sweeper, or the next reservation of
+// the same product, marks it expired.
+type Reservation struct {
+	ID        int64             `json:"id"`
+	ProductID int64             `json:"product_id"`
+	Quantity  int               `json:"quantity"`
+	Status    ReservationStatus `json:"status"`
+	Reference string            `json:"reference"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"slices"
+	"time"
+)
+
+type MovementType string
+
+const (
+	MovementReceipt    MovementType = "receipt"
+	MovementIssue      MovementType = "issue"
+	MovementAdjustment MovementType = "adjustment"
+	MovementTransfer   MovementType = "transfer"
+)
+
+// MovementTypes lists every movement type.
+var MovementTypes = []MovementType{MovementReceipt, MovementIssue, MovementAdjustment, MovementTransfer}
+
+func (t MovementType) Valid() bool {
+	return slices.Contains(MovementTypes, t)
+}
+
+// StockMovement is one append-only entry of the stock ledger. Delta is signed,
+// Balance is the product quantity right after the movement was applied.
+//
+// A movement with a LocationID also changes the stock of that location. A
+// transfer never changes the product quantity: it moves Delta units from the
+// unallocated stock into the location, or back when Delta is negative.
+type StockMovement struct {
+	ID         int64        `json:"id"`
+	ProductID  int64        `json:"product_id"`
+	LocationID *int64       `json:"location_id,omitempty"`
+	Type       MovementType `json:"type"`
+	Delta      int          `json:"delta"`
+	Balance    int          `json:"balance"`
+	Reason     string       `json:"reason"`
+	Reference  string       `json:"reference"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
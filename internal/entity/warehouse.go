@@ -0,0 +1,37 @@
+package entity
+
+type Warehouse struct {
+	ID      int64  `json:"id"`
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// Location is a storage place inside a warehouse, usually a bin in an aisle.
+type Location struct {
+	ID          int64  `json:"id"`
+	WarehouseID int64  `json:"warehouse_id"`
+	Code        string `json:"code"`
+	Aisle       string `json:"aisle"`
+	Bin         string `json:"bin"`
+}
+
+type StockLevel struct {
+	WarehouseID  int64  `json:"warehouse_id"`
+	LocationID   int64  `json:"location_id"`
+	LocationCode string `json:"location_code"`
+	Quantity     int    `json:"quantity"`
+}
+
+// StockBreakdown splits the product quantity by location. Unallocated is the
+// part of Quantity that has not been put away into any location yet.
+// Reserved is held by active reservations and Available, the
+// available-to-promise quantity, is what is left for new orders.
+type StockBreakdown struct {
+	ProductID   int64         `json:"product_id"`
+	Quantity    int           `json:"quantity"`
+	Unallocated int           `json:"unallocated"`
+	Reserved    int           `json:"reserved"`
+	Available   int           `json:"available"`
+	Locations   []*StockLevel `json:"locations"`
+}
@@ -1,32 +1,410 @@
 package config
 
 import (
-	"log"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/goccy/go-yaml"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	// Storage is postgres, sqlite or memory. The memory store needs no
+	// database and keeps data only for the life of the process, unless
+	// StorageSnapshot names a file to persist it to.
+	Storage         string
+	StorageSnapshot string
+	SQLitePath      string
+
+	// DatabaseURL is a full Postgres DSN. When set, the DB* fields are ignored.
+	DatabaseURL string
+
 	DBHost string
 	DBPort string
 	DBUser string
 	DBPass string
 	DBName string
 	DBSSL  string
+
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+	// DBConnectTimeout bounds the connection retries at startup.
+	DBConnectTimeout time.Duration
+
+	MigrateOnStart bool
+
+	HTTPPort        string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	MaxHeaderBytes  int
+	ShutdownTimeout time.Duration
+	// ShutdownDrainDelay is how long the server keeps serving, with readiness
+	// failing, after a shutdown signal.
+	ShutdownDrainDelay time.Duration
+	HealthTimeout      time.Duration
+	RequireIfMatch     bool
+	RequestTimeout     time.Duration
+
+	// ReservationSweepInterval is how often expired stock reservations are
+	// released. Zero disables the sweeper.
+	ReservationSweepInterval time.Duration
+
+	// BatchMaxSize is the most operations one POST /products:batch takes.
+	BatchMaxSize int
+
+	LogLevel  string
+	LogFormat string
+
+	TraceExporter string
+
+	// Args holds the positional arguments left after the flags, such as the
+	// migrate subcommand.
+	Args []string
+}
+
+type setting struct {
+	key   string
+	def   string
+	usage string
+}
+
+// settings lists every option by its environment variable name. The same
+// option is spelled db_host in a config file and -db-host on the command line.
+var settings = []setting{
+	{"STORAGE", "postgres", "where data lives: postgres, sqlite or memory"},
+	{"STORAGE_SNAPSHOT", "", "JSON file the memory storage loads from and saves to"},
+	{"SQLITE_PATH", "warehouse.db", "database file of the sqlite storage"},
+	{"DATABASE_URL", "", "Postgres connection URL, overrides the DB_* settings"},
+	{"DB_HOST", "localhost", "Postgres host"},
+	{"DB_PORT", "5432", "Postgres port"},
+	{"DB_USER", "", "Postgres user"},
+	{"DB_PASSWORD", "", "Postgres password"},
+	{"DB_NAME", "", "Postgres database"},
+	{"DB_SSLMODE", "disable", "Postgres sslmode"},
+	{"DB_MAX_OPEN_CONNS", "25", "maximum open connections, 0 for no limit"},
+	{"DB_MAX_IDLE_CONNS", "10", "maximum idle connections kept in the pool"},
+	{"DB_CONN_MAX_LIFETIME", "30m", "close connections older than this, 0 keeps them"},
+	{"DB_CONN_MAX_IDLE_TIME", "5m", "close connections idle for longer than this, 0 keeps them"},
+	{"DB_CONNECT_TIMEOUT", "30s", "how long to retry connecting at startup"},
+	{"MIGRATE_ON_START", "false", "apply pending migrations before serving"},
+	{"HTTP_PORT", "8080", "HTTP listen port"},
+	{"HTTP_READ_TIMEOUT", "15s", "time allowed to read a request"},
+	{"HTTP_WRITE_TIMEOUT", "60s", "time allowed to write a response"},
+	{"HTTP_IDLE_TIMEOUT", "120s", "keep-alive timeout"},
+	{"HTTP_MAX_HEADER_BYTES", "1048576", "maximum size of request headers"},
+	{"SHUTDOWN_TIMEOUT", "30s", "how long shutdown waits for in-flight requests"},
+	{"SHUTDOWN_DRAIN_DELAY", "0s", "how long to keep serving with readiness failing before shutdown"},
+	{"HEALTH_CHECK_TIMEOUT", "2s", "time allowed for the readiness checks"},
+	{"REQUIRE_IF_MATCH", "false", "reject PUT and DELETE on products without If-Match"},
+	{"REQUEST_TIMEOUT", "30s", "per-request deadline, 0 disables it"},
+	{"RESERVATION_SWEEP_INTERVAL", "1m", "how often expired reservations are released, 0 disables it"},
+	{"BATCH_MAX_SIZE", "1000", "most operations accepted by one bulk request"},
+	{"LOG_LEVEL", "info", "debug, info, warn or error"},
+	{"LOG_FORMAT", "json", "json or text"},
+	{"TRACE_EXPORTER", "none", "where spans go: none, stdout or otlp"},
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Load builds the configuration from, in increasing order of precedence:
+// built-in defaults, the config file named by -config or CONFIG_FILE,
+// environment variables (a .env file in the working directory fills in the
+// ones that are not set) and command-line flags in args.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or JSON config file")
+	for _, s := range settings {
+		fs.String(flagName(s.key), "", s.usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	flags := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			flags[envName(f.Name)] = f.Value.String()
+		}
+	})
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	var file map[string]string
+	if path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &parser{lookup: func(key string) string {
+		if v, ok := flags[key]; ok {
+			return v
+		}
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+		if v := file[key]; v != "" {
+			return v
+		}
+		return defaultValue(key)
+	}}
+
+	cfg := &Config{
+		Storage:         p.oneOf("STORAGE", []string{"postgres", "sqlite", "memory"}),
+		StorageSnapshot: p.string("STORAGE_SNAPSHOT"),
+		SQLitePath:      p.string("SQLITE_PATH"),
+
+		DatabaseURL: p.string("DATABASE_URL"),
+
+		DBHost: p.string("DB_HOST"),
+		DBPort: p.port("DB_PORT"),
+		DBUser: p.string("DB_USER"),
+		DBPass: p.string("DB_PASSWORD"),
+		DBName: p.string("DB_NAME"),
+		DBSSL:  p.oneOf("DB_SSLMODE", sslModes),
+
+		DBMaxOpenConns:    p.nonNegativeInt("DB_MAX_OPEN_CONNS"),
+		DBMaxIdleConns:    p.nonNegativeInt("DB_MAX_IDLE_CONNS"),
+		DBConnMaxLifetime: p.duration("DB_CONN_MAX_LIFETIME"),
+		DBConnMaxIdleTime: p.duration("DB_CONN_MAX_IDLE_TIME"),
+		DBConnectTimeout:  p.duration("DB_CONNECT_TIMEOUT"),
+
+		MigrateOnStart: p.bool("MIGRATE_ON_START"),
+
+		HTTPPort:           p.port("HTTP_PORT"),
+		ReadTimeout:        p.duration("HTTP_READ_TIMEOUT"),
+		WriteTimeout:       p.duration("HTTP_WRITE_TIMEOUT"),
+		IdleTimeout:        p.duration("HTTP_IDLE_TIMEOUT"),
+		MaxHeaderBytes:     p.positiveInt("HTTP_MAX_HEADER_BYTES"),
+		ShutdownTimeout:    p.duration("SHUTDOWN_TIMEOUT"),
+		ShutdownDrainDelay: p.duration("SHUTDOWN_DRAIN_DELAY"),
+		HealthTimeout:      p.duration("HEALTH_CHECK_TIMEOUT"),
+		RequireIfMatch:     p.bool("REQUIRE_IF_MATCH"),
+		RequestTimeout:     p.duration("REQUEST_TIMEOUT"),
+
+		ReservationSweepInterval: p.duration("RESERVATION_SWEEP_INTERVAL"),
+		BatchMaxSize:             p.positiveInt("BATCH_MAX_SIZE"),
+
+		LogLevel:  p.oneOf("LOG_LEVEL", []string{"debug", "info", "warn", "error"}),
+		LogFormat: p.oneOf("LOG_FORMAT", []string{"json", "text"}),
+
+		TraceExporter: p.oneOf("TRACE_EXPORTER", []string{"none", "stdout", "otlp"}),
+
+		Args: fs.Args(),
+	}
+
+	switch {
+	case cfg.Storage == "memory":
+		// The memory storage needs no database settings.
+	case cfg.Storage == "sqlite":
+		p.required("SQLITE_PATH", cfg.SQLitePath)
+	case cfg.DatabaseURL != "":
+		if u, err := url.Parse(cfg.DatabaseURL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			p.fail("DATABASE_URL", "must be a postgres:// URL")
+		}
+	default:
+		p.required("DB_USER", cfg.DBUser)
+		p.required("DB_NAME", cfg.DBName)
+	}
+
+	if cfg.HealthTimeout == 0 {
+		p.fail("HEALTH_CHECK_TIMEOUT", "must be greater than zero")
+	}
+
+	if err := errors.Join(p.errs...); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
 }
 
-func Load() *Config {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal(err)
+// String describes the configuration with the password and the password
+// part of DatabaseURL redacted, so it is safe to log.
+func (c *Config) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "STORAGE=%s STORAGE_SNAPSHOT=%s SQLITE_PATH=%s ", c.Storage, c.StorageSnapshot, c.SQLitePath)
+
+	if c.DatabaseURL != "" {
+		dsn := "<invalid>"
+		if u, err := url.Parse(c.DatabaseURL); err == nil {
+			dsn = u.Redacted()
+		}
+		fmt.Fprintf(&b, "DATABASE_URL=%s", dsn)
+	} else {
+		pass := ""
+		if c.DBPass != "" {
+			pass = "xxxxx"
+		}
+		fmt.Fprintf(&b, "DB_HOST=%s DB_PORT=%s DB_USER=%s DB_PASSWORD=%s DB_NAME=%s DB_SSLMODE=%s",
+			c.DBHost, c.DBPort, c.DBUser, pass, c.DBName, c.DBSSL)
 	}
 
-	return &Config{
-		DBHost: os.Getenv("DB_HOST"),
-		DBPort: os.Getenv("DB_PORT"),
-		DBUser: os.Getenv("DB_USER"),
-		DBPass: os.Getenv("DB_PASSWORD"),
-		DBName: os.Getenv("DB_NAME"),
-		DBSSL:  os.Getenv("DB_SSLMODE"),
+	fmt.Fprintf(&b, " DB_MAX_OPEN_CONNS=%d DB_MAX_IDLE_CONNS=%d DB_CONN_MAX_LIFETIME=%s DB_CONN_MAX_IDLE_TIME=%s DB_CONNECT_TIMEOUT=%s",
+		c.DBMaxOpenConns, c.DBMaxIdleConns, c.DBConnMaxLifetime, c.DBConnMaxIdleTime, c.DBConnectTimeout)
+
+	fmt.Fprintf(&b, " MIGRATE_ON_START=%t HTTP_PORT=%s HTTP_READ_TIMEOUT=%s HTTP_WRITE_TIMEOUT=%s HTTP_IDLE_TIMEOUT=%s HTTP_MAX_HEADER_BYTES=%d SHUTDOWN_TIMEOUT=%s SHUTDOWN_DRAIN_DELAY=%s HEALTH_CHECK_TIMEOUT=%s REQUIRE_IF_MATCH=%t REQUEST_TIMEOUT=%s RESERVATION_SWEEP_INTERVAL=%s BATCH_MAX_SIZE=%d LOG_LEVEL=%s LOG_FORMAT=%s TRACE_EXPORTER=%s",
+		c.MigrateOnStart, c.HTTPPort, c.ReadTimeout, c.WriteTimeout, c.IdleTimeout,
+		c.MaxHeaderBytes, c.ShutdownTimeout, c.ShutdownDrainDelay, c.HealthTimeout, c.RequireIfMatch, c.RequestTimeout, c.ReservationSweepInterval, c.BatchMaxSize, c.LogLevel, c.LogFormat, c.TraceExporter)
+
+	return b.String()
+}
+
+// readFile reads a flat YAML or JSON object whose keys are the lower-case
+// setting names, e.g. {"db_host": "db", "http_port": 8080}.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	raw := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.UseNumber()
+		err = dec.Decode(&raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		key := strings.ToUpper(k)
+		if !known(key) {
+			return nil, fmt.Errorf("config file %s: unknown setting %q", path, k)
+		}
+		if v != nil {
+			values[key] = fmt.Sprint(v)
+		}
+	}
+
+	return values, nil
+}
+
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+func envName(flag string) string {
+	return strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+func defaultValue(key string) string {
+	for _, s := range settings {
+		if s.key == key {
+			return s.def
+		}
+	}
+	return ""
+}
+
+func known(key string) bool {
+	for _, s := range settings {
+		if s.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parser converts setting values and collects every problem instead of
+// stopping at the first one.
+type parser struct {
+	lookup func(key string) string
+	errs   []error
+}
+
+func (p *parser) fail(key, msg string) {
+	p.errs = append(p.errs, fmt.Errorf("%s %s", key, msg))
+}
+
+func (p *parser) string(key string) string {
+	return p.lookup(key)
+}
+
+func (p *parser) required(key, value string) {
+	if value == "" {
+		p.fail(key, "is required")
+	}
+}
+
+func (p *parser) port(key string) string {
+	v := p.lookup(key)
+	if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 65535 {
+		p.fail(key, fmt.Sprintf("must be a port number, got %q", v))
+	}
+	return v
+}
+
+func (p *parser) oneOf(key string, allowed []string) string {
+	v := p.lookup(key)
+	for _, a := range allowed {
+		if v == a {
+			return v
+		}
+	}
+	p.fail(key, fmt.Sprintf("must be one of %s, got %q", strings.Join(allowed, ", "), v))
+	return v
+}
+
+func (p *parser) bool(key string) bool {
+	v := p.lookup(key)
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.fail(key, fmt.Sprintf("must be true or false, got %q", v))
+	}
+	return b
+}
+
+func (p *parser) duration(key string) time.Duration {
+	v := p.lookup(key)
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		p.fail(key, fmt.Sprintf("must be a non-negative duration such as 30s, got %q", v))
+	}
+	return d
+}
+
+func (p *parser) nonNegativeInt(key string) int {
+	v := p.lookup(key)
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		p.fail(key, fmt.Sprintf("must be a non-negative integer, got %q", v))
+	}
+	return n
+}
+
+func (p *parser) positiveInt(key string) int {
+	v := p.lookup(key)
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		p.fail(key, fmt.Sprintf("must be a positive integer, got %q", v))
 	}
+	return n
 }
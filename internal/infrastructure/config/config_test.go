@@ -1,7 +1,11 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestConfigStructure(t *testing.T) {
@@ -53,3 +57,181 @@ func TestConfigEmpty(t *testing.T) {
 		t.Errorf("Expected empty DBHost, got %s", cfg.DBHost)
 	}
 }
+
+// isolate убирает переменные окружения и .env, чтобы тест видел только свои значения
+func isolate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range settings {
+		t.Setenv(s.key, "")
+	}
+}
+
+func TestLoad_DefaultsWithoutDotEnv(t *testing.T) {
+	isolate(t)
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_NAME", "warehouse")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cfg.DBHost != "localhost" || cfg.DBPort != "5432" || cfg.DBSSL != "disable" || cfg.HTTPPort != "8080" {
+		t.Errorf("Unexpected defaults: %s", cfg)
+	}
+
+	if cfg.RequestTimeout != 30*time.Second || cfg.MaxHeaderBytes != 1<<20 || cfg.RequireIfMatch || cfg.ReservationSweepInterval != time.Minute || cfg.BatchMaxSize != 1000 {
+		t.Errorf("Unexpected defaults: %s", cfg)
+	}
+
+	if cfg.DBMaxOpenConns != 25 || cfg.DBMaxIdleConns != 10 || cfg.DBConnMaxLifetime != 30*time.Minute || cfg.DBConnectTimeout != 30*time.Second {
+		t.Errorf("Unexpected pool defaults: %s", cfg)
+	}
+
+	if cfg.TraceExporter != "none" {
+		t.Errorf("Expected tracing to be off by default, got %q", cfg.TraceExporter)
+	}
+}
+
+func TestLoad_Validation(t *testing.T) {
+	isolate(t)
+	t.Setenv("DB_PORT", "postgres")
+	t.Setenv("DB_SSLMODE", "sometimes")
+	t.Setenv("REQUEST_TIMEOUT", "soon")
+	t.Setenv("TRACE_EXPORTER", "jaeger")
+	t.Setenv("DB_MAX_OPEN_CONNS", "-1")
+
+	_, err := Load(nil)
+	if err == nil {
+		t.Fatal("Expected validation error, got nil")
+	}
+
+	for _, key := range []string{"DB_PORT", "DB_SSLMODE", "REQUEST_TIMEOUT", "TRACE_EXPORTER", "DB_MAX_OPEN_CONNS", "DB_USER", "DB_NAME"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected error to mention %s, got %v", key, err)
+		}
+	}
+}
+
+func TestLoad_DatabaseURL(t *testing.T) {
+	isolate(t)
+	t.Setenv("DATABASE_URL", "postgres://app:secret@db:5432/warehouse?sslmode=require")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Expected DATABASE_URL to replace DB_USER and DB_NAME, got %v", err)
+	}
+
+	if cfg.DatabaseURL == "" {
+		t.Error("Expected DatabaseURL to be set")
+	}
+
+	t.Setenv("DATABASE_URL", "mysql://app@db/warehouse")
+	if _, err := Load(nil); err == nil {
+		t.Error("Expected error for non-postgres URL")
+	}
+}
+
+func TestLoad_MemoryStorage(t *testing.T) {
+	isolate(t)
+	t.Setenv("STORAGE", "memory")
+	t.Setenv("STORAGE_SNAPSHOT", "/tmp/warehouse.json")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Expected memory storage to need no database settings, got %v", err)
+	}
+
+	if cfg.Storage != "memory" || cfg.StorageSnapshot != "/tmp/warehouse.json" {
+		t.Errorf("Expected memory storage with a snapshot, got %q %q", cfg.Storage, cfg.StorageSnapshot)
+	}
+
+	t.Setenv("STORAGE", "redis")
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "STORAGE") {
+		t.Errorf("Expected error for unknown storage, got %v", err)
+	}
+}
+
+func TestLoad_SQLiteStorage(t *testing.T) {
+	isolate(t)
+	t.Setenv("STORAGE", "sqlite")
+	t.Setenv("SQLITE_PATH", "/var/lib/warehouse.db")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Expected sqlite storage to need no database settings, got %v", err)
+	}
+
+	if cfg.Storage != "sqlite" || cfg.SQLitePath != "/var/lib/warehouse.db" {
+		t.Errorf("Expected sqlite storage with a path, got %q %q", cfg.Storage, cfg.SQLitePath)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	isolate(t)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "db_user: from_file\ndb_name: warehouse\ndb_host: file-host\nhttp_port: 7070\nrequire_if_match: true\n"
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("HTTP_PORT", "9090")
+
+	cfg, err := Load([]string{"-http-port", "9191", "migrate", "up"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cfg.DBUser != "from_file" || !cfg.RequireIfMatch {
+		t.Errorf("Expected values from file, got %s", cfg)
+	}
+	if cfg.DBHost != "env-host" {
+		t.Errorf("Expected environment to override file, got %s", cfg.DBHost)
+	}
+	if cfg.HTTPPort != "9191" {
+		t.Errorf("Expected flag to override environment, got %s", cfg.HTTPPort)
+	}
+	if len(cfg.Args) != 2 || cfg.Args[0] != "migrate" {
+		t.Errorf("Expected positional args to be kept, got %v", cfg.Args)
+	}
+}
+
+func TestLoad_JSONFile(t *testing.T) {
+	isolate(t)
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"db_user": "app", "db_name": "warehouse", "http_max_header_bytes": 1048576}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load([]string{"-config", file})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.MaxHeaderBytes != 1048576 {
+		t.Errorf("Expected 1048576, got %d", cfg.MaxHeaderBytes)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"db_hots": "typo"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load([]string{"-config", file}); err == nil {
+		t.Error("Expected error for unknown setting")
+	}
+}
+
+func TestConfig_StringRedactsSecrets(t *testing.T) {
+	cfg := &Config{DBHost: "localhost", DBUser: "postgres", DBPass: "hunter2"}
+	if s := cfg.String(); strings.Contains(s, "hunter2") {
+		t.Errorf("Password leaked: %s", s)
+	}
+
+	cfg = &Config{DatabaseURL: "postgres://app:hunter2@db/warehouse"}
+	if s := cfg.String(); strings.Contains(s, "hunter2") || !strings.Contains(s, "db/warehouse") {
+		t.Errorf("Unexpected DATABASE_URL rendering: %s", s)
+	}
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DB is a *sql.DB whose context-aware calls tag each statement with the
+// request ID, so a row in pg_stat_activity or the Postgres log can be traced
+// back to the HTTP call that issued it, and record a span per statement.
+type DB struct {
+	*sql.DB
+	tracer  trace.Tracer
+	dialect Dialect
+}
+
+type Tx struct {
+	*sql.Tx
+	tracer  trace.Tracer
+	dialect Dialect
+}
+
+// Rows is a *sql.Rows whose statement span ends when it is closed, so the
+// span covers reading the result and records an error met along the way.
+type Rows struct {
+	*sql.Rows
+	span trace.Span
+	once sync.Once
+}
+
+func newRows(rows *sql.Rows, span trace.Span, err error) (*Rows, error) {
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &Rows{Rows: rows, span: span}, nil
+}
+
+func (r *Rows) Close() error {
+	iterErr := r.Rows.Err()
+	err := r.Rows.Close()
+	r.once.Do(func() {
+		if iterErr == nil {
+			iterErr = err
+		}
+		endSpan(r.span, iterErr)
+	})
+	return err
+}
+
+type Option func(*DB)
+
+// WithTracerProvider replaces the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(d *DB) {
+		d.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// WithDialect sets the database flavor, Postgres by default.
+func WithDialect(dialect Dialect) Option {
+	return func(d *DB) {
+		d.dialect = dialect
+	}
+}
+
+func Wrap(db *sql.DB, opts ...Option) *DB {
+	d := &DB{DB: db, tracer: otel.Tracer(tracerName), dialect: Postgres}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Dialect is the SQL flavor of the database, set with WithDialect.
+func (d *DB) Dialect() Dialect {
+	return d.dialect
+}
+
+// Annotate prefixes query with a /* request_id=... */ comment when ctx carries
+// a request ID. requestid only lets through IDs that cannot end the comment.
+func Annotate(ctx context.Context, query string) string {
+	id := requestid.FromContext(ctx)
+	if id == "" || !requestid.Valid(id) {
+		return query
+	}
+	return "/* request_id=" + id + " */ " + query
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSpan(ctx, d.tracer, d.dialect, query)
+	res, err := d.DB.ExecContext(ctx, Annotate(ctx, query), args...)
+	endExecSpan(span, res, err)
+	return res, err
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	ctx, span := startSpan(ctx, d.tracer, d.dialect, query)
+	rows, err := d.DB.QueryContext(ctx, Annotate(ctx, query), args...)
+	return newRows(rows, span, err)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSpan(ctx, d.tracer, d.dialect, query)
+	row := d.DB.QueryRowContext(ctx, Annotate(ctx, query), args...)
+	endSpan(span, row.Err())
+	return row
+}
+
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, tracer: d.tracer, dialect: d.dialect}, nil
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSpan(ctx, t.tracer, t.dialect, query)
+	res, err := t.Tx.ExecContext(ctx, Annotate(ctx, query), args...)
+	endExecSpan(span, res, err)
+	return res, err
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	ctx, span := startSpan(ctx, t.tracer, t.dialect, query)
+	rows, err := t.Tx.QueryContext(ctx, Annotate(ctx, query), args...)
+	return newRows(rows, span, err)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSpan(ctx, t.tracer, t.dialect, query)
+	row := t.Tx.QueryRowContext(ctx, Annotate(ctx, query), args...)
+	endSpan(span, row.Err())
+	return row
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+)
+
+func TestAnnotate(t *testing.T) {
+	query := "SELECT 1"
+
+	if got := Annotate(context.Background(), query); got != query {
+		t.Errorf("Expected query unchanged without request id, got %q", got)
+	}
+
+	ctx := requestid.NewContext(context.Background(), "abc-123")
+	if got := Annotate(ctx, query); got != "/* request_id=abc-123 */ SELECT 1" {
+		t.Errorf("Unexpected annotated query: %q", got)
+	}
+
+	ctx = requestid.NewContext(context.Background(), "x */ DROP TABLE products; --")
+	if got := Annotate(ctx, query); got != query {
+		t.Errorf("Expected unsafe id to be ignored, got %q", got)
+	}
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect is the SQL flavor of a database. It decides the db.system.name of
+// statement spans and how migrations are locked and recorded, and covers the
+// few places where repositories cannot write the same SQL for both.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+func (d Dialect) system() attribute.KeyValue {
+	if d == SQLite {
+		return semconv.DBSystemNameSQLite
+	}
+	return semconv.DBSystemNamePostgreSQL
+}
+
+// ForUpdate returns the clause that locks the selected rows until the
+// transaction ends. SQLite has none: its transactions take the write lock
+// when they begin, see NewSQLiteDB.
+func (d Dialect) ForUpdate() string {
+	if d == SQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// ILike returns the operator that matches a LIKE pattern regardless of case.
+// SQLite's LIKE already does, for ASCII letters only.
+func (d Dialect) ILike() string {
+	if d == SQLite {
+		return "LIKE"
+	}
+	return "ILIKE"
+}
+
+// StringAgg returns the aggregate that joins expr with commas in the given
+// order.
+func (d Dialect) StringAgg(expr, orderBy string) string {
+	if d == SQLite {
+		return "group_concat(" + expr + ", ',' ORDER BY " + orderBy + ")"
+	}
+	return "string_agg(" + expr + ", ',' ORDER BY " + orderBy + ")"
+}
+
+// sqliteTimeLayout has a fixed width, so stored times compare in time order.
+const sqliteTimeLayout = "2006-01-02 15:04:05.000000000"
+
+// Time returns t as a statement argument. SQLite has no time type and
+// compares the text, so times are written in UTC with a fixed width.
+func (d Dialect) Time(t time.Time) interface{} {
+	if d == SQLite {
+		return t.UTC().Format(sqliteTimeLayout)
+	}
+	return t
+}
+
+// IsUniqueViolation reports whether err is a UNIQUE or PRIMARY KEY
+// constraint violation.
+func (d Dialect) IsUniqueViolation(err error) bool {
+	if d == SQLite {
+		return isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+	}
+	return isPostgresError(err, "23505")
+}
+
+// IsForeignKeyViolation reports whether err is a FOREIGN KEY constraint
+// violation.
+func (d Dialect) IsForeignKeyViolation(err error) bool {
+	if d == SQLite {
+		return isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY)
+	}
+	return isPostgresError(err, "23503")
+}
+
+func isPostgresError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
+func isSQLiteError(err error, codes ...int) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && slices.Contains(codes, sqliteErr.Code())
+}
@@ -0,0 +1,371 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrationLockKey is the pg_advisory_lock key held while migrating, so
+// replicas starting at the same time apply each migration once.
+const migrationLockKey = 7_411_802_936
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the numbered *.up.sql / *.down.sql pairs from a file
+// system and records applied versions in the schema_versions table.
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+}
+
+// NewMigrator takes the same options as Wrap; WithDialect(SQLite) is needed
+// for a SQLite database.
+func NewMigrator(db *sql.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: Wrap(db, opts...), migrations: migrations}, nil
+}
+
+// LoadMigrations reads the migration pairs in fsys ordered by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		match := migrationFile.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", e.Name())
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: both up and down files are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every migration that has not been applied yet.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return apply(ctx, conn, m.migrations[i], false)
+			}
+		}
+		return nil
+	})
+}
+
+// Goto migrates up or down until exactly the migrations up to version are
+// applied. Version 0 rolls everything back.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; ok && mig.Version > version {
+				if err := apply(ctx, conn, mig, false); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+				if err := apply(ctx, conn, mig, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration with the time it was applied, if it was.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var status []MigrationStatus
+
+	err := m.withLock(ctx, func(_ *sql.Conn, applied map[int64]time.Time) error {
+		for _, mig := range m.migrations {
+			s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+			if at, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			status = append(status, s)
+		}
+		return nil
+	})
+
+	return status, err
+}
+
+// Latest is the version of the newest migration the binary knows about.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current returns the highest applied version, 0 if none. Unlike Status it
+// does not take the migration lock, so it is cheap enough for health checks.
+func (m *Migrator) Current(ctx context.Context) (int64, error) {
+	exists, err := m.tableExists(ctx, m.db, "schema_versions")
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int64
+	err = m.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_versions`).Scan(&version)
+	return version, err
+}
+
+func (m *Migrator) find(version int64) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// querier is what tableExists needs from a *sql.DB or a *sql.Conn.
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (m *Migrator) tableExists(ctx context.Context, q querier, name string) (bool, error) {
+	query := `SELECT to_regclass($1) IS NOT NULL`
+	if m.db.dialect == SQLite {
+		query = `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)`
+	}
+
+	var exists bool
+	err := q.QueryRowContext(ctx, query, name).Scan(&exists)
+	return exists, err
+}
+
+// withLock runs fn on a single connection holding the migration advisory
+// lock, after making sure the schema_versions table exists. SQLite has no
+// advisory locks; it serves a single node, and every migration is applied in
+// a transaction that takes the database write lock anyway.
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn, map[int64]time.Time) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	createVersions := `
+		CREATE TABLE IF NOT EXISTS schema_versions (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`
+
+	if m.db.dialect == SQLite {
+		createVersions = `
+			CREATE TABLE IF NOT EXISTS schema_versions (
+				version INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)
+		`
+	} else {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+	}
+
+	if _, err := conn.ExecContext(ctx, createVersions); err != nil {
+		return err
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 && m.db.dialect == Postgres {
+		if err := m.adoptGolangMigrate(ctx, conn, applied); err != nil {
+			return err
+		}
+	}
+
+	return fn(conn, applied)
+}
+
+// adoptGolangMigrate records the versions a database was brought to with
+// golang-migrate, which the README used to recommend, so they are not
+// applied a second time.
+func (m *Migrator) adoptGolangMigrate(ctx context.Context, conn *sql.Conn, applied map[int64]time.Time) error {
+	exists, err := m.tableExists(ctx, conn, "schema_migrations")
+	if err != nil || !exists {
+		return err
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err = conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("golang-migrate left version %d dirty, fix it before migrating", version)
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+		var at time.Time
+		err := conn.QueryRowContext(ctx,
+			`INSERT INTO schema_versions (version, name) VALUES ($1, $2) RETURNING applied_at`,
+			mig.Version, mig.Name,
+		).Scan(&at)
+		if err != nil {
+			return err
+		}
+		applied[mig.Version] = at
+	}
+
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_versions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var (
+			version int64
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+
+	return applied, rows.Err()
+}
+
+// apply runs one direction of mig together with its schema_versions
+// bookkeeping in a single transaction.
+func apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script, record := mig.Down, `DELETE FROM schema_versions WHERE version = $1`
+	args := []interface{}{mig.Version}
+	if up {
+		script, record = mig.Up, `INSERT INTO schema_versions (version, name) VALUES ($1, $2)`
+		args = append(args, mig.Name)
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
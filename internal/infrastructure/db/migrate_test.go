@@ -0,0 +1,81 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/imbafff/product-warehouse-api/migrations"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	list, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("Expected embedded migrations to load, got %v", err)
+	}
+
+	if len(list) == 0 || list[0].Version != 1 || list[0].Name != "create_products_table" {
+		t.Fatalf("Unexpected first migration: %+v", list)
+	}
+
+	for i, m := range list {
+		if m.Version != int64(i+1) {
+			t.Errorf("Expected contiguous versions, got %d at position %d", m.Version, i)
+		}
+		if !strings.Contains(m.Up, ";") || !strings.Contains(m.Down, ";") {
+			t.Errorf("Migration %d has an empty script", m.Version)
+		}
+	}
+}
+
+func TestLoadMigrations_Ordering(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000010_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"000010_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"000002_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"000002_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"README.md":              {Data: []byte("ignored")},
+	}
+
+	list, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(list) != 2 || list[0].Version != 2 || list[1].Version != 10 {
+		t.Fatalf("Expected versions [2 10], got %+v", list)
+	}
+
+	if list[1].Up != "CREATE TABLE b ();" || list[1].Down != "DROP TABLE b;" {
+		t.Errorf("Scripts paired incorrectly: %+v", list[1])
+	}
+
+	m, _ := NewMigrator(nil, fsys)
+	if m.Latest() != 10 {
+		t.Errorf("Expected latest version 10, got %d", m.Latest())
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	testCases := map[string]fstest.MapFS{
+		"missing down": {
+			"000001_a.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"conflicting names": {
+			"000001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"000001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"zero version": {
+			"000000_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"000000_a.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+
+	for name, fsys := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadMigrations(fsys); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -1,33 +1,125 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
 
 	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
 
 	_ "github.com/lib/pq"
 )
 
-func NewPostgresDB(cfg *config.Config) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBUser,
-		cfg.DBPass,
-		cfg.DBName,
-		cfg.DBSSL,
-	)
+var connectBackoff = backoff{initial: 100 * time.Millisecond, max: 5 * time.Second}
+
+type connectOptions struct {
+	logger *slog.Logger
+}
+
+// ConnectOption configures NewPostgresDB.
+type ConnectOption func(*connectOptions)
+
+// WithLogger sets the logger that reports failed connection attempts.
+func WithLogger(l *slog.Logger) ConnectOption {
+	return func(o *connectOptions) {
+		o.logger = l
+	}
+}
 
-	db, err := sql.Open("postgres", dsn)
+// NewPostgresDB opens the pool with the configured limits and pings it,
+// retrying with backoff until cfg.DBConnectTimeout has passed so the app
+// can start alongside a database that is still booting. A zero timeout
+// tries once.
+func NewPostgresDB(ctx context.Context, cfg *config.Config, opts ...ConnectOption) (*sql.DB, error) {
+	o := connectOptions{logger: logger.Discard()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	db, err := sql.Open("postgres", dsn(cfg))
 	if err != nil {
 		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	if err := connectBackoff.retry(ctx, o.logger, cfg.DBConnectTimeout, db.PingContext); err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
+
+func dsn(cfg *config.Config) string {
+	if cfg.DatabaseURL != "" {
+		return cfg.DatabaseURL
+	}
+
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		quote(cfg.DBHost),
+		quote(cfg.DBPort),
+		quote(cfg.DBUser),
+		quote(cfg.DBPass),
+		quote(cfg.DBName),
+		quote(cfg.DBSSL),
+	)
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// quote writes v as a libpq keyword/value, quoting it when it is empty or
+// contains spaces, quotes or backslashes.
+func quote(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + dsnEscaper.Replace(v) + "'"
+}
+
+// backoff waits twice as long after every failed attempt, up to max, and
+// picks each wait at random from its upper half so that replicas restarted
+// together do not retry in lockstep.
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+}
+
+func (b backoff) retry(ctx context.Context, log *slog.Logger, timeout time.Duration, fn func(context.Context) error) error {
+	deadline := time.Now().Add(timeout)
+	delay := b.initial
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		wait := delay/2 + rand.N(delay/2+1)
+		if time.Now().Add(wait).After(deadline) {
+			if attempt == 1 {
+				return err
+			}
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		log.WarnContext(ctx, "database not ready, retrying", "attempt", attempt, "wait", wait, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay = min(delay*2, b.max)
+	}
+}
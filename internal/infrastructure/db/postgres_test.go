@@ -1,9 +1,16 @@
 package db
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
 )
 
 func TestNewPostgresDB_InvalidConfig(t *testing.T) {
@@ -16,7 +23,7 @@ func TestNewPostgresDB_InvalidConfig(t *testing.T) {
 		DBSSL:  "disable",
 	}
 
-	db, err := NewPostgresDB(cfg)
+	db, err := NewPostgresDB(context.Background(), cfg)
 
 	// Should fail to connect to invalid host
 	if err == nil {
@@ -53,5 +60,95 @@ func TestDSN_Construction(t *testing.T) {
 	}()
 
 	// Call function (it will fail to connect, but that's okay)
-	_ , _ = NewPostgresDB(cfg)
+	_, _ = NewPostgresDB(context.Background(), cfg)
+}
+
+func TestDSN_Escaping(t *testing.T) {
+	base := config.Config{
+		DBHost: "localhost",
+		DBPort: "5432",
+		DBUser: "postgres",
+		DBName: "testdb",
+		DBSSL:  "disable",
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		expected string
+	}{
+		{"plain", "secret", "password=secret "},
+		{"empty", "", "password='' "},
+		{"spaces", "correct horse battery", "password='correct horse battery' "},
+		{"single quote", "it's", `password='it\'s' `},
+		{"backslash", `a\b`, `password='a\\b' `},
+		{"looks like a keyword", "x dbname=other", "password='x dbname=other' "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.DBPass = tt.password
+
+			got := dsn(&cfg)
+			expected := "host=localhost port=5432 user=postgres " + tt.expected + "dbname=testdb sslmode=disable"
+			if got != expected {
+				t.Errorf("Expected %q, got %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestDSN_DatabaseURL(t *testing.T) {
+	cfg := &config.Config{DatabaseURL: "postgres://u:p@db/warehouse", DBHost: "ignored"}
+
+	if got := dsn(cfg); got != cfg.DatabaseURL {
+		t.Errorf("Expected DATABASE_URL to be used as is, got %q", got)
+	}
+}
+
+func TestBackoff_Retry(t *testing.T) {
+	b := backoff{initial: time.Millisecond, max: 4 * time.Millisecond}
+	down := errors.New("connection refused")
+
+	var logs bytes.Buffer
+	calls := 0
+	err := b.retry(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)), time.Second, func(context.Context) error {
+		calls++
+		if calls < 4 {
+			return down
+		}
+		return nil
+	})
+	if err != nil || calls != 4 {
+		t.Errorf("Expected success on the 4th attempt, got %v after %d calls", err, calls)
+	}
+	if n := strings.Count(logs.String(), "database not ready"); n != 3 {
+		t.Errorf("Expected a warning per failed attempt, got %d:\n%s", n, logs.String())
+	}
+
+	calls = 0
+	err = b.retry(context.Background(), logger.Discard(), 20*time.Millisecond, func(context.Context) error {
+		calls++
+		return down
+	})
+	if !errors.Is(err, down) || calls < 2 {
+		t.Errorf("Expected to give up with the last error after several attempts, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = b.retry(context.Background(), logger.Discard(), 0, func(context.Context) error {
+		calls++
+		return down
+	})
+	if !errors.Is(err, down) || calls != 1 {
+		t.Errorf("Expected a single attempt without a timeout, got %v after %d calls", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = b.retry(ctx, logger.Discard(), time.Second, func(context.Context) error { return down })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancellation to stop retrying, got %v", err)
+	}
 }
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"path/filepath"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
+
+	_ "modernc.org/sqlite"
+)
+
+// NewSQLiteDB opens the database file at cfg.SQLitePath, creating it if
+// needed. Foreign keys are enforced, the journal is in WAL mode so readers do
+// not block the writer, and transactions take the write lock when they begin,
+// which makes them as safe as Postgres' SELECT ... FOR UPDATE at the cost of
+// serializing writers. A busy database is waited on for up to five seconds.
+func NewSQLiteDB(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", sqliteDSN(cfg.SQLitePath))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// sqliteDSN returns the file: URI of path. The path is escaped, so a '?' or
+// '#' in it is not taken for the start of the parameters.
+func sqliteDSN(path string) string {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Set("_txlock", "immediate")
+
+	escaped := (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+	return "file:" + escaped + "?" + q.Encode()
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
+)
+
+func TestNewSQLiteDB_EscapesPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stock #1?.db")
+
+	db, err := NewSQLiteDB(context.Background(), &config.Config{SQLitePath: path, DBMaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewSQLiteDB: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE t (id INTEGER)`); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the database at %q, got %v", path, err)
+	}
+
+	var foreignKeys int
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&foreignKeys); err != nil || foreignKeys != 1 {
+		t.Errorf("Expected the pragmas to apply, got foreign_keys=%d (%v)", foreignKeys, err)
+	}
+}
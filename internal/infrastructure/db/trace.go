@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+
+// RowsAffectedKey is set on the span of an Exec statement.
+const RowsAffectedKey = attribute.Key("db.rows_affected")
+
+type spanAttrsKey struct{}
+
+// WithSpanAttributes returns a context whose statement spans carry attrs as
+// well, e.g. the product a repository call is about.
+func WithSpanAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	prev, _ := ctx.Value(spanAttrsKey{}).([]attribute.KeyValue)
+	return context.WithValue(ctx, spanAttrsKey{}, append(prev[:len(prev):len(prev)], attrs...))
+}
+
+// startSpan starts the span of one statement, named after its leading
+// keyword such as SELECT. The query text is recorded without arguments.
+func startSpan(ctx context.Context, tracer trace.Tracer, dialect Dialect, query string) (context.Context, trace.Span) {
+	op := operation(query)
+	attrs, _ := ctx.Value(spanAttrsKey{}).([]attribute.KeyValue)
+
+	return tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(dialect.system(), semconv.DBOperationName(op), semconv.DBQueryText(strings.TrimSpace(query))),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func endExecSpan(span trace.Span, res sql.Result, err error) {
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			span.SetAttributes(RowsAffectedKey.Int64(n))
+		}
+	}
+	endSpan(span, err)
+}
+
+func operation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// execConn выполняет любой запрос, затрагивая три строки, а UPDATE с ошибкой
+type execConn struct{}
+
+func (execConn) Connect(context.Context) (driver.Conn, error) { return execConn{}, nil }
+func (execConn) Driver() driver.Driver                        { return nil }
+func (execConn) Prepare(string) (driver.Stmt, error)          { return nil, driver.ErrSkip }
+func (execConn) Close() error                                 { return nil }
+func (execConn) Begin() (driver.Tx, error)                    { return nil, errors.New("no transactions") }
+
+func (execConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "UPDATE") {
+		return nil, errors.New("boom")
+	}
+	return driver.RowsAffected(3), nil
+}
+
+// QueryContext отдаёт две строки, а для запроса с "broken" затем ошибку
+func (execConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	rows := &idRows{left: 2}
+	if strings.Contains(query, "broken") {
+		rows.err = errors.New("connection lost")
+	}
+	return rows, nil
+}
+
+type idRows struct {
+	left int
+	err  error
+}
+
+func (r *idRows) Columns() []string { return []string{"id"} }
+func (r *idRows) Close() error      { return nil }
+
+func (r *idRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	dest[0] = int64(r.left)
+	r.left--
+	return nil
+}
+
+func TestDB_StatementSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	sqlDB := sql.OpenDB(execConn{})
+	defer sqlDB.Close()
+	d := Wrap(sqlDB, WithTracerProvider(tp))
+
+	ctx := requestid.NewContext(context.Background(), "abc-123")
+	ctx = WithSpanAttributes(ctx, attribute.Int64("product.id", 42))
+
+	if _, err := d.ExecContext(ctx, "\n\t\tdelete FROM products WHERE id = $1", 42); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	d.ExecContext(ctx, "UPDATE products SET name = $1", "x")
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, a := range spans[0].Attributes() {
+		attrs[a.Key] = a.Value
+	}
+
+	if spans[0].Name() != "DELETE" {
+		t.Errorf("Expected span named DELETE, got %q", spans[0].Name())
+	}
+	if attrs["db.query.text"].AsString() != "delete FROM products WHERE id = $1" {
+		t.Errorf("Expected the query without the request id comment, got %q", attrs["db.query.text"].AsString())
+	}
+	if attrs["product.id"].AsInt64() != 42 || attrs[RowsAffectedKey].AsInt64() != 3 {
+		t.Errorf("Expected product.id and rows affected, got %v", spans[0].Attributes())
+	}
+
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("Expected failed statement to mark its span, got %v", spans[1].Status())
+	}
+}
+
+func TestDB_QuerySpanEndsOnClose(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	sqlDB := sql.OpenDB(execConn{})
+	defer sqlDB.Close()
+	d := Wrap(sqlDB, WithTracerProvider(tp))
+
+	for i, query := range []string{"SELECT id FROM products", "SELECT broken"} {
+		rows, err := d.QueryContext(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for rows.Next() {
+		}
+		if len(recorder.Ended()) != i {
+			t.Errorf("Expected the span of %q to stay open until Close", query)
+		}
+		rows.Close()
+		rows.Close()
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Status().Code == codes.Error {
+		t.Errorf("Expected the complete read to succeed, got %v", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error || spans[1].Status().Description != "connection lost" {
+		t.Errorf("Expected the failed read to mark its span, got %v", spans[1].Status())
+	}
+}
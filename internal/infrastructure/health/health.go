@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+type Result struct {
+	Status  Status                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+type Check func(ctx context.Context) Result
+
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs the readiness checks. A warning is reported but keeps the
+// service ready; any failure, or a shutdown in progress, makes it unready.
+type Checker struct {
+	timeout  time.Duration
+	names    []string
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: map[string]Check{}}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks[name] = check
+}
+
+// Drain marks the service as shutting down. Readiness fails from then on so
+// load balancers stop routing new requests here.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready runs every check concurrently, each bounded by the checker timeout.
+func (c *Checker) Ready(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]Result, len(c.names))
+	var wg sync.WaitGroup
+	for i, name := range c.names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, c.checks[name])
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.names)+1)}
+	for i, name := range c.names {
+		report.Checks[name] = results[i]
+	}
+
+	shutdown := Result{Status: StatusOK}
+	if c.draining.Load() {
+		shutdown = Result{Status: StatusFail, Error: "shutting down"}
+	}
+	report.Checks["shutdown"] = shutdown
+
+	for _, r := range report.Checks {
+		if r.Status == StatusFail {
+			report.Status = StatusFail
+		} else if r.Status == StatusWarn && report.Status == StatusOK {
+			report.Status = StatusWarn
+		}
+	}
+
+	return report
+}
+
+// run gives up on a check that ignores its context once the deadline passes.
+func run(ctx context.Context, check Check) Result {
+	done := make(chan Result, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	select {
+	case r := <-done:
+		return r
+	case <-ctx.Done():
+		return fail(ctx.Err())
+	}
+}
+
+func fail(err error) Result {
+	return Result{Status: StatusFail, Error: err.Error()}
+}
+
+// Ping checks that the database answers.
+func Ping(db *sql.DB) Check {
+	return func(ctx context.Context) Result {
+		if err := db.PingContext(ctx); err != nil {
+			return fail(err)
+		}
+		return Result{Status: StatusOK}
+	}
+}
+
+// Versioner is implemented by db.Migrator.
+type Versioner interface {
+	Latest() int64
+	Current(ctx context.Context) (int64, error)
+}
+
+// Migrations checks that the schema is at the version the binary was built
+// with, neither behind nor ahead.
+func Migrations(v Versioner) Check {
+	return func(ctx context.Context) Result {
+		current, err := v.Current(ctx)
+		if err != nil {
+			return fail(err)
+		}
+
+		r := Result{
+			Status:  StatusOK,
+			Details: map[string]interface{}{"current": current, "expected": v.Latest()},
+		}
+		if current != v.Latest() {
+			r.Status = StatusFail
+			r.Error = fmt.Sprintf("schema is at version %d, expected %d", current, v.Latest())
+		}
+		return r
+	}
+}
+
+// Pool reports connection pool usage and warns when every connection is in
+// use, which means new queries queue up.
+func Pool(db *sql.DB) Check {
+	return func(context.Context) Result {
+		s := db.Stats()
+
+		r := Result{
+			Status: StatusOK,
+			Details: map[string]interface{}{
+				"open":       s.OpenConnections,
+				"in_use":     s.InUse,
+				"idle":       s.Idle,
+				"max_open":   s.MaxOpenConnections,
+				"wait_count": s.WaitCount,
+			},
+		}
+		if s.MaxOpenConnections > 0 {
+			r.Details["saturation"] = float64(s.InUse) / float64(s.MaxOpenConnections)
+			if s.InUse >= s.MaxOpenConnections {
+				r.Status = StatusWarn
+				r.Error = "all connections are in use"
+			}
+		}
+		return r
+	}
+}
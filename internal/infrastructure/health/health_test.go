@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func static(r Result) Check {
+	return func(context.Context) Result { return r }
+}
+
+// Тесты для Checker
+func TestReady_Statuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		checks   map[string]Result
+		expected Status
+	}{
+		{"all ok", map[string]Result{"a": {Status: StatusOK}, "b": {Status: StatusOK}}, StatusOK},
+		{"warning", map[string]Result{"a": {Status: StatusOK}, "b": {Status: StatusWarn}}, StatusWarn},
+		{"failure wins", map[string]Result{"a": {Status: StatusWarn}, "b": {Status: StatusFail}}, StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(time.Second)
+			for name, r := range tt.checks {
+				c.Add(name, static(r))
+			}
+
+			report := c.Ready(context.Background())
+			if report.Status != tt.expected {
+				t.Errorf("Expected %s, got %s: %+v", tt.expected, report.Status, report)
+			}
+			if len(report.Checks) != len(tt.checks)+1 {
+				t.Errorf("Expected every check and shutdown in the report, got %+v", report.Checks)
+			}
+		})
+	}
+}
+
+func TestReady_Draining(t *testing.T) {
+	c := New(time.Second)
+	c.Add("db", static(Result{Status: StatusOK}))
+
+	if r := c.Ready(context.Background()); r.Status != StatusOK {
+		t.Fatalf("Expected ready before shutdown, got %+v", r)
+	}
+
+	c.Drain()
+
+	r := c.Ready(context.Background())
+	if r.Status != StatusFail || r.Checks["shutdown"].Status != StatusFail {
+		t.Errorf("Expected readiness to fail while shutting down, got %+v", r)
+	}
+}
+
+func TestReady_Timeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	c := New(20 * time.Millisecond)
+	c.Add("stuck", func(context.Context) Result {
+		<-block
+		return Result{Status: StatusOK}
+	})
+
+	start := time.Now()
+	r := c.Ready(context.Background())
+
+	if r.Checks["stuck"].Status != StatusFail || time.Since(start) > time.Second {
+		t.Errorf("Expected a stuck check to fail after the timeout, got %+v", r.Checks["stuck"])
+	}
+}
+
+type fakeVersioner struct {
+	current int64
+	err     error
+}
+
+func (v fakeVersioner) Latest() int64                          { return 6 }
+func (v fakeVersioner) Current(context.Context) (int64, error) { return v.current, v.err }
+
+func TestMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        fakeVersioner
+		expected Status
+	}{
+		{"up to date", fakeVersioner{current: 6}, StatusOK},
+		{"behind", fakeVersioner{current: 5}, StatusFail},
+		{"ahead", fakeVersioner{current: 7}, StatusFail},
+		{"unreachable", fakeVersioner{err: errors.New("connection refused")}, StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Migrations(tt.v)(context.Background())
+			if r.Status != tt.expected {
+				t.Errorf("Expected %s, got %+v", tt.expected, r)
+			}
+		})
+	}
+}
@@ -1,10 +1,71 @@
 package logger
 
 import (
-	"log"
-	"os"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-func New() *log.Logger {
-	return log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile)
+// New builds a slog logger writing to w. format is "json" or "text", level
+// one of debug, info, warn or error. Lines logged with a context that carries
+// a request ID get a request_id attribute, and trace_id and span_id when the
+// context holds a span.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "json", "":
+		return slog.New(contextHandler{slog.NewJSONHandler(w, opts)}), nil
+	case "text":
+		return slog.New(contextHandler{slog.NewTextHandler(w, opts)}), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+func ParseLevel(s string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return lvl, nil
+}
+
+// Discard returns a logger that drops everything. It is the default for
+// components that were not given a logger.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
@@ -1,38 +1,118 @@
 package logger
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
 	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/requestid"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-func TestNewLogger_ReturnsLogger(t *testing.T) {
-	logger := New()
+func TestNew_JSONHandler(t *testing.T) {
+	var buf bytes.Buffer
+
+	log, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	log.Info("product created", "product_id", 42)
 
-	if logger == nil {
-		t.Error("Expected logger, got nil")
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON line, got %q", buf.String())
+	}
+
+	if line["msg"] != "product created" || line["level"] != "INFO" || line["product_id"] != float64(42) {
+		t.Errorf("Unexpected log line: %v", line)
 	}
 }
 
-func TestNewLogger_Type(t *testing.T) {
-	logger := New()
+func TestNew_TextHandler(t *testing.T) {
+	var buf bytes.Buffer
 
-	// Check that it's a *log.Logger
-	_, ok := interface{}(logger).(*log.Logger)
-	if !ok {
-		t.Errorf("Expected *log.Logger, got %T", logger)
+	log, err := New(&buf, "text", "debug")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	log.Debug("stock changed", "delta", -3)
+
+	if out := buf.String(); !strings.Contains(out, "level=DEBUG") || !strings.Contains(out, "delta=-3") {
+		t.Errorf("Unexpected log line: %q", out)
+	}
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+
+	log, _ := New(&buf, "json", "warn")
+	log.Info("hidden")
+	log.Warn("shown")
+
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Errorf("Expected only warn and above, got %q", out)
 	}
 }
 
-func TestLogger_CanLog(t *testing.T) {
-	logger := New()
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+
+	if _, err := New(&bytes.Buffer{}, "json", "loud"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
 
-	// Should not panic when logging
-	defer func() {
-		if r := recover(); r != nil {
-			t.Errorf("Logger panicked: %v", r)
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(s); err != nil || got != want {
+			t.Errorf("%s: expected %v, got %v (%v)", s, want, got, err)
 		}
-	}()
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	// Не должен паниковать и ничего не пишет
+	Discard().Error("dropped", "error", "boom")
+}
+
+func TestNew_RequestIDFromContext(t *testing.T) {
+	var buf bytes.Buffer
+
+	log, _ := New(&buf, "json", "info")
+	log.With("component", "test").InfoContext(requestid.NewContext(context.Background(), "abc-123"), "hello")
+
+	var line map[string]interface{}
+	json.Unmarshal(buf.Bytes(), &line)
 
-	logger.Println("Test message")
-	logger.Printf("Test format: %d\n", 42)
+	if line["request_id"] != "abc-123" || line["component"] != "test" {
+		t.Errorf("Expected request_id and component, got %v", line)
+	}
+}
+
+func TestNew_TraceIDFromContext(t *testing.T) {
+	var buf bytes.Buffer
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	log, _ := New(&buf, "json", "info")
+	log.InfoContext(ctx, "hello")
+
+	var line map[string]interface{}
+	json.Unmarshal(buf.Bytes(), &line)
+
+	if line["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || line["span_id"] != "00f067aa0ba902b7" {
+		t.Errorf("Expected trace_id and span_id, got %v", line)
+	}
 }
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+// Metrics is the set of series the service exposes on /metrics.
+type Metrics struct {
+	registry *Registry
+
+	requests *CounterVec
+	latency  *HistogramVec
+
+	productsCreated   *CounterVec
+	stockMovements    *CounterVec
+	insufficientStock *CounterVec
+}
+
+func New() *Metrics {
+	r := NewRegistry()
+
+	m := &Metrics{
+		registry: r,
+		requests: r.NewCounterVec("http_requests_total",
+			"HTTP requests by method, route template and status.", "method", "route", "status"),
+		latency: r.NewHistogramVec("http_request_duration_seconds",
+			"HTTP request latency by method, route template and status.", DefaultBuckets, "method", "route", "status"),
+		productsCreated: r.NewCounterVec("products_created_total",
+			"Products created."),
+		stockMovements: r.NewCounterVec("stock_movements_total",
+			"Stock movements recorded, by movement type.", "type"),
+		insufficientStock: r.NewCounterVec("stock_insufficient_total",
+			"Stock changes rejected because the balance would go negative."),
+	}
+
+	// The counters start at zero, so rate() and increase() see their first
+	// increment instead of a series that appears with it.
+	m.productsCreated.Add(0)
+	m.insufficientStock.Add(0)
+	for _, t := range entity.MovementTypes {
+		m.stockMovements.Add(0, string(t))
+	}
+
+	r.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+
+	return m
+}
+
+// RegisterDB exposes the sql.DBStats of the connection pool.
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	gauge := func(name, help string, fn func(sql.DBStats) float64) {
+		m.registry.NewGaugeFunc(name, help, func() float64 { return fn(db.Stats()) })
+	}
+	counter := func(name, help string, fn func(sql.DBStats) float64) {
+		m.registry.NewCounterFunc(name, help, func() float64 { return fn(db.Stats()) })
+	}
+
+	gauge("db_max_open_connections", "Maximum number of open connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("db_open_connections", "Established connections, in use and idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("db_in_use_connections", "Connections currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("db_idle_connections", "Idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("db_wait_count_total", "Connections waited for.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("db_wait_duration_seconds_total", "Time blocked waiting for a new connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("db_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) })
+	counter("db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return m.registry.Handler()
+}
+
+// ObserveRequest records one HTTP request. route must be the route template,
+// not the raw path, to keep the number of series bounded.
+func (m *Metrics) ObserveRequest(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.Inc(method, route, code)
+	m.latency.Observe(d.Seconds(), method, route, code)
+}
+
+func (m *Metrics) ProductCreated() {
+	m.productsCreated.Inc()
+}
+
+func (m *Metrics) StockMoved(movementType string) {
+	m.stockMovements.Inc(movementType)
+}
+
+func (m *Metrics) StockRejected() {
+	m.insufficientStock.Inc()
+}
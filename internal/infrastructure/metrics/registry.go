@@ -0,0 +1,281 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the text exposition format Prometheus
+// scrapes.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds metric families and writes them in the Prometheus text
+// exposition format. It covers the counters, histograms and gauges this
+// service needs without pulling in the client library.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+	names    map[string]bool
+}
+
+type family interface {
+	write(b *bytes.Buffer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+func (r *Registry) register(name string, f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.names[name] = true
+	r.families = append(r.families, f)
+}
+
+// WriteTo writes every family in registration order.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	var b bytes.Buffer
+	for _, f := range families {
+		f.write(&b)
+	}
+	return b.WriteTo(w)
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteTo(w)
+	})
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) header(b *bytes.Buffer) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ)
+}
+
+func (d *desc) check(values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+}
+
+// series is one combination of label values.
+type series struct {
+	values []string
+	value  float64
+
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+type seriesMap map[string]*series
+
+func (m seriesMap) get(values []string, buckets int) *series {
+	key := strings.Join(values, "\xff")
+	s, ok := m[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		if buckets > 0 {
+			s.buckets = make([]uint64, buckets)
+		}
+		m[key] = s
+	}
+	return s
+}
+
+func (m seriesMap) sorted() []*series {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]*series, len(keys))
+	for i, k := range keys {
+		out[i] = m[k]
+	}
+	return out
+}
+
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	series seriesMap
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name, help, "counter", labels}, series: seriesMap{}}
+	r.register(name, c)
+	return c
+}
+
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add increases the counter for values by v, which must not be negative.
+func (c *CounterVec) Add(v float64, values ...string) {
+	c.check(values)
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.name))
+	}
+
+	c.mu.Lock()
+	c.series.get(values, 0).value += v
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) write(b *bytes.Buffer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(b)
+	for _, s := range c.series.sorted() {
+		writeSample(b, c.name, c.labels, s.values, "", "", s.value)
+	}
+}
+
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  seriesMap
+}
+
+// DefaultBuckets suit request latencies in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{desc: desc{name, help, "histogram", labels}, buckets: buckets, series: seriesMap{}}
+	r.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.check(values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.series.get(values, len(h.buckets))
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.buckets[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// write emits the cumulative le buckets followed by _sum and _count.
+func (h *HistogramVec) write(b *bytes.Buffer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(b)
+	for _, s := range h.series.sorted() {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.buckets[i]
+			writeSample(b, h.name+"_bucket", h.labels, s.values, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(b, h.name+"_bucket", h.labels, s.values, "le", "+Inf", float64(s.count))
+		writeSample(b, h.name+"_sum", h.labels, s.values, "", "", s.sum)
+		writeSample(b, h.name+"_count", h.labels, s.values, "", "", float64(s.count))
+	}
+}
+
+// funcMetric reads its value when scraped, for numbers owned by someone else
+// such as the connection pool.
+type funcMetric struct {
+	desc
+	fn func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{desc: desc{name: name, help: help, typ: "gauge"}, fn: fn})
+}
+
+// NewCounterFunc registers a counter whose fn must never go down.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{desc: desc{name: name, help: help, typ: "counter"}, fn: fn})
+}
+
+func (f *funcMetric) write(b *bytes.Buffer) {
+	f.header(b)
+	writeSample(b, f.name, nil, nil, "", "", f.fn())
+}
+
+func writeSample(b *bytes.Buffer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	b.WriteString(name)
+
+	if len(labels) > 0 || extraLabel != "" {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", l, escapeLabel(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Тесты для Registry
+func TestRegistry_Counter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("jobs_total", "Jobs run.", "queue")
+	c.Inc("b")
+	c.Add(2, "a")
+	c.Inc("b")
+
+	var b strings.Builder
+	r.WriteTo(&b)
+
+	expected := "# HELP jobs_total Jobs run.\n" +
+		"# TYPE jobs_total counter\n" +
+		"jobs_total{queue=\"a\"} 2\n" +
+		"jobs_total{queue=\"b\"} 2\n"
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{1, 0.1})
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	var b strings.Builder
+	r.WriteTo(&b)
+
+	expected := "# HELP latency_seconds Latency.\n" +
+		"# TYPE latency_seconds histogram\n" +
+		"latency_seconds_bucket{le=\"0.1\"} 2\n" +
+		"latency_seconds_bucket{le=\"1\"} 3\n" +
+		"latency_seconds_bucket{le=\"+Inf\"} 4\n" +
+		"latency_seconds_sum 3.65\n" +
+		"latency_seconds_count 4\n"
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestRegistry_EscapesLabelsAndHelp(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("odd_total", "Line one\nline \\ two.", "v").Inc("say \"hi\"\n")
+
+	var b strings.Builder
+	r.WriteTo(&b)
+
+	if !strings.Contains(b.String(), "# HELP odd_total Line one\\nline \\\\ two.\n") {
+		t.Errorf("Expected escaped help, got:\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), "odd_total{v=\"say \\\"hi\\\"\\n\"} 1\n") {
+		t.Errorf("Expected escaped label, got:\n%s", b.String())
+	}
+}
+
+func TestRegistry_GaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	n := 1.0
+	r.NewGaugeFunc("queue_depth", "Depth.", func() float64 { return n })
+	n = 7
+
+	var b strings.Builder
+	r.WriteTo(&b)
+
+	if !strings.HasSuffix(b.String(), "queue_depth 7\n") {
+		t.Errorf("Expected gauge to be read at scrape time, got:\n%s", b.String())
+	}
+}
+
+func TestRegistry_Panics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("dup_total", "Dup.", "a")
+
+	cases := map[string]func(){
+		"duplicate name":    func() { r.NewCounterVec("dup_total", "Dup.") },
+		"wrong label count": func() { c.Inc() },
+		"negative add":      func() { c.Add(-1, "x") },
+	}
+	for name, fn := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
+
+func TestMetrics_StartAtZero(t *testing.T) {
+	var b strings.Builder
+	New().registry.WriteTo(&b)
+
+	for _, line := range []string{
+		"products_created_total 0",
+		`stock_movements_total{type="adjustment"} 0`,
+		`stock_movements_total{type="issue"} 0`,
+		`stock_movements_total{type="receipt"} 0`,
+		`stock_movements_total{type="transfer"} 0`,
+		"stock_insufficient_total 0",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.ProductCreated()
+	m.StockMoved("receipt")
+	m.StockRejected()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Header().Get("Content-Type") != ContentType {
+		t.Errorf("Expected content type %q, got %q", ContentType, w.Header().Get("Content-Type"))
+	}
+
+	for _, line := range []string{
+		"products_created_total 1",
+		`stock_movements_total{type="receipt"} 1`,
+		"stock_insufficient_total 1",
+		"# TYPE http_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(w.Body.String(), line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, w.Body.String())
+		}
+	}
+}
+
+type noConnector struct{}
+
+func (noConnector) Connect(context.Context) (driver.Conn, error) { return nil, errors.New("no db") }
+func (noConnector) Driver() driver.Driver                        { return nil }
+
+func TestMetrics_RegisterDB(t *testing.T) {
+	db := sql.OpenDB(noConnector{})
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+
+	m := New()
+	m.RegisterDB(db)
+
+	var b strings.Builder
+	m.registry.WriteTo(&b)
+
+	for _, line := range []string{
+		"db_max_open_connections 7",
+		"db_in_use_connections 0",
+		"# TYPE db_wait_count_total counter",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, b.String())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package requestid carries the ID that ties an HTTP request to its log lines
+// and SQL statements.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+const Header = "X-Request-ID"
+
+// An incoming ID ends up in logs and SQL comments, so anything beyond a
+// short run of safe characters is replaced with a fresh one.
+var valid = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type contextKey struct{}
+
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func Valid(id string) bool {
+	return valid.MatchString(id)
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
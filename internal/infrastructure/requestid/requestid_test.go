@@ -0,0 +1,44 @@
+package requestid
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNew_IsValidAndUnique(t *testing.T) {
+	a, b := New(), New()
+
+	if !Valid(a) || len(a) != 32 {
+		t.Errorf("Expected 32 hex characters, got %q", a)
+	}
+
+	if a == b {
+		t.Error("Expected distinct IDs")
+	}
+}
+
+func TestValid(t *testing.T) {
+	for id, want := range map[string]bool{
+		"abc-123":                 true,
+		"7f3c9a1e.req:1_x":        true,
+		"":                        false,
+		"a b":                     false,
+		"x*/ DROP TABLE products": false,
+		string(make([]byte, 129)): false,
+	} {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q): expected %t, got %t", id, want, got)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("Expected empty ID, got %q", id)
+	}
+
+	ctx := NewContext(context.Background(), "abc")
+	if id := FromContext(ctx); id != "abc" {
+		t.Errorf("Expected abc, got %q", id)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
+)
+
+type Server struct {
+	srv             *http.Server
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	onDrain         []func()
+}
+
+type Option func(*Server)
+
+// WithDrain calls fn as soon as shutdown begins, before the listener is
+// closed, e.g. to make readiness fail.
+func WithDrain(fn func()) Option {
+	return func(s *Server) {
+		s.onDrain = append(s.onDrain, fn)
+	}
+}
+
+func New(cfg *config.Config, handler http.Handler, opts ...Option) *Server {
+	s := &Server{
+		srv: &http.Server{
+			Addr:              ":" + cfg.HTTPPort,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		},
+		shutdownTimeout: cfg.ShutdownTimeout,
+		drainDelay:      cfg.ShutdownDrainDelay,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Server) Addr() string {
+	return s.srv.Addr
+}
+
+// ListenAndServe listens on the configured port and serves until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is done. It then runs the drain
+// hooks and keeps serving for the drain delay, so load balancers notice the
+// failing readiness check, before it stops accepting new connections and
+// waits up to the shutdown timeout for in-flight requests to finish;
+// connections still busy after that are closed and an error is returned.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	for _, fn := range s.onDrain {
+		fn()
+	}
+	time.Sleep(s.drainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
+		s.srv.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
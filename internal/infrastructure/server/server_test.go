@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
+)
+
+// pipeListener отдаёт серверу соединения из net.Pipe, без настоящего сокета
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+func (l *pipeListener) client() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			server, client := net.Pipe()
+			select {
+			case l.conns <- server:
+				return client, nil
+			case <-l.closed:
+				return nil, net.ErrClosed
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	}}
+}
+
+func testConfig(shutdown time.Duration) *config.Config {
+	return &config.Config{
+		HTTPPort:        "8080",
+		ReadTimeout:     time.Second,
+		WriteTimeout:    time.Second,
+		IdleTimeout:     time.Second,
+		MaxHeaderBytes:  1 << 20,
+		ShutdownTimeout: shutdown,
+	}
+}
+
+// blockingHandler держит запрос, пока тест не закроет release
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+}
+
+func TestNew_AppliesConfig(t *testing.T) {
+	cfg := testConfig(time.Second)
+	cfg.HTTPPort = "9090"
+
+	s := New(cfg, http.NotFoundHandler())
+
+	if s.Addr() != ":9090" {
+		t.Errorf("Expected addr :9090, got %s", s.Addr())
+	}
+
+	if s.srv.MaxHeaderBytes != 1<<20 || s.srv.WriteTimeout != time.Second || s.srv.IdleTimeout != time.Second {
+		t.Errorf("Unexpected server settings: %+v", s.srv)
+	}
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	s := New(testConfig(5*time.Second), blockingHandler(started, release))
+	ln := newPipeListener()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, ln) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := ln.client().Get("http://app/")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		done <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned before the request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	if r := <-done; r.err != nil || r.body != "done" {
+		t.Errorf("Expected in-flight request to complete, got %q, %v", r.body, r.err)
+	}
+
+	if err := <-served; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestServe_ShutdownDeadline(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+
+	s := New(testConfig(20*time.Millisecond), blockingHandler(started, release))
+	ln := newPipeListener()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, ln) }()
+
+	go ln.client().Get("http://app/")
+
+	<-started
+	cancel()
+
+	if err := <-served; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected shutdown deadline error, got %v", err)
+	}
+}
+
+func TestServe_DrainsBeforeClosing(t *testing.T) {
+	cfg := testConfig(time.Second)
+	cfg.ShutdownDrainDelay = 200 * time.Millisecond
+
+	drained := make(chan struct{})
+	s := New(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}), WithDrain(func() { close(drained) }))
+	ln := newPipeListener()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ctx, ln) }()
+
+	cancel()
+	<-drained
+
+	resp, err := ln.client().Get("http://app/")
+	if err != nil {
+		t.Fatalf("Expected requests to be served during the drain delay, got %v", err)
+	}
+	resp.Body.Close()
+
+	if err := <-served; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
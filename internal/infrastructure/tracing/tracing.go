@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ServiceName = "product-warehouse-api"
+
+var Exporters = []string{"none", "stdout", "otlp"}
+
+// Setup installs the global tracer provider for exporter, one of Exporters,
+// and the W3C trace context propagator. The OTLP exporter is configured with
+// the standard OTEL_EXPORTER_OTLP_* variables. The returned function flushes
+// buffered spans and must be called before exiting.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "none", "":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exp, err = stdouttrace.New()
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// ProductIDKey is the span attribute holding the product a call is about.
+const ProductIDKey = attribute.Key("product.id")
+
+// ReservationIDKey is the span attribute holding the reservation a call is about.
+const ReservationIDKey = attribute.Key("reservation.id")
+
+// End records *err on span and ends it, meant to be deferred with a named
+// error result. Domain errors are kept as events without marking the span
+// failed, since they are answers, not faults.
+func End(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		if !entity.IsDomainError(*err) {
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,106 @@
+package product
+
+import (
+	"context"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+)
+
+// maxInsertRows is the most products inserted by one multi-row statement.
+const maxInsertRows = 1000
+
+// batchSteps are the statements a SQL repository applies batch operations
+// with, all inside the batch transaction.
+type batchSteps struct {
+	// createMany inserts a run of consecutive creates at once. Without it
+	// every product is inserted on its own.
+	createMany func(ctx context.Context, tx *db.Tx, products []*entity.Product) error
+	create     func(ctx context.Context, tx *db.Tx, p *entity.Product) error
+	update     func(ctx context.Context, tx *db.Tx, id int64, p *entity.Product) error
+	delete     func(ctx context.Context, q querier, id int64, version int64) error
+}
+
+// runBatch applies ops in order inside tx and leaves the error of each failed
+// operation in its Err. An atomic batch stops at the first failure and
+// returns it, so the caller rolls everything back. Otherwise every operation
+// runs under a savepoint, which undoes a failed one on its own, and the rest
+// go on. A run of creates that fails as a whole is retried one by one to find
+// the products at fault. Errors that are not domain errors end the batch
+// either way.
+func runBatch(ctx context.Context, tx *db.Tx, ops []*entity.BatchOperation, atomic bool, steps batchSteps) error {
+	for i := 0; i < len(ops); {
+		run := 1
+		if steps.createMany != nil && ops[i].Action == entity.BatchCreate {
+			for run < maxInsertRows && i+run < len(ops) && ops[i+run].Action == entity.BatchCreate {
+				run++
+			}
+		}
+
+		if run > 1 {
+			products := make([]*entity.Product, run)
+			for j, op := range ops[i : i+run] {
+				products[j] = op.Product
+			}
+
+			err := savepoint(ctx, tx, func() error { return steps.createMany(ctx, tx, products) })
+			if err == nil {
+				i += run
+				continue
+			}
+			if !entity.IsDomainError(err) {
+				return err
+			}
+		}
+
+		for _, op := range ops[i : i+run] {
+			apply := func() error { return applyBatchOperation(ctx, tx, op, steps) }
+			if atomic {
+				op.Err = apply()
+			} else {
+				op.Err = savepoint(ctx, tx, apply)
+			}
+
+			if op.Err != nil && (atomic || !entity.IsDomainError(op.Err)) {
+				return op.Err
+			}
+		}
+		i += run
+	}
+
+	return nil
+}
+
+func applyBatchOperation(ctx context.Context, tx *db.Tx, op *entity.BatchOperation, steps batchSteps) error {
+	switch op.Action {
+	case entity.BatchCreate:
+		return steps.create(ctx, tx, op.Product)
+	case entity.BatchUpdate:
+		op.Product.ID = op.ID
+		op.Product.Version = op.Version
+		return steps.update(ctx, tx, op.ID, op.Product)
+	default:
+		return steps.delete(ctx, tx, op.ID, op.Version)
+	}
+}
+
+// savepoint runs fn so that, if it fails, only its own changes are undone and
+// the transaction stays usable.
+func savepoint(ctx context.Context, tx *db.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_item`); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rerr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_item`); rerr != nil {
+			return rerr
+		}
+		if _, rerr := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_item`); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT batch_item`)
+	return err
+}
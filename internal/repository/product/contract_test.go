@@ -0,0 +1,78 @@
+package product_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+	productRepo "github.com/imbafff/product-warehouse-api/internal/repository/product"
+	"github.com/imbafff/product-warehouse-api/internal/repository/product/repositorytest"
+	warehouseRepo "github.com/imbafff/product-warehouse-api/internal/repository/warehouse"
+	"github.com/imbafff/product-warehouse-api/migrations"
+	sqliteMigrations "github.com/imbafff/product-warehouse-api/migrations/sqlite"
+)
+
+// Контрактные тесты на реальной БД, только если задан TEST_DATABASE_URL.
+// Таблицы очищаются перед каждым случаем.
+func TestPostgresRepository_Contract(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping Postgres contract tests: TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+
+	database, err := db.NewPostgresDB(ctx, &config.Config{DatabaseURL: dsn})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer database.Close()
+
+	migrator, err := db.NewMigrator(database, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		_, err := database.Exec(`TRUNCATE products, stock_movements, product_stock, reservations, locations, warehouses RESTART IDENTITY CASCADE`)
+		if err != nil {
+			t.Fatalf("Failed to clean tables: %v", err)
+		}
+		return repositorytest.Backend{
+			Products:   productRepo.NewPostgresRepository(database),
+			Warehouses: warehouseRepo.NewPostgresRepository(database),
+		}
+	})
+}
+
+// Контрактные тесты на SQLite: каждый случай получает новый файл БД
+func TestSQLiteRepository_Contract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		ctx := context.Background()
+
+		database, err := db.NewSQLiteDB(ctx, &config.Config{SQLitePath: filepath.Join(t.TempDir(), "warehouse.db")})
+		if err != nil {
+			t.Fatalf("Failed to open: %v", err)
+		}
+		t.Cleanup(func() { database.Close() })
+
+		migrator, err := db.NewMigrator(database, sqliteMigrations.FS, db.WithDialect(db.SQLite))
+		if err != nil {
+			t.Fatalf("Failed to load migrations: %v", err)
+		}
+		if err := migrator.Up(ctx); err != nil {
+			t.Fatalf("Failed to migrate: %v", err)
+		}
+
+		return repositorytest.Backend{
+			Products:   productRepo.NewSQLiteRepository(database),
+			Warehouses: warehouseRepo.NewSQLiteRepository(database),
+		}
+	})
+}
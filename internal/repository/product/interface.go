@@ -1,11 +1,29 @@
 package product
 
-import "github.com/imbafff/product-warehouse-api/internal/entity"
+import (
+	"context"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
 
 type Repository interface {
-	Create(product *entity.Product) (int64, error)
-	GetByID(id int64) (*entity.Product, error)
-	Update(id int64, product *entity.Product) error
-	Delete(id int64) error
-	GetAll() ([]*entity.Product, error)
+	Create(ctx context.Context, product *entity.Product) (int64, error)
+	GetByID(ctx context.Context, id int64) (*entity.Product, error)
+	GetBySKU(ctx context.Context, sku string) (*entity.Product, error)
+	GetByBarcode(ctx context.Context, code string) (*entity.Product, error)
+	Update(ctx context.Context, id int64, product *entity.Product) error
+	Delete(ctx context.Context, id int64, version int64) error
+	Batch(ctx context.Context, ops []*entity.BatchOperation, atomic bool) error
+	Import(ctx context.Context, key entity.ImportKey, keys []string, build func(found []*entity.Product) []*entity.BatchOperation) error
+	GetAll(ctx context.Context, query entity.ProductQuery) (*entity.ProductPage, error)
+	AddMovement(ctx context.Context, productID int64, movement *entity.StockMovement) (int64, error)
+	ChangeQuantity(ctx context.Context, productID int64, movement *entity.StockMovement) error
+	GetMovements(ctx context.Context, productID int64) ([]*entity.StockMovement, error)
+	GetStock(ctx context.Context, productID int64, warehouseID *int64) (*entity.StockBreakdown, error)
+	Reserve(ctx context.Context, productID int64, reservation *entity.Reservation) error
+	GetReservation(ctx context.Context, id int64) (*entity.Reservation, error)
+	ConfirmReservation(ctx context.Context, id int64, now time.Time, movement *entity.StockMovement) (*entity.Reservation, error)
+	ReleaseReservation(ctx context.Context, id int64) (*entity.Reservation, error)
+	ExpireReservations(ctx context.Context, now time.Time) (int, error)
 }
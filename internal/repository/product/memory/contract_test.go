@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/repository/product/repositorytest"
+)
+
+func TestRepository_Contract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		r := newRepo(t)
+		return repositorytest.Backend{Products: r, Warehouses: r.Warehouses()}
+	})
+}
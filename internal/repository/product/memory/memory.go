@@ -0,0 +1,945 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+// Repository keeps products, their stock ledger and, because stock lives in
+// locations, the warehouses too, all behind one lock. It mirrors the
+// behavior of the Postgres repositories and is meant for local development
+// and tests.
+type Repository struct {
+	mu   sync.RWMutex
+	data state
+	path string
+	now  func() time.Time
+}
+
+// state is everything the repository holds, in the shape of the snapshot file.
+type state struct {
+	Products     map[int64]*entity.Product         `json:"products"`
+	Movements    map[int64][]*entity.StockMovement `json:"movements"`
+	Stock        map[int64]map[int64]int           `json:"stock"`
+	Reservations map[int64]*entity.Reservation     `json:"reservations"`
+	Warehouses   map[int64]*entity.Warehouse       `json:"warehouses"`
+	Locations    map[int64]*entity.Location        `json:"locations"`
+	Sequences    map[string]int64                  `json:"sequences"`
+}
+
+type Option func(*Repository)
+
+// WithSnapshot loads the repository from the JSON file at path, if it
+// exists, and rewrites the file after every change.
+func WithSnapshot(path string) Option {
+	return func(r *Repository) {
+		r.path = path
+	}
+}
+
+func New(opts ...Option) (*Repository, error) {
+	r := &Repository{
+		data: state{
+			Products:     map[int64]*entity.Product{},
+			Movements:    map[int64][]*entity.StockMovement{},
+			Stock:        map[int64]map[int64]int{},
+			Reservations: map[int64]*entity.Reservation{},
+			Warehouses:   map[int64]*entity.Warehouse{},
+			Locations:    map[int64]*entity.Location{},
+			Sequences:    map[string]int64{},
+		},
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.path != "" {
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// copy returns a deep copy of s, to put back when a change fails halfway.
+func (s state) copy() state {
+	c := state{
+		Products:     make(map[int64]*entity.Product, len(s.Products)),
+		Movements:    make(map[int64][]*entity.StockMovement, len(s.Movements)),
+		Stock:        make(map[int64]map[int64]int, len(s.Stock)),
+		Reservations: make(map[int64]*entity.Reservation, len(s.Reservations)),
+		Warehouses:   make(map[int64]*entity.Warehouse, len(s.Warehouses)),
+		Locations:    make(map[int64]*entity.Location, len(s.Locations)),
+		Sequences:    maps.Clone(s.Sequences),
+	}
+	for k, v := range s.Products {
+		c.Products[k] = clone(v)
+	}
+	for k, v := range s.Movements {
+		movements := make([]*entity.StockMovement, len(v))
+		for i, m := range v {
+			cp := *m
+			movements[i] = &cp
+		}
+		c.Movements[k] = movements
+	}
+	for k, v := range s.Stock {
+		c.Stock[k] = maps.Clone(v)
+	}
+	for k, v := range s.Reservations {
+		cp := *v
+		c.Reservations[k] = &cp
+	}
+	for k, v := range s.Warehouses {
+		cp := *v
+		c.Warehouses[k] = &cp
+	}
+	for k, v := range s.Locations {
+		cp := *v
+		c.Locations[k] = &cp
+	}
+	return c
+}
+
+func (r *Repository) load() error {
+	raw, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+
+	if err := json.Unmarshal(raw, &r.data); err != nil {
+		return fmt.Errorf("parse snapshot %s: %w", r.path, err)
+	}
+	return nil
+}
+
+// save writes the snapshot through a temporary file, so a crash never leaves
+// a half-written one behind. Callers hold the write lock.
+func (r *Repository) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	raw, err := json.Marshal(r.data)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	return nil
+}
+
+// change runs fn and saves the snapshot. fn checks everything before it
+// changes anything, so a failing fn leaves the state alone. If saving fails,
+// the state is put back as it was, so what is in memory never runs ahead of
+// the file; without a snapshot there is nothing to copy for that. Callers
+// hold the write lock.
+func (r *Repository) change(fn func() error) error {
+	if r.path == "" {
+		return fn()
+	}
+
+	return r.undoOnError(func() error {
+		if err := fn(); err != nil {
+			return err
+		}
+		return r.save()
+	})
+}
+
+// undoOnError runs fn and puts the state back as it was if fn fails.
+func (r *Repository) undoOnError(fn func() error) error {
+	before := r.data.copy()
+
+	err := fn()
+	if err != nil {
+		r.data = before
+	}
+	return err
+}
+
+func (r *Repository) next(sequence string) int64 {
+	r.data.Sequences[sequence]++
+	return r.data.Sequences[sequence]
+}
+
+func (r *Repository) Create(ctx context.Context, p *entity.Product) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.change(func() error { return r.create(p) }); err != nil {
+		return 0, err
+	}
+
+	return p.ID, nil
+}
+
+func (r *Repository) create(p *entity.Product) error {
+	if err := r.checkCodes(0, p); err != nil {
+		return err
+	}
+
+	stored := clone(p)
+	stored.ID = r.next("products")
+	stored.Version = 1
+	r.data.Products[stored.ID] = stored
+
+	if stored.Quantity != 0 {
+		r.appendMovement(&entity.StockMovement{
+			ProductID: stored.ID,
+			Type:      entity.MovementReceipt,
+			Delta:     stored.Quantity,
+			Balance:   stored.Quantity,
+			Reason:    "initial_stock",
+		})
+	}
+
+	p.ID = stored.ID
+	p.Version = stored.Version
+	return nil
+}
+
+func (r *Repository) GetByID(ctx context.Context, id int64) (*entity.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.data.Products[id]
+	if !ok {
+		return nil, entity.ErrProductNotFound
+	}
+
+	return clone(p), nil
+}
+
+func (r *Repository) GetBySKU(ctx context.Context, sku string) (*entity.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.data.Products {
+		if p.SKU != "" && strings.EqualFold(p.SKU, sku) {
+			return clone(p), nil
+		}
+	}
+
+	return nil, entity.ErrProductNotFound
+}
+
+func (r *Repository) GetByBarcode(ctx context.Context, code string) (*entity.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	gtin := entity.GTIN14(code)
+	for _, p := range r.data.Products {
+		for _, c := range p.Barcodes {
+			if entity.GTIN14(c) == gtin {
+				return clone(p), nil
+			}
+		}
+	}
+
+	return nil, entity.ErrProductNotFound
+}
+
+// checkCodes makes sure no product other than id holds the SKU or one of the
+// barcodes of p.
+func (r *Repository) checkCodes(id int64, p *entity.Product) error {
+	gtins := make(map[string]bool, len(p.Barcodes))
+	for _, c := range p.Barcodes {
+		gtins[entity.GTIN14(c)] = true
+	}
+
+	for _, other := range r.data.Products {
+		if other.ID == id {
+			continue
+		}
+		if p.SKU != "" && strings.EqualFold(other.SKU, p.SKU) {
+			return entity.ErrSKUTaken
+		}
+		for _, c := range other.Barcodes {
+			if gtins[entity.GTIN14(c)] {
+				return entity.ErrBarcodeTaken
+			}
+		}
+	}
+
+	return nil
+}
+
+// clone copies p along with its barcodes, so callers never share a slice
+// with the store.
+func clone(p *entity.Product) *entity.Product {
+	cp := *p
+	cp.Barcodes = append([]string{}, p.Barcodes...)
+	return &cp
+}
+
+func (r *Repository) Update(ctx context.Context, id int64, p *entity.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.change(func() error { return r.update(id, p) })
+}
+
+func (r *Repository) update(id int64, p *entity.Product) error {
+	current, ok := r.data.Products[id]
+	if !ok {
+		return entity.ErrProductNotFound
+	}
+
+	if p.Version != 0 && p.Version != current.Version {
+		return entity.ErrVersionConflict
+	}
+
+	if err := r.checkCodes(id, p); err != nil {
+		return err
+	}
+
+	if p.Quantity < r.allocated(id) || p.Quantity < r.reserved(id) {
+		return entity.ErrInsufficientStock
+	}
+
+	delta := p.Quantity - current.Quantity
+
+	updated := clone(p)
+	updated.ID = id
+	updated.Version = current.Version + 1
+	r.data.Products[id] = updated
+
+	if delta != 0 {
+		r.appendMovement(&entity.StockMovement{
+			ProductID: id,
+			Type:      entity.MovementAdjustment,
+			Delta:     delta,
+			Balance:   p.Quantity,
+			Reason:    "manual_update",
+		})
+	}
+
+	p.Version = updated.Version
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.change(func() error { return r.delete(id, version) })
+}
+
+func (r *Repository) delete(id int64, version int64) error {
+	current, ok := r.data.Products[id]
+	if !ok {
+		return entity.ErrProductNotFound
+	}
+
+	if version != 0 && version != current.Version {
+		return entity.ErrVersionConflict
+	}
+
+	delete(r.data.Products, id)
+	delete(r.data.Movements, id)
+	delete(r.data.Stock, id)
+	for resID, res := range r.data.Reservations {
+		if res.ProductID == id {
+			delete(r.data.Reservations, resID)
+		}
+	}
+
+	return nil
+}
+
+// Batch applies ops in order under one lock and saves the snapshot once. An
+// atomic batch stops at the first failure and puts the state back as it was.
+func (r *Repository) Batch(ctx context.Context, ops []*entity.BatchOperation, atomic bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run := func() error { return r.batch(ops, atomic) }
+
+	// Without a snapshot change keeps no copy to undo the operations that
+	// went through before the failing one.
+	if atomic && r.path == "" {
+		return r.undoOnError(run)
+	}
+	return r.change(run)
+}
+
+// Import hands the products matching keys to build and applies the
+// operations it returns like a best-effort Batch, all under one lock.
+func (r *Repository) Import(ctx context.Context, key entity.ImportKey, keys []string, build func(found []*entity.Product) []*entity.BatchOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		wanted[strings.ToUpper(k)] = true
+	}
+
+	found := []*entity.Product{}
+	for _, p := range r.data.Products {
+		k := strconv.FormatInt(p.ID, 10)
+		if key == entity.ImportBySKU {
+			k = strings.ToUpper(p.SKU)
+		}
+		if k != "" && wanted[k] {
+			found = append(found, clone(p))
+		}
+	}
+
+	ops := build(found)
+	return r.change(func() error { return r.batch(ops, false) })
+}
+
+// batch applies ops in order. Callers hold the write lock.
+func (r *Repository) batch(ops []*entity.BatchOperation, atomic bool) error {
+	for _, op := range ops {
+		switch op.Action {
+		case entity.BatchCreate:
+			op.Err = r.create(op.Product)
+		case entity.BatchUpdate:
+			op.Product.ID = op.ID
+			op.Product.Version = op.Version
+			op.Err = r.update(op.ID, op.Product)
+		default:
+			op.Err = r.delete(op.ID, op.Version)
+		}
+
+		if op.Err != nil && atomic {
+			return op.Err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) AddMovement(ctx context.Context, productID int64, m *entity.StockMovement) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.change(func() error { return r.addMovement(productID, m) }); err != nil {
+		return 0, err
+	}
+
+	return m.ID, nil
+}
+
+func (r *Repository) addMovement(productID int64, m *entity.StockMovement) error {
+	p, ok := r.data.Products[productID]
+	if !ok {
+		return entity.ErrProductNotFound
+	}
+
+	total, allocated, located := p.Quantity, r.allocated(productID), 0
+	if m.LocationID != nil {
+		if _, ok := r.data.Locations[*m.LocationID]; !ok {
+			return entity.ErrLocationNotFound
+		}
+		located = r.data.Stock[productID][*m.LocationID]
+	}
+
+	newTotal, newAllocated := total+m.Delta, allocated
+	if m.Type == entity.MovementTransfer {
+		newTotal = total
+	}
+	if m.LocationID != nil {
+		located += m.Delta
+		newAllocated += m.Delta
+	}
+
+	if newTotal < 0 || located < 0 || newTotal < newAllocated || newTotal < r.reserved(productID) {
+		return entity.ErrInsufficientStock
+	}
+
+	if newTotal != total {
+		p.Quantity = newTotal
+		p.Version++
+	}
+
+	if m.LocationID != nil {
+		r.setStock(productID, *m.LocationID, located)
+	}
+
+	m.ProductID = productID
+	m.Balance = newTotal
+	r.appendMovement(m)
+
+	return nil
+}
+
+func (r *Repository) ChangeQuantity(ctx context.Context, productID int64, m *entity.StockMovement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.change(func() error { return r.changeQuantity(productID, m) })
+}
+
+func (r *Repository) changeQuantity(productID int64, m *entity.StockMovement) error {
+	p, ok := r.data.Products[productID]
+	if !ok {
+		return entity.ErrProductNotFound
+	}
+
+	quantity := p.Quantity + m.Delta
+	if quantity < 0 || quantity < r.allocated(productID) || quantity < r.reserved(productID) {
+		return entity.ErrInsufficientStock
+	}
+
+	p.Quantity = quantity
+	p.Version++
+
+	m.ProductID = productID
+	m.Balance = quantity
+	r.appendMovement(m)
+
+	return nil
+}
+
+func (r *Repository) GetMovements(ctx context.Context, productID int64) ([]*entity.StockMovement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.data.Products[productID]; !ok {
+		return nil, entity.ErrProductNotFound
+	}
+
+	movements := make([]*entity.StockMovement, 0, len(r.data.Movements[productID]))
+	for _, m := range r.data.Movements[productID] {
+		cp := *m
+		movements = append(movements, &cp)
+	}
+
+	return movements, nil
+}
+
+func (r *Repository) GetStock(ctx context.Context, productID int64, warehouseID *int64) (*entity.StockBreakdown, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.data.Products[productID]
+	if !ok {
+		return nil, entity.ErrProductNotFound
+	}
+
+	reserved := r.reserved(productID)
+	b := &entity.StockBreakdown{
+		ProductID:   productID,
+		Quantity:    p.Quantity,
+		Unallocated: p.Quantity - r.allocated(productID),
+		Reserved:    reserved,
+		Available:   p.Quantity - reserved,
+		Locations:   []*entity.StockLevel{},
+	}
+
+	for locationID, quantity := range r.data.Stock[productID] {
+		l := r.data.Locations[locationID]
+		if quantity <= 0 || (warehouseID != nil && l.WarehouseID != *warehouseID) {
+			continue
+		}
+		b.Locations = append(b.Locations, &entity.StockLevel{
+			WarehouseID:  l.WarehouseID,
+			LocationID:   l.ID,
+			LocationCode: l.Code,
+			Quantity:     quantity,
+		})
+	}
+
+	sort.Slice(b.Locations, func(i, j int) bool {
+		a, c := b.Locations[i], b.Locations[j]
+		if a.WarehouseID != c.WarehouseID {
+			return a.WarehouseID < c.WarehouseID
+		}
+		return a.LocationCode < c.LocationCode
+	})
+
+	return b, nil
+}
+
+// Reserve holds stock for res. Holds of the product that have expired by
+// res.CreatedAt are expired first, so they do not block the new one.
+func (r *Repository) Reserve(ctx context.Context, productID int64, res *entity.Reservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.change(func() error { return r.reserve(productID, res) })
+}
+
+func (r *Repository) reserve(productID int64, res *entity.Reservation) error {
+	p, ok := r.data.Products[productID]
+	if !ok {
+		return entity.ErrProductNotFound
+	}
+
+	due := r.expirable(productID, res.CreatedAt)
+	stale := 0
+	for _, expired := range due {
+		stale += expired.Quantity
+	}
+
+	if p.Quantity-r.reserved(productID)+stale < res.Quantity {
+		return entity.ErrInsufficientStock
+	}
+
+	for _, expired := range due {
+		expired.Status = entity.ReservationExpired
+	}
+
+	res.ID = r.next("reservations")
+	res.ProductID = productID
+	res.Status = entity.ReservationActive
+
+	cp := *res
+	r.data.Reservations[res.ID] = &cp
+
+	return nil
+}
+
+func (r *Repository) GetReservation(ctx context.Context, id int64) (*entity.Reservation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res, ok := r.data.Reservations[id]
+	if !ok {
+		return nil, entity.ErrReservationNotFound
+	}
+
+	cp := *res
+	return &cp, nil
+}
+
+func (r *Repository) ConfirmReservation(ctx context.Context, id int64, now time.Time, m *entity.StockMovement) (*entity.Reservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var res *entity.Reservation
+	err := r.change(func() (err error) {
+		res, err = r.confirmReservation(id, now, m)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cp := *res
+	return &cp, nil
+}
+
+func (r *Repository) confirmReservation(id int64, now time.Time, m *entity.StockMovement) (*entity.Reservation, error) {
+	res, err := r.activeReservation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !now.Before(res.ExpiresAt) {
+		return nil, entity.ErrReservationExpired
+	}
+
+	p := r.data.Products[res.ProductID]
+	balance := p.Quantity - res.Quantity
+	if balance < r.allocated(res.ProductID) {
+		return nil, entity.ErrInsufficientStock
+	}
+
+	p.Quantity = balance
+	p.Version++
+	res.Status = entity.ReservationConfirmed
+
+	m.ProductID = res.ProductID
+	m.Delta = -res.Quantity
+	m.Balance = balance
+	m.Reference = res.Reference
+	r.appendMovement(m)
+
+	return res, nil
+}
+
+func (r *Repository) ReleaseReservation(ctx context.Context, id int64) (*entity.Reservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var res *entity.Reservation
+	err := r.change(func() (err error) {
+		res, err = r.activeReservation(id)
+		if err == nil {
+			res.Status = entity.ReservationReleased
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cp := *res
+	return &cp, nil
+}
+
+func (r *Repository) ExpireReservations(ctx context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := r.expirable(0, now)
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	err := r.change(func() error {
+		for _, res := range due {
+			res.Status = entity.ReservationExpired
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(due), nil
+}
+
+func (r *Repository) activeReservation(id int64) (*entity.Reservation, error) {
+	res, ok := r.data.Reservations[id]
+	if !ok {
+		return nil, entity.ErrReservationNotFound
+	}
+	if res.Status != entity.ReservationActive {
+		return nil, entity.ErrReservationClosed
+	}
+	return res, nil
+}
+
+// expirable returns the active reservations that have expired by now, of one
+// product or, with productID 0, of all of them.
+func (r *Repository) expirable(productID int64, now time.Time) []*entity.Reservation {
+	var due []*entity.Reservation
+	for _, res := range r.data.Reservations {
+		if res.Status != entity.ReservationActive || now.Before(res.ExpiresAt) {
+			continue
+		}
+		if productID != 0 && res.ProductID != productID {
+			continue
+		}
+		due = append(due, res)
+	}
+	return due
+}
+
+func (r *Repository) GetAll(ctx context.Context, q entity.ProductQuery) (*entity.ProductPage, error) {
+	compare, ok := comparators[q.SortField]
+	if !ok {
+		return nil, entity.NewValidationError("sort", "unsupported", "unsupported sort field")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*entity.Product
+	for _, p := range r.data.Products {
+		if r.matches(p, q.Filter) {
+			matched = append(matched, p)
+		}
+	}
+
+	less := func(a, b *entity.Product) bool {
+		c := compare(a, b)
+		if c == 0 {
+			c = compareInts(a.ID, b.ID)
+		}
+		if q.SortDesc {
+			return c > 0
+		}
+		return c < 0
+	}
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	page := &entity.ProductPage{Items: []*entity.Product{}}
+
+	if q.WithTotal {
+		total := int64(len(matched))
+		page.Total = &total
+	}
+
+	if q.After != nil {
+		after, err := cursorProduct(q.After, q.SortField)
+		if err != nil {
+			return nil, err
+		}
+		i := sort.Search(len(matched), func(i int) bool { return less(after, matched[i]) })
+		matched = matched[i:]
+	}
+
+	matched = matched[min(q.Offset, len(matched)):]
+
+	for _, p := range matched[:min(q.Limit+1, len(matched))] {
+		page.Items = append(page.Items, clone(p))
+	}
+
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.Next = &entity.Cursor{
+			Sort:  q.SortField,
+			Desc:  q.SortDesc,
+			Value: sortValue(last, q.SortField),
+			ID:    last.ID,
+		}
+	}
+
+	return page, nil
+}
+
+func (r *Repository) matches(p *entity.Product, f entity.ProductFilter) bool {
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if f.MinPrice != nil && p.Price < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && p.Price > *f.MaxPrice {
+		return false
+	}
+	if f.MinQuantity != nil && p.Quantity < *f.MinQuantity {
+		return false
+	}
+	if f.MaxQuantity != nil && p.Quantity > *f.MaxQuantity {
+		return false
+	}
+	if f.WarehouseID != nil {
+		for locationID, quantity := range r.data.Stock[p.ID] {
+			if quantity > 0 && r.data.Locations[locationID].WarehouseID == *f.WarehouseID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// allocated is the part of a product's quantity put away into locations.
+func (r *Repository) allocated(productID int64) int {
+	sum := 0
+	for _, quantity := range r.data.Stock[productID] {
+		sum += quantity
+	}
+	return sum
+}
+
+// reserved is the part of a product's quantity held by active reservations.
+func (r *Repository) reserved(productID int64) int {
+	sum := 0
+	for _, res := range r.data.Reservations {
+		if res.ProductID == productID && res.Status == entity.ReservationActive {
+			sum += res.Quantity
+		}
+	}
+	return sum
+}
+
+func (r *Repository) setStock(productID, locationID int64, quantity int) {
+	if quantity == 0 {
+		delete(r.data.Stock[productID], locationID)
+		return
+	}
+	if r.data.Stock[productID] == nil {
+		r.data.Stock[productID] = map[int64]int{}
+	}
+	r.data.Stock[productID][locationID] = quantity
+}
+
+func (r *Repository) appendMovement(m *entity.StockMovement) {
+	m.ID = r.next("movements")
+	m.CreatedAt = r.now()
+
+	cp := *m
+	r.data.Movements[m.ProductID] = append(r.data.Movements[m.ProductID], &cp)
+}
+
+var comparators = map[string]func(a, b *entity.Product) int{
+	"id": func(a, b *entity.Product) int { return 0 },
+	"name": func(a, b *entity.Product) int {
+		return strings.Compare(a.Name, b.Name)
+	},
+	"price": func(a, b *entity.Product) int {
+		return compareInts(int64(a.Price), int64(b.Price))
+	},
+	"quantity": func(a, b *entity.Product) int {
+		return compareInts(int64(a.Quantity), int64(b.Quantity))
+	},
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cursorProduct turns a cursor back into a product carrying just the sort
+// value and id, to compare against with the same ordering as the page.
+func cursorProduct(c *entity.Cursor, field string) (*entity.Product, error) {
+	p := &entity.Product{ID: c.ID}
+
+	var err error
+	switch field {
+	case "name":
+		p.Name = c.Value
+	case "price":
+		p.Price, err = entity.ParseMoney(c.Value)
+	case "quantity":
+		p.Quantity, err = strconv.Atoi(c.Value)
+	}
+	if err != nil {
+		return nil, entity.ErrInvalidCursor
+	}
+
+	return p, nil
+}
+
+func sortValue(p *entity.Product, field string) string {
+	switch field {
+	case "name":
+		return p.Name
+	case "price":
+		return p.Price.String()
+	case "quantity":
+		return strconv.Itoa(p.Quantity)
+	default:
+		return strconv.FormatInt(p.ID, 10)
+	}
+}
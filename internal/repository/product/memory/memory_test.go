@@ -0,0 +1,288 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+func newRepo(t *testing.T, opts ...Option) *Repository {
+	t.Helper()
+	r, err := New(opts...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return r
+}
+
+// Тесты для memory Repository
+func TestRepository_CreateAndGet(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+
+	p := &entity.Product{Name: "Widget", Price: 1999, Currency: "USD", Quantity: 5}
+	id, err := r.Create(ctx, p)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if id != 1 || p.Version != 1 {
+		t.Errorf("Expected id 1 and version 1, got %d and %d", id, p.Version)
+	}
+
+	got, err := r.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got.Name = "changed"
+
+	again, _ := r.GetByID(ctx, id)
+	if again.Name != "Widget" {
+		t.Error("Expected GetByID to return a copy")
+	}
+
+	movements, _ := r.GetMovements(ctx, id)
+	if len(movements) != 1 || movements[0].Reason != "initial_stock" || movements[0].Balance != 5 {
+		t.Errorf("Expected an initial_stock receipt, got %+v", movements)
+	}
+
+	if _, err := r.GetByID(ctx, 99); !errors.Is(err, entity.ErrProductNotFound) {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+}
+
+func TestRepository_UpdateAndDeleteCheckVersion(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+
+	id, _ := r.Create(ctx, &entity.Product{Name: "Widget", Quantity: 1})
+
+	if err := r.Update(ctx, id, &entity.Product{Name: "Gadget", Quantity: 3, Version: 2}); !errors.Is(err, entity.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	p := &entity.Product{Name: "Gadget", Quantity: 3, Version: 1}
+	if err := r.Update(ctx, id, p); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Version != 2 {
+		t.Errorf("Expected version 2, got %d", p.Version)
+	}
+
+	movements, _ := r.GetMovements(ctx, id)
+	if last := movements[len(movements)-1]; last.Type != entity.MovementAdjustment || last.Delta != 2 {
+		t.Errorf("Expected an adjustment of 2, got %+v", last)
+	}
+
+	if err := r.Delete(ctx, id, 1); !errors.Is(err, entity.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+	if err := r.Delete(ctx, id, 2); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := r.Delete(ctx, id, 0); !errors.Is(err, entity.ErrProductNotFound) {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+}
+
+func TestRepository_StockInLocations(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	w := r.Warehouses()
+
+	whID, _ := w.Create(ctx, &entity.Warehouse{Code: "MAIN", Name: "Main"})
+	locID, _ := w.CreateLocation(ctx, &entity.Location{WarehouseID: whID, Code: "A-01"})
+	id, _ := r.Create(ctx, &entity.Product{Name: "Widget", Quantity: 10})
+
+	if _, err := r.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementTransfer, Delta: 4, LocationID: &locID}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stock, err := r.GetStock(ctx, id, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stock.Quantity != 10 || stock.Unallocated != 6 || len(stock.Locations) != 1 || stock.Locations[0].LocationCode != "A-01" {
+		t.Errorf("Expected 4 of 10 in A-01, got %+v", stock)
+	}
+
+	if err := r.Update(ctx, id, &entity.Product{Name: "Widget", Quantity: 3}); !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock below the allocated quantity, got %v", err)
+	}
+
+	if err := w.DeleteLocation(ctx, locID); !errors.Is(err, entity.ErrLocationNotEmpty) {
+		t.Errorf("Expected ErrLocationNotEmpty, got %v", err)
+	}
+	if err := w.Delete(ctx, whID); !errors.Is(err, entity.ErrWarehouseNotEmpty) {
+		t.Errorf("Expected ErrWarehouseNotEmpty, got %v", err)
+	}
+
+	if _, err := r.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementTransfer, Delta: -4, LocationID: &locID}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := w.DeleteLocation(ctx, locID); err != nil {
+		t.Fatalf("Expected an emptied location to be deleted, got %v", err)
+	}
+
+	movements, _ := r.GetMovements(ctx, id)
+	for _, m := range movements {
+		if m.LocationID != nil {
+			t.Errorf("Expected movements to lose the deleted location, got %d", *m.LocationID)
+		}
+	}
+
+	missing := int64(42)
+	if _, err := r.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementReceipt, Delta: 1, LocationID: &missing}); !errors.Is(err, entity.ErrLocationNotFound) {
+		t.Errorf("Expected ErrLocationNotFound, got %v", err)
+	}
+}
+
+func TestRepository_GetAllPagesDeterministically(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+
+	for _, name := range []string{"b", "a", "c", "a"} {
+		r.Create(ctx, &entity.Product{Name: name})
+	}
+
+	q := entity.ProductQuery{SortField: "name", Limit: 3, WithTotal: true}
+	page, err := r.GetAll(ctx, q)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *page.Total != 4 || len(page.Items) != 3 || page.Next == nil {
+		t.Fatalf("Expected 3 of 4 items and a cursor, got %+v", page)
+	}
+
+	var ids []int64
+	for _, p := range page.Items {
+		ids = append(ids, p.ID)
+	}
+
+	q.After = page.Next
+	page, _ = r.GetAll(ctx, q)
+	for _, p := range page.Items {
+		ids = append(ids, p.ID)
+	}
+
+	want := []int64{2, 4, 1, 3}
+	for i := range want {
+		if i >= len(ids) || ids[i] != want[i] {
+			t.Fatalf("Expected ids %v, got %v", want, ids)
+		}
+	}
+	if page.Next != nil {
+		t.Error("Expected no cursor on the last page")
+	}
+
+	if _, err := r.GetAll(ctx, entity.ProductQuery{SortField: "color", Limit: 1}); !errors.Is(err, entity.ErrValidation) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+}
+
+func TestRepository_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	r := newRepo(t, WithSnapshot(path))
+	whID, _ := r.Warehouses().Create(ctx, &entity.Warehouse{Code: "MAIN"})
+	id, _ := r.Create(ctx, &entity.Product{Name: "Widget", Price: 1999, Quantity: 2})
+
+	reopened := newRepo(t, WithSnapshot(path))
+
+	p, err := reopened.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("Expected the product to survive a restart, got %v", err)
+	}
+	if p.Name != "Widget" || p.Price != 1999 || p.Quantity != 2 {
+		t.Errorf("Expected the stored product, got %+v", p)
+	}
+	if _, err := reopened.Warehouses().GetByID(ctx, whID); err != nil {
+		t.Errorf("Expected the warehouse to survive a restart, got %v", err)
+	}
+
+	next, _ := reopened.Create(ctx, &entity.Product{Name: "Gadget"})
+	if next != id+1 {
+		t.Errorf("Expected ids to continue at %d, got %d", id+1, next)
+	}
+}
+
+func TestRepository_SnapshotFailureRollsBack(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRepo(t, WithSnapshot(filepath.Join(dir, "store.json")))
+	id, _ := r.Create(ctx, &entity.Product{Name: "Widget", Price: 1999, Quantity: 5})
+	res := &entity.Reservation{Quantity: 2, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := r.Reserve(ctx, id, res); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without its directory the snapshot cannot be written.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Create(ctx, &entity.Product{Name: "Gadget", Price: 100}); err == nil {
+		t.Error("Expected Create to fail")
+	}
+	if err := r.ChangeQuantity(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -1}); err == nil {
+		t.Error("Expected ChangeQuantity to fail")
+	}
+	if _, err := r.ReleaseReservation(ctx, res.ID); err == nil {
+		t.Error("Expected ReleaseReservation to fail")
+	}
+	ops := []*entity.BatchOperation{{Action: entity.BatchDelete, ID: id}}
+	if err := r.Batch(ctx, ops, false); err == nil {
+		t.Error("Expected Batch to fail")
+	}
+
+	p, err := r.GetByID(ctx, id)
+	if err != nil || p.Quantity != 5 || p.Version != 1 {
+		t.Errorf("Expected the product unchanged, got %+v, %v", p, err)
+	}
+	movements, _ := r.GetMovements(ctx, id)
+	if len(movements) != 1 {
+		t.Errorf("Expected only the initial movement, got %d", len(movements))
+	}
+	if got, _ := r.GetReservation(ctx, res.ID); got.Status != entity.ReservationActive {
+		t.Errorf("Expected the reservation to stay active, got %s", got.Status)
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	next, err := r.Create(ctx, &entity.Product{Name: "Gadget", Price: 100})
+	if err != nil || next != id+1 {
+		t.Errorf("Expected the failed create not to use up id %d, got %d, %v", id+1, next, err)
+	}
+}
+
+func TestRepository_ConcurrentMovements(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	id, _ := r.Create(ctx, &entity.Product{Name: "Widget", Quantity: 50})
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.ChangeQuantity(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -1})
+		}()
+	}
+	wg.Wait()
+
+	p, _ := r.GetByID(ctx, id)
+	if p.Quantity != 0 {
+		t.Errorf("Expected exactly 50 issues to succeed, got quantity %d", p.Quantity)
+	}
+}
@@ -0,0 +1,236 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+// Warehouses is the warehouse repository over the same store, so putting
+// stock away and deleting locations see one consistent state.
+type Warehouses struct {
+	r *Repository
+}
+
+func (r *Repository) Warehouses() *Warehouses {
+	return &Warehouses{r: r}
+}
+
+func (w *Warehouses) Create(ctx context.Context, wh *entity.Warehouse) (int64, error) {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+
+	if w.codeTaken(0, wh.Code) {
+		return 0, entity.ErrWarehouseCodeTaken
+	}
+
+	stored := *wh
+	err := w.r.change(func() error {
+		stored.ID = w.r.next("warehouses")
+		w.r.data.Warehouses[stored.ID] = &stored
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return stored.ID, nil
+}
+
+func (w *Warehouses) GetByID(ctx context.Context, id int64) (*entity.Warehouse, error) {
+	w.r.mu.RLock()
+	defer w.r.mu.RUnlock()
+
+	wh, ok := w.r.data.Warehouses[id]
+	if !ok {
+		return nil, entity.ErrWarehouseNotFound
+	}
+
+	cp := *wh
+	return &cp, nil
+}
+
+func (w *Warehouses) Update(ctx context.Context, id int64, wh *entity.Warehouse) error {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+
+	if _, ok := w.r.data.Warehouses[id]; !ok {
+		return entity.ErrWarehouseNotFound
+	}
+
+	if w.codeTaken(id, wh.Code) {
+		return entity.ErrWarehouseCodeTaken
+	}
+
+	updated := *wh
+	updated.ID = id
+
+	return w.r.change(func() error {
+		w.r.data.Warehouses[id] = &updated
+		return nil
+	})
+}
+
+func (w *Warehouses) Delete(ctx context.Context, id int64) error {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+
+	if _, ok := w.r.data.Warehouses[id]; !ok {
+		return entity.ErrWarehouseNotFound
+	}
+
+	for _, l := range w.r.data.Locations {
+		if l.WarehouseID == id {
+			return entity.ErrWarehouseNotEmpty
+		}
+	}
+
+	return w.r.change(func() error {
+		delete(w.r.data.Warehouses, id)
+		return nil
+	})
+}
+
+func (w *Warehouses) GetAll(ctx context.Context) ([]*entity.Warehouse, error) {
+	w.r.mu.RLock()
+	defer w.r.mu.RUnlock()
+
+	warehouses := make([]*entity.Warehouse, 0, len(w.r.data.Warehouses))
+	for _, wh := range w.r.data.Warehouses {
+		cp := *wh
+		warehouses = append(warehouses, &cp)
+	}
+
+	sort.Slice(warehouses, func(i, j int) bool { return warehouses[i].ID < warehouses[j].ID })
+
+	return warehouses, nil
+}
+
+func (w *Warehouses) CreateLocation(ctx context.Context, l *entity.Location) (int64, error) {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+
+	if _, ok := w.r.data.Warehouses[l.WarehouseID]; !ok {
+		return 0, entity.ErrWarehouseNotFound
+	}
+
+	if w.locationCodeTaken(0, l.WarehouseID, l.Code) {
+		return 0, entity.ErrLocationCodeTaken
+	}
+
+	stored := *l
+	err := w.r.change(func() error {
+		stored.ID = w.r.next("locations")
+		w.r.data.Locations[stored.ID] = &stored
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return stored.ID, nil
+}
+
+func (w *Warehouses) GetLocation(ctx context.Context, id int64) (*entity.Location, error) {
+	w.r.mu.RLock()
+	defer w.r.mu.RUnlock()
+
+	l, ok := w.r.data.Locations[id]
+	if !ok {
+		return nil, entity.ErrLocationNotFound
+	}
+
+	cp := *l
+	return &cp, nil
+}
+
+func (w *Warehouses) UpdateLocation(ctx context.Context, id int64, l *entity.Location) error {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+
+	current, ok := w.r.data.Locations[id]
+	if !ok {
+		return entity.ErrLocationNotFound
+	}
+
+	if w.locationCodeTaken(id, current.WarehouseID, l.Code) {
+		return entity.ErrLocationCodeTaken
+	}
+
+	updated := *current
+	updated.Code, updated.Aisle, updated.Bin = l.Code, l.Aisle, l.Bin
+
+	return w.r.change(func() error {
+		w.r.data.Locations[id] = &updated
+		return nil
+	})
+}
+
+func (w *Warehouses) DeleteLocation(ctx context.Context, id int64) error {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+
+	if _, ok := w.r.data.Locations[id]; !ok {
+		return entity.ErrLocationNotFound
+	}
+
+	for _, stock := range w.r.data.Stock {
+		if stock[id] > 0 {
+			return entity.ErrLocationNotEmpty
+		}
+	}
+
+	return w.r.change(func() error {
+		delete(w.r.data.Locations, id)
+
+		// Movements keep their history but lose the location, like ON DELETE SET NULL.
+		for _, movements := range w.r.data.Movements {
+			for _, m := range movements {
+				if m.LocationID != nil && *m.LocationID == id {
+					m.LocationID = nil
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (w *Warehouses) GetLocations(ctx context.Context, warehouseID int64) ([]*entity.Location, error) {
+	w.r.mu.RLock()
+	defer w.r.mu.RUnlock()
+
+	if _, ok := w.r.data.Warehouses[warehouseID]; !ok {
+		return nil, entity.ErrWarehouseNotFound
+	}
+
+	locations := []*entity.Location{}
+	for _, l := range w.r.data.Locations {
+		if l.WarehouseID == warehouseID {
+			cp := *l
+			locations = append(locations, &cp)
+		}
+	}
+
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Code < locations[j].Code })
+
+	return locations, nil
+}
+
+func (w *Warehouses) codeTaken(id int64, code string) bool {
+	for _, wh := range w.r.data.Warehouses {
+		if wh.ID != id && wh.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Warehouses) locationCodeTaken(id, warehouseID int64, code string) bool {
+	for _, l := range w.r.data.Locations {
+		if l.ID != id && l.WarehouseID == warehouseID && l.Code == code {
+			return true
+		}
+	}
+	return false
+}
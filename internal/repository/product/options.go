@@ -0,0 +1,61 @@
+package product
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// options are shared by the SQL repositories.
+type options struct {
+	logger *slog.Logger
+	db     []db.Option
+}
+
+type Option func(*options)
+
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithTracerProvider replaces the global tracer provider for statement spans.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.db = append(o.db, db.WithTracerProvider(tp))
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{logger: logger.Discard()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type errorLogger struct {
+	logger *slog.Logger
+}
+
+// logError logs a failed database call with the operation and its
+// attributes. Domain errors such as ErrProductNotFound are expected outcomes
+// and are left to the caller; cancelled calls are only a warning.
+func (l errorLogger) logError(ctx context.Context, op string, err *error, attrs ...any) {
+	if *err == nil || entity.IsDomainError(*err) {
+		return
+	}
+
+	attrs = append([]any{"op", op, "error", *err}, attrs...)
+	if ctx.Err() != nil {
+		l.logger.WarnContext(ctx, "database call cancelled", attrs...)
+		return
+	}
+	l.logger.ErrorContext(ctx, "database call failed", attrs...)
+}
@@ -1,151 +1,133 @@
 package product
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
 )
 
-type PostgresRepository struct {
-	db *sql.DB
+// NewPostgresRepository stores products in PostgreSQL. Runs of creates in a
+// batch are inserted with multi-row statements.
+func NewPostgresRepository(database *sql.DB, opts ...Option) *SQLRepository {
+	return newSQLRepository(database, db.Postgres, postgresSchema, opts)
 }
 
-func NewPostgresRepository(db *sql.DB) *PostgresRepository {
-	return &PostgresRepository{db: db}
+var postgresSchema = schema{
+	price:      "price",
+	priceArg:   func(m entity.Money) interface{} { return m },
+	priceDest:  func(m *entity.Money) interface{} { return m },
+	createMany: (*SQLRepository).insertProducts,
 }
 
-func (r *PostgresRepository) Create(p *entity.Product) (int64, error) {
-	query := `
-		INSERT INTO products (name, description, price, quantity)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
-	`
-
-	var id int64
-	err := r.db.QueryRow(
-		query,
-		p.Name,
-		p.Description,
-		p.Price,
-		p.Quantity,
-	).Scan(&id)
-
-	if err != nil {
-		return 0, err
-	}
-
-	return id, nil
-}
-
-func (r *PostgresRepository) GetByID(id int64) (*entity.Product, error) {
-	query := `
-		SELECT id, name, description, price, quantity
-		FROM products
-		WHERE id = $1
-	`
-
-	var p entity.Product
-
-	err := r.db.QueryRow(query, id).Scan(
-		&p.ID,
-		&p.Name,
-		&p.Description,
-		&p.Price,
-		&p.Quantity,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, errors.New("product not found")
-	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &p, nil
-}
-
-func (r *PostgresRepository) Update(id int64, p *entity.Product) error {
-	query := `
-		UPDATE products
-		SET name = $1, description = $2, price = $3, quantity = $4
-		WHERE id = $5
-	`
-
-	res, err := r.db.Exec(
-		query,
-		p.Name,
-		p.Description,
-		p.Price,
-		p.Quantity,
-		id,
-	)
-
+// insertProducts creates products with one statement per table. The ids are
+// drawn from the sequence up front, so matching the rows with the products
+// does not depend on the order RETURNING yields them in.
+func (r *SQLRepository) insertProducts(ctx context.Context, tx *db.Tx, products []*entity.Product) error {
+	rows, err := tx.QueryContext(ctx, `SELECT nextval(pg_get_serial_sequence('products', 'id')) FROM generate_series(1, $1)`, len(products))
 	if err != nil {
 		return err
 	}
-
-	rows, err := res.RowsAffected()
-	if err != nil {
+	for i := 0; rows.Next(); i++ {
+		if err := rows.Scan(&products[i].ID); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
 		return err
 	}
 
-	if rows == 0 {
-		return errors.New("product not found")
+	var productRows, barcodeRows, movementRows [][]interface{}
+	byID := make(map[int64]*entity.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+		productRows = append(productRows, []interface{}{p.ID, p.SKU, p.Name, p.Description, p.Price, p.Currency, p.Quantity})
+		for i, code := range p.Barcodes {
+			barcodeRows = append(barcodeRows, []interface{}{entity.GTIN14(code), code, p.ID, i})
+		}
+		if p.Quantity != 0 {
+			movementRows = append(movementRows, []interface{}{p.ID, entity.MovementReceipt, p.Quantity, p.Quantity, "initial_stock"})
+		}
 	}
 
-	return nil
-}
-
-func (r *PostgresRepository) Delete(id int64) error {
-	query := `DELETE FROM products WHERE id = $1`
+	query := `INSERT INTO products (id, sku, name, description, price, currency, quantity) VALUES ` +
+		valuesList(len(productRows), 7, map[int]string{1: "NULLIF(%s, '')"}) +
+		` RETURNING id, version`
 
-	res, err := r.db.Exec(query, id)
+	rows, err = tx.QueryContext(ctx, query, flatten(productRows)...)
 	if err != nil {
-		return err
+		return r.uniqueCodeError(err)
 	}
-
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
+	defer rows.Close()
+	for rows.Next() {
+		var id, version int64
+		if err := rows.Scan(&id, &version); err != nil {
+			return err
+		}
+		byID[id].Version = version
+	}
+	if err := rows.Err(); err != nil {
+		return r.uniqueCodeError(err)
 	}
 
-	if rows == 0 {
-		return errors.New("product not found")
+	if err := insertRows(ctx, tx, `INSERT INTO product_barcodes (gtin, code, product_id, position) VALUES `, 4, barcodeRows); err != nil {
+		return r.uniqueCodeError(err)
 	}
 
-	return nil
+	return insertRows(ctx, tx, `INSERT INTO stock_movements (product_id, type, delta, balance, reason) VALUES `, 5, movementRows)
 }
 
-func (r *PostgresRepository) GetAll() ([]*entity.Product, error) {
-	query := `
-		SELECT id, name, description, price, quantity
-		FROM products
-		ORDER BY id
-	`
-
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return nil, err
+// maxParams is the most bind parameters Postgres takes in one statement.
+const maxParams = 65535
+
+// insertRows runs prefix followed by rows as VALUES, with as many rows per
+// statement as the parameter limit allows.
+func insertRows(ctx context.Context, tx *db.Tx, prefix string, columns int, rows [][]interface{}) error {
+	perStatement := maxParams / columns
+	for len(rows) > 0 {
+		n := min(len(rows), perStatement)
+		if _, err := tx.ExecContext(ctx, prefix+valuesList(n, columns, nil), flatten(rows[:n])...); err != nil {
+			return err
+		}
+		rows = rows[n:]
 	}
-	defer rows.Close()
-
-	var products []*entity.Product
+	return nil
+}
 
-	for rows.Next() {
-		var p entity.Product
-		if err := rows.Scan(
-			&p.ID,
-			&p.Name,
-			&p.Description,
-			&p.Price,
-			&p.Quantity,
-		); err != nil {
-			return nil, err
+// valuesList returns "($1, $2), ($3, $4)" for rows of columns placeholders.
+// wrap replaces the placeholder of a column with a format around it.
+func valuesList(rows, columns int, wrap map[int]string) string {
+	var b strings.Builder
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
 		}
-		products = append(products, &p)
+		b.WriteByte('(')
+		for j := 0; j < columns; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			placeholder := "$" + strconv.Itoa(i*columns+j+1)
+			if format, ok := wrap[j]; ok {
+				placeholder = fmt.Sprintf(format, placeholder)
+			}
+			b.WriteString(placeholder)
+		}
+		b.WriteByte(')')
 	}
+	return b.String()
+}
 
-	return products, nil
+func flatten(rows [][]interface{}) []interface{} {
+	var args []interface{}
+	for _, row := range rows {
+		args = append(args, row...)
+	}
+	return args
 }
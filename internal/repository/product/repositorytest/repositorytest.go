@@ -0,0 +1,1044 @@
+// Package repositorytest is a conformance suite for product and warehouse
+// repositories. Every backend runs the same cases, so a behavior the service
+// relies on cannot drift between them unnoticed.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/repository/product"
+	"github.com/imbafff/product-warehouse-api/internal/repository/warehouse"
+)
+
+// Backend is one implementation under test. Warehouses must share storage
+// with Products, since stock is kept per location.
+type Backend struct {
+	Products   product.Repository
+	Warehouses warehouse.Repository
+}
+
+// Factory returns an empty backend. It is called once per case.
+type Factory func(t *testing.T) Backend
+
+// Run runs every case against the backends built by newBackend.
+func Run(t *testing.T, newBackend Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, b Backend)
+	}{
+		{"CreateAssignsIDs", testCreateAssignsIDs},
+		{"NotFound", testNotFound},
+		{"UpdateChecksVersion", testUpdateChecksVersion},
+		{"DeleteChecksVersion", testDeleteChecksVersion},
+		{"SKUAndBarcodes", testSKUAndBarcodes},
+		{"Batch", testBatch},
+		{"Import", testImport},
+		{"GetAllOrdering", testGetAllOrdering},
+		{"GetAllPagination", testGetAllPagination},
+		{"GetAllFilters", testGetAllFilters},
+		{"Movements", testMovements},
+		{"StockInLocations", testStockInLocations},
+		{"ConcurrentStockChanges", testConcurrentStockChanges},
+		{"Reservations", testReservations},
+		{"ConcurrentReservations", testConcurrentReservations},
+		{"Warehouses", testWarehouses},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newBackend(t))
+		})
+	}
+}
+
+func create(t *testing.T, b Backend, p *entity.Product) int64 {
+	t.Helper()
+	if p.Currency == "" {
+		p.Currency = "USD"
+	}
+	id, err := b.Products.Create(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return id
+}
+
+func expectErr(t *testing.T, op string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("%s: expected %v, got %v", op, want, err)
+	}
+}
+
+func testCreateAssignsIDs(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	p := &entity.Product{Name: "widget", Description: "small", Price: 1999, Currency: "EUR", Quantity: 3}
+	first := create(t, b, p)
+	second := create(t, b, &entity.Product{Name: "gadget"})
+
+	if first <= 0 || second <= first {
+		t.Errorf("Expected positive increasing ids, got %d then %d", first, second)
+	}
+	if p.Version != 1 {
+		t.Errorf("Expected Create to set version 1, got %d", p.Version)
+	}
+
+	got, err := b.Products.GetByID(ctx, first)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	want := entity.Product{ID: first, Barcodes: []string{}, Name: "widget", Description: "small", Price: 1999, Currency: "EUR", Quantity: 3, Version: 1}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Expected %+v, got %+v", want, *got)
+	}
+
+	movements, err := b.Products.GetMovements(ctx, first)
+	if err != nil {
+		t.Fatalf("GetMovements: %v", err)
+	}
+	if len(movements) != 1 || movements[0].Type != entity.MovementReceipt || movements[0].Delta != 3 || movements[0].Reason != "initial_stock" {
+		t.Errorf("Expected an initial_stock receipt of 3, got %+v", movements)
+	}
+
+	if movements, _ := b.Products.GetMovements(ctx, second); len(movements) != 0 {
+		t.Errorf("Expected no movement for a product created empty, got %+v", movements)
+	}
+}
+
+func testNotFound(t *testing.T, b Backend) {
+	ctx := context.Background()
+	const missing = 1_000_000
+
+	_, err := b.Products.GetByID(ctx, missing)
+	expectErr(t, "GetByID", err, entity.ErrProductNotFound)
+
+	_, err = b.Products.GetBySKU(ctx, "MISSING")
+	expectErr(t, "GetBySKU", err, entity.ErrProductNotFound)
+
+	_, err = b.Products.GetByBarcode(ctx, "4006381333931")
+	expectErr(t, "GetByBarcode", err, entity.ErrProductNotFound)
+
+	for _, version := range []int64{0, 1} {
+		err := b.Products.Update(ctx, missing, &entity.Product{Name: "x", Currency: "USD", Version: version})
+		expectErr(t, "Update", err, entity.ErrProductNotFound)
+
+		err = b.Products.Delete(ctx, missing, version)
+		expectErr(t, "Delete", err, entity.ErrProductNotFound)
+	}
+
+	_, err = b.Products.AddMovement(ctx, missing, &entity.StockMovement{Type: entity.MovementReceipt, Delta: 1, Reason: "x"})
+	expectErr(t, "AddMovement", err, entity.ErrProductNotFound)
+
+	err = b.Products.ChangeQuantity(ctx, missing, &entity.StockMovement{Type: entity.MovementReceipt, Delta: 1, Reason: "x"})
+	expectErr(t, "ChangeQuantity", err, entity.ErrProductNotFound)
+
+	_, err = b.Products.GetMovements(ctx, missing)
+	expectErr(t, "GetMovements", err, entity.ErrProductNotFound)
+
+	_, err = b.Products.GetStock(ctx, missing, nil)
+	expectErr(t, "GetStock", err, entity.ErrProductNotFound)
+}
+
+func testUpdateChecksVersion(t *testing.T, b Backend) {
+	ctx := context.Background()
+	id := create(t, b, &entity.Product{Name: "widget", Quantity: 1})
+
+	err := b.Products.Update(ctx, id, &entity.Product{Name: "gadget", Currency: "USD", Quantity: 1, Version: 7})
+	expectErr(t, "Update with a stale version", err, entity.ErrVersionConflict)
+
+	p := &entity.Product{Name: "gadget", Price: 500, Currency: "USD", Quantity: 4, Version: 1}
+	if err := b.Products.Update(ctx, id, p); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if p.Version != 2 {
+		t.Errorf("Expected Update to set version 2, got %d", p.Version)
+	}
+
+	// Version 0 skips the check.
+	if err := b.Products.Update(ctx, id, &entity.Product{Name: "gadget", Price: 500, Currency: "USD", Quantity: 4}); err != nil {
+		t.Fatalf("Update without a version: %v", err)
+	}
+
+	got, _ := b.Products.GetByID(ctx, id)
+	if got.Name != "gadget" || got.Price != 500 || got.Quantity != 4 || got.Version != 3 {
+		t.Errorf("Expected the updated product at version 3, got %+v", got)
+	}
+
+	movements, _ := b.Products.GetMovements(ctx, id)
+	if len(movements) != 2 {
+		t.Fatalf("Expected one adjustment after the initial receipt, got %+v", movements)
+	}
+	if m := movements[1]; m.Type != entity.MovementAdjustment || m.Delta != 3 || m.Balance != 4 || m.Reason != "manual_update" {
+		t.Errorf("Expected a manual_update adjustment of 3, got %+v", m)
+	}
+}
+
+func testDeleteChecksVersion(t *testing.T, b Backend) {
+	ctx := context.Background()
+	id := create(t, b, &entity.Product{Name: "widget", Quantity: 2})
+
+	expectErr(t, "Delete with a stale version", b.Products.Delete(ctx, id, 5), entity.ErrVersionConflict)
+
+	if err := b.Products.Delete(ctx, id, 1); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err := b.Products.GetByID(ctx, id)
+	expectErr(t, "GetByID after Delete", err, entity.ErrProductNotFound)
+
+	_, err = b.Products.GetMovements(ctx, id)
+	expectErr(t, "GetMovements after Delete", err, entity.ErrProductNotFound)
+
+	expectErr(t, "Delete twice", b.Products.Delete(ctx, id, 0), entity.ErrProductNotFound)
+}
+
+func testSKUAndBarcodes(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	id := create(t, b, &entity.Product{SKU: "Mug-01", Name: "mug", Barcodes: []string{"4006381333931", "036000291452"}})
+	other := create(t, b, &entity.Product{Name: "plate"})
+
+	for _, sku := range []string{"Mug-01", "MUG-01", "mug-01"} {
+		got, err := b.Products.GetBySKU(ctx, sku)
+		if err != nil || got.ID != id {
+			t.Errorf("GetBySKU(%q): expected product %d, got %+v, %v", sku, id, got, err)
+		}
+	}
+
+	got, err := b.Products.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !reflect.DeepEqual(got.Barcodes, []string{"4006381333931", "036000291452"}) {
+		t.Errorf("Expected barcodes in the order given, got %v", got.Barcodes)
+	}
+
+	// A UPC-A is the EAN-13 with a leading zero.
+	for _, code := range []string{"036000291452", "0036000291452", "4006381333931"} {
+		got, err := b.Products.GetByBarcode(ctx, code)
+		if err != nil || got.ID != id {
+			t.Errorf("GetByBarcode(%q): expected product %d, got %+v, %v", code, id, got, err)
+		}
+	}
+
+	_, err = b.Products.Create(ctx, &entity.Product{SKU: "MUG-01", Name: "copy", Currency: "USD"})
+	expectErr(t, "Create with a taken SKU", err, entity.ErrSKUTaken)
+
+	_, err = b.Products.Create(ctx, &entity.Product{Name: "copy", Currency: "USD", Barcodes: []string{"00036000291452"}})
+	expectErr(t, "Create with a taken barcode", err, entity.ErrBarcodeTaken)
+
+	err = b.Products.Update(ctx, other, &entity.Product{SKU: "mug-01", Name: "plate", Currency: "USD"})
+	expectErr(t, "Update to a taken SKU", err, entity.ErrSKUTaken)
+
+	err = b.Products.Update(ctx, id, &entity.Product{SKU: "MUG-02", Name: "mug", Currency: "USD", Barcodes: []string{"10614141000415"}})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	_, err = b.Products.GetBySKU(ctx, "MUG-01")
+	expectErr(t, "GetBySKU of a replaced SKU", err, entity.ErrProductNotFound)
+
+	_, err = b.Products.GetByBarcode(ctx, "4006381333931")
+	expectErr(t, "GetByBarcode of a removed barcode", err, entity.ErrProductNotFound)
+
+	if got, err := b.Products.GetByBarcode(ctx, "10614141000415"); err != nil || got.SKU != "MUG-02" {
+		t.Errorf("Expected the new barcode to find the updated product, got %+v, %v", got, err)
+	}
+
+	// Products without a SKU do not collide with each other.
+	create(t, b, &entity.Product{Name: "bowl"})
+}
+
+func testBatch(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	kept := create(t, b, &entity.Product{SKU: "KEEP", Name: "kept", Quantity: 1})
+	gone := create(t, b, &entity.Product{SKU: "GONE", Name: "gone"})
+
+	newProduct := func(sku string, quantity int) *entity.Product {
+		return &entity.Product{SKU: sku, Name: sku, Price: 100, Currency: "USD", Quantity: quantity}
+	}
+
+	// A failing atomic batch leaves nothing behind, not even the operations
+	// that came before the failure.
+	ops := []*entity.BatchOperation{
+		{Action: entity.BatchCreate, Product: newProduct("A", 1)},
+		{Action: entity.BatchDelete, ID: gone},
+		{Action: entity.BatchCreate, Product: newProduct("a", 1)},
+	}
+	err := b.Products.Batch(ctx, ops, true)
+	expectErr(t, "Atomic batch with a repeated SKU", err, entity.ErrSKUTaken)
+
+	_, err = b.Products.GetBySKU(ctx, "A")
+	expectErr(t, "GetBySKU after a rolled back batch", err, entity.ErrProductNotFound)
+	if _, err := b.Products.GetByID(ctx, gone); err != nil {
+		t.Errorf("Expected the delete to be rolled back, got %v", err)
+	}
+
+	// Operations apply in order, so a SKU freed by a delete can be reused.
+	ops = []*entity.BatchOperation{
+		{Action: entity.BatchDelete, ID: gone, Version: 1},
+		{Action: entity.BatchCreate, Product: newProduct("GONE", 4)},
+		{Action: entity.BatchCreate, Product: newProduct("B", 0)},
+		{Action: entity.BatchUpdate, ID: kept, Version: 1, Product: newProduct("KEEP", 2)},
+	}
+	if err := b.Products.Batch(ctx, ops, true); err != nil {
+		t.Fatalf("Atomic batch: %v", err)
+	}
+	for i, op := range ops {
+		if op.Err != nil {
+			t.Errorf("Operation %d: unexpected error %v", i, op.Err)
+		}
+	}
+
+	created := ops[1].Product
+	if created.ID == 0 || created.ID == gone || created.ID == ops[2].Product.ID || created.Version != 1 {
+		t.Errorf("Expected fresh IDs and version 1, got %+v and %+v", created, ops[2].Product)
+	}
+	if got, err := b.Products.GetBySKU(ctx, "GONE"); err != nil || got.ID != created.ID || got.Quantity != 4 {
+		t.Errorf("Expected the recreated product %d with 4 in stock, got %+v, %v", created.ID, got, err)
+	}
+	movements, err := b.Products.GetMovements(ctx, created.ID)
+	if err != nil || len(movements) != 1 || movements[0].Delta != 4 {
+		t.Errorf("Expected the initial stock as a movement, got %v, %v", movements, err)
+	}
+	if got, err := b.Products.GetByID(ctx, kept); err != nil || got.Quantity != 2 || got.Version != 2 || ops[3].Product.Version != 2 {
+		t.Errorf("Expected the update at version 2, got %+v, %v", got, err)
+	}
+
+	// A best-effort batch applies what it can and reports the rest.
+	ops = []*entity.BatchOperation{
+		{Action: entity.BatchCreate, Product: newProduct("C", 0)},
+		{Action: entity.BatchCreate, Product: newProduct("c", 0)},
+		{Action: entity.BatchUpdate, ID: 999999, Product: newProduct("D", 0)},
+		{Action: entity.BatchDelete, ID: kept, Version: 1},
+		{Action: entity.BatchCreate, Product: newProduct("E", 0)},
+	}
+	if err := b.Products.Batch(ctx, ops, false); err != nil {
+		t.Fatalf("Best-effort batch: %v", err)
+	}
+
+	want := []error{nil, entity.ErrSKUTaken, entity.ErrProductNotFound, entity.ErrVersionConflict, nil}
+	for i, op := range ops {
+		if want[i] == nil && op.Err != nil {
+			t.Errorf("Operation %d: unexpected error %v", i, op.Err)
+		} else if want[i] != nil {
+			expectErr(t, fmt.Sprintf("Operation %d", i), op.Err, want[i])
+		}
+	}
+
+	for _, sku := range []string{"C", "E", "KEEP"} {
+		if _, err := b.Products.GetBySKU(ctx, sku); err != nil {
+			t.Errorf("GetBySKU(%q): %v", sku, err)
+		}
+	}
+}
+
+func testImport(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	mug := create(t, b, &entity.Product{SKU: "MUG-1", Name: "mug", Barcodes: []string{"4006381333931"}})
+	create(t, b, &entity.Product{SKU: "CUP-1", Name: "cup"})
+
+	var found []*entity.Product
+	var ops []*entity.BatchOperation
+	build := func(products []*entity.Product) []*entity.BatchOperation {
+		found = products
+		return ops
+	}
+
+	// SKUs match regardless of case; only the products asked for are read.
+	ops = []*entity.BatchOperation{
+		{Action: entity.BatchUpdate, ID: mug, Version: 1, Product: &entity.Product{SKU: "MUG-1", Name: "big mug", Currency: "USD"}},
+		{Action: entity.BatchCreate, Product: &entity.Product{SKU: "NEW-1", Name: "new", Currency: "USD"}},
+		{Action: entity.BatchCreate, Product: &entity.Product{SKU: "cup-1", Name: "copy", Currency: "USD"}},
+	}
+	if err := b.Products.Import(ctx, entity.ImportBySKU, []string{"mug-1", "NEW-1", "cup-1"}, build); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("Expected MUG-1 and CUP-1 to be found, got %+v", found)
+	}
+	for _, p := range found {
+		if p.ID == mug && (p.Name != "mug" || p.Version != 1 || len(p.Barcodes) != 1) {
+			t.Errorf("Expected the stored MUG-1, got %+v", p)
+		}
+	}
+
+	// Failed operations do not hold up the rest.
+	if ops[0].Err != nil || ops[1].Err != nil {
+		t.Errorf("Unexpected errors: %v, %v", ops[0].Err, ops[1].Err)
+	}
+	expectErr(t, "Importing a taken SKU", ops[2].Err, entity.ErrSKUTaken)
+	if got, err := b.Products.GetBySKU(ctx, "NEW-1"); err != nil || got.ID != ops[1].Product.ID {
+		t.Errorf("Expected NEW-1 to be created, got %+v, %v", got, err)
+	}
+
+	ops = nil
+	if err := b.Products.Import(ctx, entity.ImportByID, []string{fmt.Sprint(mug), "999999"}, build); err != nil {
+		t.Fatalf("Import by id: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != mug || found[0].Name != "big mug" || found[0].Version != 2 {
+		t.Errorf("Expected only the updated MUG-1 to be found, got %+v", found)
+	}
+}
+
+func ids(items []*entity.Product) []int64 {
+	out := []int64{}
+	for _, p := range items {
+		out = append(out, p.ID)
+	}
+	return out
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testGetAllOrdering(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	page, err := b.Products.GetAll(ctx, entity.ProductQuery{SortField: "id", Limit: 10})
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if page.Items == nil || len(page.Items) != 0 || page.Next != nil {
+		t.Errorf("Expected an empty, non-nil page, got %+v", page)
+	}
+
+	// Ties on every sort column are broken by id.
+	a := create(t, b, &entity.Product{Name: "pear", Price: 300, Quantity: 1})
+	c := create(t, b, &entity.Product{Name: "apple", Price: 100, Quantity: 5})
+	d := create(t, b, &entity.Product{Name: "pear", Price: 100, Quantity: 1})
+	e := create(t, b, &entity.Product{Name: "fig", Price: 200, Quantity: 3})
+
+	tests := []struct {
+		sort string
+		desc bool
+		want []int64
+	}{
+		{"id", false, []int64{a, c, d, e}},
+		{"id", true, []int64{e, d, c, a}},
+		{"name", false, []int64{c, e, a, d}},
+		{"name", true, []int64{d, a, e, c}},
+		{"price", false, []int64{c, d, e, a}},
+		{"price", true, []int64{a, e, d, c}},
+		{"quantity", false, []int64{a, d, e, c}},
+		{"quantity", true, []int64{c, e, d, a}},
+	}
+
+	for _, tt := range tests {
+		page, err := b.Products.GetAll(ctx, entity.ProductQuery{SortField: tt.sort, SortDesc: tt.desc, Limit: 10})
+		if err != nil {
+			t.Fatalf("GetAll sorted by %s: %v", tt.sort, err)
+		}
+		if got := ids(page.Items); !equal(got, tt.want) {
+			t.Errorf("Sorted by %s desc=%t: expected %v, got %v", tt.sort, tt.desc, tt.want, got)
+		}
+	}
+
+	_, err = b.Products.GetAll(ctx, entity.ProductQuery{SortField: "color", Limit: 10})
+	expectErr(t, "GetAll with an unsupported sort", err, entity.ErrValidation)
+}
+
+func testGetAllPagination(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	var want []int64
+	for _, price := range []entity.Money{500, 100, 300, 100, 200, 300, 100} {
+		create(t, b, &entity.Product{Name: "item", Price: price})
+	}
+
+	q := entity.ProductQuery{SortField: "price", SortDesc: true, Limit: 100}
+	all, err := b.Products.GetAll(ctx, q)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	want = ids(all.Items)
+
+	q.Limit, q.WithTotal = 3, true
+	var got []int64
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("Expected pagination to end")
+		}
+
+		page, err := b.Products.GetAll(ctx, q)
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if page.Total == nil || *page.Total != int64(len(want)) {
+			t.Errorf("Expected every page to report a total of %d, got %v", len(want), page.Total)
+		}
+
+		got = append(got, ids(page.Items)...)
+		if page.Next == nil {
+			break
+		}
+		if len(page.Items) != q.Limit {
+			t.Errorf("Expected full pages before the last one, got %d items", len(page.Items))
+		}
+		q.After = page.Next
+	}
+
+	if !equal(got, want) {
+		t.Errorf("Expected cursor pages to list %v once each, got %v", want, got)
+	}
+
+	page, err := b.Products.GetAll(ctx, entity.ProductQuery{SortField: "price", SortDesc: true, Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("GetAll with offset: %v", err)
+	}
+	if got := ids(page.Items); !equal(got, want[2:4]) {
+		t.Errorf("Expected offset 2 to list %v, got %v", want[2:4], got)
+	}
+	if page.Next == nil {
+		t.Error("Expected a cursor when more rows follow an offset page")
+	}
+
+	page, _ = b.Products.GetAll(ctx, entity.ProductQuery{SortField: "id", Limit: 5, Offset: 50})
+	if len(page.Items) != 0 || page.Next != nil {
+		t.Errorf("Expected an empty page past the end, got %+v", page)
+	}
+}
+
+func testGetAllFilters(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	wh, loc := newLocation(t, b, "MAIN", "A-01")
+	other, otherLoc := newLocation(t, b, "EAST", "A-01")
+
+	bolt := create(t, b, &entity.Product{Name: "Steel Bolt", Price: 150, Quantity: 10})
+	nut := create(t, b, &entity.Product{Name: "nut", Price: 50, Quantity: 0})
+	boltBig := create(t, b, &entity.Product{Name: "big bolt", Price: 900, Quantity: 4})
+	odd := create(t, b, &entity.Product{Name: "100%_off", Price: 100, Quantity: 1})
+
+	putAway(t, b, bolt, loc, 2)
+	putAway(t, b, boltBig, otherLoc, 1)
+	putAway(t, b, boltBig, loc, 1)
+	putAway(t, b, boltBig, loc, -1)
+
+	minPrice, maxPrice := entity.Money(100), entity.Money(200)
+	minQty, maxQty := 1, 5
+
+	tests := []struct {
+		name   string
+		filter entity.ProductFilter
+		want   []int64
+	}{
+		{"name is case-insensitive", entity.ProductFilter{NameContains: "BOLT"}, []int64{bolt, boltBig}},
+		{"name is matched literally", entity.ProductFilter{NameContains: "%_"}, []int64{odd}},
+		{"price range", entity.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}, []int64{bolt, odd}},
+		{"quantity range", entity.ProductFilter{MinQuantity: &minQty, MaxQuantity: &maxQty}, []int64{boltBig, odd}},
+		{"warehouse with stock", entity.ProductFilter{WarehouseID: &wh}, []int64{bolt}},
+		{"other warehouse", entity.ProductFilter{WarehouseID: &other}, []int64{boltBig}},
+		{"combined", entity.ProductFilter{NameContains: "bolt", MaxPrice: &maxPrice}, []int64{bolt}},
+		{"none", entity.ProductFilter{}, []int64{bolt, nut, boltBig, odd}},
+	}
+
+	for _, tt := range tests {
+		page, err := b.Products.GetAll(ctx, entity.ProductQuery{Filter: tt.filter, SortField: "id", Limit: 10, WithTotal: true})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := ids(page.Items); !equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+		if *page.Total != int64(len(tt.want)) {
+			t.Errorf("%s: expected a total of %d, got %d", tt.name, len(tt.want), *page.Total)
+		}
+	}
+}
+
+func testMovements(t *testing.T, b Backend) {
+	ctx := context.Background()
+	id := create(t, b, &entity.Product{Name: "widget", Quantity: 5})
+
+	m := &entity.StockMovement{Type: entity.MovementReceipt, Delta: 10, Reason: "purchase", Reference: "PO-1"}
+	movementID, err := b.Products.AddMovement(ctx, id, m)
+	if err != nil {
+		t.Fatalf("AddMovement: %v", err)
+	}
+	if movementID <= 0 || m.ID != movementID || m.Balance != 15 || m.ProductID != id || m.CreatedAt.IsZero() {
+		t.Errorf("Expected AddMovement to fill in the movement, got id %d and %+v", movementID, m)
+	}
+
+	_, err = b.Products.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -16, Reason: "sale"})
+	expectErr(t, "AddMovement below zero", err, entity.ErrInsufficientStock)
+
+	change := &entity.StockMovement{Type: entity.MovementIssue, Delta: -15, Reason: "sale"}
+	if err := b.Products.ChangeQuantity(ctx, id, change); err != nil {
+		t.Fatalf("ChangeQuantity: %v", err)
+	}
+	if change.ID <= movementID || change.Balance != 0 || change.ProductID != id || change.CreatedAt.IsZero() {
+		t.Errorf("Expected ChangeQuantity to fill in the movement, got %+v", change)
+	}
+
+	err = b.Products.ChangeQuantity(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -1, Reason: "sale"})
+	expectErr(t, "ChangeQuantity below zero", err, entity.ErrInsufficientStock)
+
+	p, _ := b.Products.GetByID(ctx, id)
+	if p.Quantity != 0 || p.Version != 3 {
+		t.Errorf("Expected quantity 0 at version 3, got %+v", p)
+	}
+
+	movements, err := b.Products.GetMovements(ctx, id)
+	if err != nil {
+		t.Fatalf("GetMovements: %v", err)
+	}
+
+	wantBalances := []int{5, 15, 0}
+	if len(movements) != len(wantBalances) {
+		t.Fatalf("Expected %d movements, got %+v", len(wantBalances), movements)
+	}
+	for i, m := range movements {
+		if m.Balance != wantBalances[i] {
+			t.Errorf("Movement %d: expected balance %d, got %d", i, wantBalances[i], m.Balance)
+		}
+		if i > 0 && m.ID <= movements[i-1].ID {
+			t.Errorf("Expected movements in id order, got %d after %d", m.ID, movements[i-1].ID)
+		}
+	}
+	if movements[1].Reference != "PO-1" || movements[1].Reason != "purchase" {
+		t.Errorf("Expected the reason and reference to be kept, got %+v", movements[1])
+	}
+}
+
+func newLocation(t *testing.T, b Backend, warehouseCode, code string) (warehouseID, locationID int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	warehouseID, err := b.Warehouses.Create(ctx, &entity.Warehouse{Code: warehouseCode, Name: warehouseCode})
+	if err != nil {
+		t.Fatalf("Create warehouse: %v", err)
+	}
+	locationID, err = b.Warehouses.CreateLocation(ctx, &entity.Location{WarehouseID: warehouseID, Code: code})
+	if err != nil {
+		t.Fatalf("CreateLocation: %v", err)
+	}
+	return warehouseID, locationID
+}
+
+// putAway moves quantity between the unallocated stock and a location.
+func putAway(t *testing.T, b Backend, productID, locationID int64, quantity int) {
+	t.Helper()
+	m := &entity.StockMovement{Type: entity.MovementTransfer, Delta: quantity, Reason: "put_away", LocationID: &locationID}
+	if _, err := b.Products.AddMovement(context.Background(), productID, m); err != nil {
+		t.Fatalf("AddMovement: %v", err)
+	}
+}
+
+func testStockInLocations(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	wh, binB := newLocation(t, b, "MAIN", "B-01")
+	binA, err := b.Warehouses.CreateLocation(ctx, &entity.Location{WarehouseID: wh, Code: "A-01"})
+	if err != nil {
+		t.Fatalf("CreateLocation: %v", err)
+	}
+	other, otherBin := newLocation(t, b, "EAST", "A-01")
+
+	id := create(t, b, &entity.Product{Name: "widget", Quantity: 10})
+
+	putAway(t, b, id, binB, 3)
+	putAway(t, b, id, binA, 2)
+	putAway(t, b, id, otherBin, 1)
+
+	// A receipt straight into a location raises the total too.
+	if _, err := b.Products.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementReceipt, Delta: 4, Reason: "purchase", LocationID: &binA}); err != nil {
+		t.Fatalf("AddMovement: %v", err)
+	}
+
+	stock, err := b.Products.GetStock(ctx, id, nil)
+	if err != nil {
+		t.Fatalf("GetStock: %v", err)
+	}
+	if stock.ProductID != id || stock.Quantity != 14 || stock.Unallocated != 4 {
+		t.Errorf("Expected 14 with 4 unallocated, got %+v", stock)
+	}
+
+	want := []entity.StockLevel{
+		{WarehouseID: wh, LocationID: binA, LocationCode: "A-01", Quantity: 6},
+		{WarehouseID: wh, LocationID: binB, LocationCode: "B-01", Quantity: 3},
+		{WarehouseID: other, LocationID: otherBin, LocationCode: "A-01", Quantity: 1},
+	}
+	if len(stock.Locations) != len(want) {
+		t.Fatalf("Expected %d locations, got %+v", len(want), stock.Locations)
+	}
+	for i := range want {
+		if *stock.Locations[i] != want[i] {
+			t.Errorf("Location %d: expected %+v, got %+v", i, want[i], *stock.Locations[i])
+		}
+	}
+
+	stock, _ = b.Products.GetStock(ctx, id, &other)
+	if len(stock.Locations) != 1 || stock.Locations[0].LocationID != otherBin || stock.Unallocated != 4 {
+		t.Errorf("Expected only the EAST location, got %+v", stock)
+	}
+
+	_, err = b.Products.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementTransfer, Delta: -4, Reason: "move", LocationID: &binB})
+	expectErr(t, "Taking more than a location holds", err, entity.ErrInsufficientStock)
+
+	_, err = b.Products.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementTransfer, Delta: 5, Reason: "move", LocationID: &binB})
+	expectErr(t, "Putting away more than is unallocated", err, entity.ErrInsufficientStock)
+
+	err = b.Products.ChangeQuantity(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -5, Reason: "sale"})
+	expectErr(t, "Issuing allocated stock", err, entity.ErrInsufficientStock)
+
+	err = b.Products.Update(ctx, id, &entity.Product{Name: "widget", Currency: "USD", Quantity: 9})
+	expectErr(t, "Updating below the allocated quantity", err, entity.ErrInsufficientStock)
+
+	err = b.Products.Update(ctx, id, &entity.Product{Name: "widget", Currency: "USD", Quantity: 9, Version: 1})
+	expectErr(t, "Updating below the allocated quantity with a stale version", err, entity.ErrVersionConflict)
+
+	missing := int64(1_000_000)
+	_, err = b.Products.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementReceipt, Delta: 1, Reason: "x", LocationID: &missing})
+	expectErr(t, "AddMovement to a missing location", err, entity.ErrLocationNotFound)
+
+	expectErr(t, "Deleting a location with stock", b.Warehouses.DeleteLocation(ctx, binB), entity.ErrLocationNotEmpty)
+
+	putAway(t, b, id, binB, -3)
+	if err := b.Warehouses.DeleteLocation(ctx, binB); err != nil {
+		t.Fatalf("Expected an emptied location to be deleted, got %v", err)
+	}
+
+	movements, _ := b.Products.GetMovements(ctx, id)
+	for _, m := range movements {
+		if m.LocationID != nil && *m.LocationID == binB {
+			t.Errorf("Expected movements to drop the deleted location, got %+v", m)
+		}
+	}
+
+	if err := b.Products.Delete(ctx, id, 0); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := b.Warehouses.DeleteLocation(ctx, otherBin); err != nil {
+		t.Errorf("Expected deleting the product to release its locations, got %v", err)
+	}
+}
+
+// testConcurrentStockChanges races issues against put-aways. Each takes one
+// unit of unallocated stock, so exactly as many as there are units must
+// succeed and nothing may end up allocated beyond the quantity.
+func testConcurrentStockChanges(t *testing.T, b Backend) {
+	ctx := context.Background()
+	const stock = 10
+
+	_, bin := newLocation(t, b, "MAIN", "A-01")
+	id := create(t, b, &entity.Product{Name: "widget", Quantity: stock})
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	count := func(err error) {
+		switch {
+		case err == nil:
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		case !errors.Is(err, entity.ErrInsufficientStock):
+			t.Errorf("Stock change: %v", err)
+		}
+	}
+	for i := 0; i < stock; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			count(b.Products.ChangeQuantity(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -1, Reason: "sale"}))
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := b.Products.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementTransfer, Delta: 1, Reason: "put_away", LocationID: &bin})
+			count(err)
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != stock {
+		t.Errorf("Expected %d stock changes to succeed, got %d", stock, succeeded)
+	}
+	s, err := b.Products.GetStock(ctx, id, nil)
+	if err != nil {
+		t.Fatalf("GetStock: %v", err)
+	}
+	if s.Unallocated != 0 {
+		t.Errorf("Expected no unallocated stock left, got %+v", s)
+	}
+}
+
+func reserve(t *testing.T, b Backend, productID int64, quantity int, at time.Time, ttl time.Duration) *entity.Reservation {
+	t.Helper()
+	r := &entity.Reservation{Quantity: quantity, Reference: "SO-1", ExpiresAt: at.Add(ttl), CreatedAt: at}
+	if err := b.Products.Reserve(context.Background(), productID, r); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	return r
+}
+
+func expectReserved(t *testing.T, b Backend, productID int64, quantity, reserved int) {
+	t.Helper()
+	stock, err := b.Products.GetStock(context.Background(), productID, nil)
+	if err != nil {
+		t.Fatalf("GetStock: %v", err)
+	}
+	if stock.Quantity != quantity || stock.Reserved != reserved || stock.Available != quantity-reserved {
+		t.Errorf("Expected %d on hand with %d reserved, got %+v", quantity, reserved, stock)
+	}
+}
+
+func testReservations(t *testing.T, b Backend) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	const missing = 1_000_000
+
+	id := create(t, b, &entity.Product{Name: "widget", Quantity: 10})
+
+	first := reserve(t, b, id, 6, start, time.Minute)
+	if first.ID <= 0 || first.ProductID != id || first.Status != entity.ReservationActive {
+		t.Errorf("Expected Reserve to fill in the reservation, got %+v", first)
+	}
+
+	got, err := b.Products.GetReservation(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetReservation: %v", err)
+	}
+	if got.Quantity != 6 || got.Reference != "SO-1" || !got.ExpiresAt.Equal(start.Add(time.Minute)) || !got.CreatedAt.Equal(start) {
+		t.Errorf("Expected the reservation to round-trip, got %+v", got)
+	}
+	expectReserved(t, b, id, 10, 6)
+
+	err = b.Products.Reserve(ctx, id, &entity.Reservation{Quantity: 5, ExpiresAt: start.Add(time.Minute), CreatedAt: start})
+	expectErr(t, "Reserving more than is available", err, entity.ErrInsufficientStock)
+
+	err = b.Products.ChangeQuantity(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -5, Reason: "sale"})
+	expectErr(t, "Issuing reserved stock", err, entity.ErrInsufficientStock)
+	_, err = b.Products.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -5, Reason: "sale"})
+	expectErr(t, "Adding a movement into reserved stock", err, entity.ErrInsufficientStock)
+	err = b.Products.Update(ctx, id, &entity.Product{Name: "widget", Currency: "USD", Quantity: 5})
+	expectErr(t, "Updating below the reserved quantity", err, entity.ErrInsufficientStock)
+
+	if err := b.Products.ChangeQuantity(ctx, id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -4, Reason: "sale"}); err != nil {
+		t.Fatalf("Expected the unreserved stock to be issued, got %v", err)
+	}
+
+	m := &entity.StockMovement{Type: entity.MovementIssue, Reason: "reservation_confirmed"}
+	confirmed, err := b.Products.ConfirmReservation(ctx, first.ID, start.Add(30*time.Second), m)
+	if err != nil {
+		t.Fatalf("ConfirmReservation: %v", err)
+	}
+	if confirmed.Status != entity.ReservationConfirmed {
+		t.Errorf("Expected a confirmed reservation, got %+v", confirmed)
+	}
+	if m.ID <= 0 || m.ProductID != id || m.Delta != -6 || m.Balance != 0 || m.Reference != "SO-1" || m.CreatedAt.IsZero() {
+		t.Errorf("Expected ConfirmReservation to record the issue, got %+v", m)
+	}
+	expectReserved(t, b, id, 0, 0)
+
+	_, err = b.Products.ConfirmReservation(ctx, first.ID, start, &entity.StockMovement{Type: entity.MovementIssue, Reason: "x"})
+	expectErr(t, "Confirming twice", err, entity.ErrReservationClosed)
+	_, err = b.Products.ReleaseReservation(ctx, first.ID)
+	expectErr(t, "Releasing a confirmed reservation", err, entity.ErrReservationClosed)
+
+	if _, err := b.Products.AddMovement(ctx, id, &entity.StockMovement{Type: entity.MovementReceipt, Delta: 4, Reason: "purchase"}); err != nil {
+		t.Fatalf("AddMovement: %v", err)
+	}
+	stale := reserve(t, b, id, 3, start, time.Minute)
+	released := reserve(t, b, id, 1, start, 2*time.Minute)
+
+	_, err = b.Products.ConfirmReservation(ctx, stale.ID, start.Add(time.Minute), &entity.StockMovement{Type: entity.MovementIssue, Reason: "x"})
+	expectErr(t, "Confirming an expired reservation", err, entity.ErrReservationExpired)
+
+	// The stale hold is expired by the next reservation, not the sweeper.
+	last := reserve(t, b, id, 3, start.Add(time.Minute), time.Minute)
+	if got, _ := b.Products.GetReservation(ctx, stale.ID); got == nil || got.Status != entity.ReservationExpired {
+		t.Errorf("Expected the stale reservation to expire, got %+v", got)
+	}
+	expectReserved(t, b, id, 4, 4)
+
+	got, err = b.Products.ReleaseReservation(ctx, released.ID)
+	if err != nil || got.Status != entity.ReservationReleased {
+		t.Errorf("Expected the reservation to be released, got %+v, %v", got, err)
+	}
+	expectReserved(t, b, id, 4, 3)
+
+	expired, err := b.Products.ExpireReservations(ctx, start.Add(2*time.Minute))
+	if err != nil || expired != 1 {
+		t.Errorf("Expected 1 expired reservation, got %d, %v", expired, err)
+	}
+	if got, _ := b.Products.GetReservation(ctx, last.ID); got == nil || got.Status != entity.ReservationExpired {
+		t.Errorf("Expected the last reservation to expire, got %+v", got)
+	}
+	expectReserved(t, b, id, 4, 0)
+
+	if expired, _ := b.Products.ExpireReservations(ctx, start.Add(time.Hour)); expired != 0 {
+		t.Errorf("Expected nothing left to expire, got %d", expired)
+	}
+
+	_, err = b.Products.GetReservation(ctx, missing)
+	expectErr(t, "GetReservation", err, entity.ErrReservationNotFound)
+	_, err = b.Products.ConfirmReservation(ctx, missing, start, &entity.StockMovement{Type: entity.MovementIssue, Reason: "x"})
+	expectErr(t, "ConfirmReservation", err, entity.ErrReservationNotFound)
+	_, err = b.Products.ReleaseReservation(ctx, missing)
+	expectErr(t, "ReleaseReservation", err, entity.ErrReservationNotFound)
+	err = b.Products.Reserve(ctx, missing, &entity.Reservation{Quantity: 1, ExpiresAt: start.Add(time.Minute), CreatedAt: start})
+	expectErr(t, "Reserve", err, entity.ErrProductNotFound)
+
+	held := reserve(t, b, id, 1, start.Add(time.Hour), time.Minute)
+	if err := b.Products.Delete(ctx, id, 0); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = b.Products.GetReservation(ctx, held.ID)
+	expectErr(t, "GetReservation of a deleted product", err, entity.ErrReservationNotFound)
+}
+
+// testConcurrentReservations races more reservations than there is stock
+// for. Exactly as many as fit must succeed.
+func testConcurrentReservations(t *testing.T, b Backend) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	const stock, attempts = 10, 25
+
+	id := create(t, b, &entity.Product{Name: "widget", Quantity: stock})
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.Products.Reserve(ctx, id, &entity.Reservation{Quantity: 1, ExpiresAt: start.Add(time.Minute), CreatedAt: start})
+			switch {
+			case err == nil:
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			case !errors.Is(err, entity.ErrInsufficientStock):
+				t.Errorf("Reserve: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reserved != stock {
+		t.Errorf("Expected %d reservations to succeed, got %d", stock, reserved)
+	}
+	expectReserved(t, b, id, stock, stock)
+}
+
+func testWarehouses(t *testing.T, b Backend) {
+	ctx := context.Background()
+	const missing = 1_000_000
+
+	first, err := b.Warehouses.Create(ctx, &entity.Warehouse{Code: "MAIN", Name: "Main", Address: "1 Dock Rd"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	second, _ := b.Warehouses.Create(ctx, &entity.Warehouse{Code: "EAST", Name: "East"})
+	if first <= 0 || second <= first {
+		t.Errorf("Expected positive increasing ids, got %d then %d", first, second)
+	}
+
+	_, err = b.Warehouses.Create(ctx, &entity.Warehouse{Code: "MAIN", Name: "Again"})
+	expectErr(t, "Create with a taken code", err, entity.ErrWarehouseCodeTaken)
+
+	expectErr(t, "Update to a taken code", b.Warehouses.Update(ctx, second, &entity.Warehouse{Code: "MAIN", Name: "East"}), entity.ErrWarehouseCodeTaken)
+	expectErr(t, "Update missing", b.Warehouses.Update(ctx, missing, &entity.Warehouse{Code: "WEST", Name: "West"}), entity.ErrWarehouseNotFound)
+
+	if err := b.Warehouses.Update(ctx, first, &entity.Warehouse{Code: "MAIN", Name: "Main DC", Address: "2 Dock Rd"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := b.Warehouses.GetByID(ctx, first)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if want := (entity.Warehouse{ID: first, Code: "MAIN", Name: "Main DC", Address: "2 Dock Rd"}); *got != want {
+		t.Errorf("Expected %+v, got %+v", want, *got)
+	}
+
+	_, err = b.Warehouses.GetByID(ctx, missing)
+	expectErr(t, "GetByID missing", err, entity.ErrWarehouseNotFound)
+
+	all, err := b.Warehouses.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != first || all[1].ID != second {
+		t.Errorf("Expected warehouses in id order, got %+v", all)
+	}
+
+	bin2, _ := b.Warehouses.CreateLocation(ctx, &entity.Location{WarehouseID: first, Code: "A-02", Aisle: "A", Bin: "2"})
+	bin1, _ := b.Warehouses.CreateLocation(ctx, &entity.Location{WarehouseID: first, Code: "A-01", Aisle: "A", Bin: "1"})
+
+	_, err = b.Warehouses.CreateLocation(ctx, &entity.Location{WarehouseID: first, Code: "A-01"})
+	expectErr(t, "CreateLocation with a taken code", err, entity.ErrLocationCodeTaken)
+
+	if _, err := b.Warehouses.CreateLocation(ctx, &entity.Location{WarehouseID: second, Code: "A-01"}); err != nil {
+		t.Errorf("Expected location codes to be unique per warehouse only, got %v", err)
+	}
+
+	_, err = b.Warehouses.CreateLocation(ctx, &entity.Location{WarehouseID: missing, Code: "A-01"})
+	expectErr(t, "CreateLocation in a missing warehouse", err, entity.ErrWarehouseNotFound)
+
+	locations, err := b.Warehouses.GetLocations(ctx, first)
+	if err != nil {
+		t.Fatalf("GetLocations: %v", err)
+	}
+	if len(locations) != 2 || locations[0].ID != bin1 || locations[1].ID != bin2 {
+		t.Errorf("Expected locations in code order, got %+v", locations)
+	}
+
+	_, err = b.Warehouses.GetLocations(ctx, missing)
+	expectErr(t, "GetLocations of a missing warehouse", err, entity.ErrWarehouseNotFound)
+
+	expectErr(t, "UpdateLocation to a taken code", b.Warehouses.UpdateLocation(ctx, bin2, &entity.Location{Code: "A-01"}), entity.ErrLocationCodeTaken)
+	expectErr(t, "UpdateLocation missing", b.Warehouses.UpdateLocation(ctx, missing, &entity.Location{Code: "Z"}), entity.ErrLocationNotFound)
+
+	if err := b.Warehouses.UpdateLocation(ctx, bin2, &entity.Location{Code: "B-01", Aisle: "B", Bin: "1"}); err != nil {
+		t.Fatalf("UpdateLocation: %v", err)
+	}
+	l, err := b.Warehouses.GetLocation(ctx, bin2)
+	if err != nil {
+		t.Fatalf("GetLocation: %v", err)
+	}
+	if want := (entity.Location{ID: bin2, WarehouseID: first, Code: "B-01", Aisle: "B", Bin: "1"}); *l != want {
+		t.Errorf("Expected %+v, got %+v", want, *l)
+	}
+
+	_, err = b.Warehouses.GetLocation(ctx, missing)
+	expectErr(t, "GetLocation missing", err, entity.ErrLocationNotFound)
+
+	expectErr(t, "Delete a warehouse with locations", b.Warehouses.Delete(ctx, first), entity.ErrWarehouseNotEmpty)
+
+	for _, id := range []int64{bin1, bin2} {
+		if err := b.Warehouses.DeleteLocation(ctx, id); err != nil {
+			t.Fatalf("DeleteLocation: %v", err)
+		}
+	}
+	expectErr(t, "DeleteLocation twice", b.Warehouses.DeleteLocation(ctx, bin1), entity.ErrLocationNotFound)
+
+	if err := b.Warehouses.Delete(ctx, first); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	expectErr(t, "Delete twice", b.Warehouses.Delete(ctx, first), entity.ErrWarehouseNotFound)
+}
@@ -0,0 +1,1179 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/tracing"
+)
+
+// querier is what *db.DB and *db.Tx have in common, for statements that run
+// either on their own or as part of a larger transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// schema is what differs between the product tables of the SQL backends.
+// Everything else is written once, with the db.Dialect of the database
+// covering the few statements that differ.
+type schema struct {
+	// price is the column the price is stored in.
+	price string
+	// priceArg converts a price to a statement argument, priceDest returns
+	// the scan destination that reads it back.
+	priceArg  func(entity.Money) interface{}
+	priceDest func(*entity.Money) interface{}
+	// createMany, if set, inserts a run of new products at once, see
+	// batchSteps.
+	createMany func(r *SQLRepository, ctx context.Context, tx *db.Tx, products []*entity.Product) error
+}
+
+// SQLRepository stores products in a SQL database, see NewPostgresRepository
+// and NewSQLiteRepository.
+type SQLRepository struct {
+	errorLogger
+	db     *db.DB
+	schema schema
+	// columns are read by scanProduct.
+	columns string
+}
+
+func newSQLRepository(database *sql.DB, dialect db.Dialect, s schema, opts []Option) *SQLRepository {
+	o := newOptions(opts)
+	return &SQLRepository{
+		errorLogger: errorLogger{o.logger},
+		db:          db.Wrap(database, append(o.db, db.WithDialect(dialect))...),
+		schema:      s,
+		columns: `id, COALESCE(sku, ''), name, description, ` + s.price + `, currency, quantity, version,
+			(SELECT ` + dialect.StringAgg("code", "position") + ` FROM product_barcodes WHERE product_id = products.id)`,
+	}
+}
+
+func (r *SQLRepository) Create(ctx context.Context, p *entity.Product) (_ int64, err error) {
+	defer r.logError(ctx, "create", &err, "name", p.Name)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := r.createProduct(ctx, tx, p); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return p.ID, nil
+}
+
+func (r *SQLRepository) createProduct(ctx context.Context, tx *db.Tx, p *entity.Product) error {
+	query := `
+		INSERT INTO products (sku, name, description, ` + r.schema.price + `, currency, quantity)
+		VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6)
+		RETURNING id, version
+	`
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		p.SKU,
+		p.Name,
+		p.Description,
+		r.schema.priceArg(p.Price),
+		p.Currency,
+		p.Quantity,
+	).Scan(&p.ID, &p.Version)
+
+	if err != nil {
+		return r.uniqueCodeError(err)
+	}
+
+	if err := r.insertBarcodes(ctx, tx, p.ID, p.Barcodes); err != nil {
+		return err
+	}
+
+	if p.Quantity != 0 {
+		m := &entity.StockMovement{
+			ProductID: p.ID,
+			Type:      entity.MovementReceipt,
+			Delta:     p.Quantity,
+			Balance:   p.Quantity,
+			Reason:    "initial_stock",
+		}
+		if err := insertMovement(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Batch applies ops in order in one transaction, see runBatch.
+func (r *SQLRepository) Batch(ctx context.Context, ops []*entity.BatchOperation, atomic bool) (err error) {
+	defer r.logError(ctx, "batch", &err, "operations", len(ops), "atomic", atomic)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := runBatch(ctx, tx, ops, atomic, r.batchSteps()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLRepository) batchSteps() batchSteps {
+	steps := batchSteps{
+		create: r.createProduct,
+		update: r.updateProduct,
+		delete: deleteProduct,
+	}
+	if r.schema.createMany != nil {
+		steps.createMany = func(ctx context.Context, tx *db.Tx, products []*entity.Product) error {
+			return r.schema.createMany(r, ctx, tx, products)
+		}
+	}
+	return steps
+}
+
+// Import reads and locks the products matching keys with one query, then
+// applies the operations build derives from them like a best-effort Batch.
+// Both happen in one transaction, so the products cannot change in between.
+func (r *SQLRepository) Import(ctx context.Context, key entity.ImportKey, keys []string, build func(found []*entity.Product) []*entity.BatchOperation) (err error) {
+	defer r.logError(ctx, "import", &err, "key", key, "keys", len(keys))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	found, err := r.lockByKeys(ctx, tx, key, keys)
+	if err != nil {
+		return err
+	}
+
+	if err := runBatch(ctx, tx, build(found), false, r.batchSteps()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockByKeys returns the products whose SKU, or id, is one of keys and locks
+// them for the rest of the transaction. Rows are locked in id order, so two
+// imports never wait for each other's locks in a cycle.
+func (r *SQLRepository) lockByKeys(ctx context.Context, tx *db.Tx, key entity.ImportKey, keys []string) ([]*entity.Product, error) {
+	found := []*entity.Product{}
+
+	var (
+		placeholders []string
+		args         []interface{}
+	)
+	for _, k := range keys {
+		var arg interface{} = k
+		if key == entity.ImportByID {
+			id, err := strconv.ParseInt(k, 10, 64)
+			if err != nil {
+				continue
+			}
+			arg = id
+		}
+		args = append(args, arg)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+	if len(args) == 0 {
+		return found, nil
+	}
+
+	cond := "id IN (" + strings.Join(placeholders, ", ") + ")"
+	if key == entity.ImportBySKU {
+		for i, p := range placeholders {
+			placeholders[i] = "lower(" + p + ")"
+		}
+		cond = "lower(sku) IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	query := `
+		SELECT ` + r.columns + `
+		FROM products
+		WHERE ` + cond + `
+		ORDER BY id` + r.db.Dialect().ForUpdate()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := r.scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, p)
+	}
+
+	return found, rows.Err()
+}
+
+func (r *SQLRepository) GetByID(ctx context.Context, id int64) (_ *entity.Product, err error) {
+	defer r.logError(ctx, "get", &err, "product_id", id)
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(id))
+
+	return r.getOne(ctx, "id = $1", id)
+}
+
+func (r *SQLRepository) GetBySKU(ctx context.Context, sku string) (_ *entity.Product, err error) {
+	defer r.logError(ctx, "get_by_sku", &err, "sku", sku)
+
+	return r.getOne(ctx, "lower(sku) = lower($1)", sku)
+}
+
+func (r *SQLRepository) GetByBarcode(ctx context.Context, code string) (_ *entity.Product, err error) {
+	defer r.logError(ctx, "get_by_barcode", &err, "barcode", code)
+
+	return r.getOne(ctx, "id = (SELECT product_id FROM product_barcodes WHERE gtin = $1)", entity.GTIN14(code))
+}
+
+// getOne returns the product matching cond, which takes a single argument.
+func (r *SQLRepository) getOne(ctx context.Context, cond string, arg interface{}) (*entity.Product, error) {
+	query := `
+		SELECT ` + r.columns + `
+		FROM products
+		WHERE ` + cond
+
+	p, err := r.scanProduct(r.db.QueryRowContext(ctx, query, arg))
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrProductNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *SQLRepository) Update(ctx context.Context, id int64, p *entity.Product) (err error) {
+	defer r.logError(ctx, "update", &err, "product_id", id)
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(id))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.updateProduct(ctx, tx, id, p); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateProduct checks p.Version before anything else, so a stale write is
+// reported as a conflict even when its quantity would not fit either.
+func (r *SQLRepository) updateProduct(ctx context.Context, tx *db.Tx, id int64, p *entity.Product) error {
+	current, err := r.lockStock(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if p.Version != 0 && p.Version != current.version {
+		return entity.ErrVersionConflict
+	}
+
+	if p.Quantity < current.allocated || p.Quantity < current.reserved {
+		return entity.ErrInsufficientStock
+	}
+
+	query := `
+		UPDATE products
+		SET sku = NULLIF($1, ''), name = $2, description = $3, ` + r.schema.price + ` = $4, currency = $5, quantity = $6, version = version + 1
+		WHERE id = $7
+		RETURNING version
+	`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		p.SKU,
+		p.Name,
+		p.Description,
+		r.schema.priceArg(p.Price),
+		p.Currency,
+		p.Quantity,
+		id,
+	).Scan(&p.Version)
+
+	if err != nil {
+		return r.uniqueCodeError(err)
+	}
+
+	if err := r.replaceBarcodes(ctx, tx, id, p.Barcodes); err != nil {
+		return err
+	}
+
+	if delta := p.Quantity - current.quantity; delta != 0 {
+		m := &entity.StockMovement{
+			ProductID: id,
+			Type:      entity.MovementAdjustment,
+			Delta:     delta,
+			Balance:   p.Quantity,
+			Reason:    "manual_update",
+		}
+		if err := insertMovement(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *SQLRepository) Delete(ctx context.Context, id int64, version int64) (err error) {
+	defer r.logError(ctx, "delete", &err, "product_id", id)
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(id))
+
+	return deleteProduct(ctx, r.db, id, version)
+}
+
+func deleteProduct(ctx context.Context, q querier, id int64, version int64) error {
+	query := `DELETE FROM products WHERE id = $1 AND ($2 = 0 OR version = $2)`
+
+	res, err := q.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		exists, err := productExists(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return entity.ErrVersionConflict
+		}
+		return entity.ErrProductNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLRepository) AddMovement(ctx context.Context, productID int64, m *entity.StockMovement) (_ int64, err error) {
+	defer r.logError(ctx, "add_movement", &err, "product_id", productID, "type", m.Type, "delta", m.Delta)
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(productID))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	s, err := r.lockStock(ctx, tx, productID)
+	if err != nil {
+		return 0, err
+	}
+
+	located := 0
+	if m.LocationID != nil {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM locations WHERE id = $1)`, *m.LocationID).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, entity.ErrLocationNotFound
+		}
+
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT quantity FROM product_stock WHERE product_id = $1 AND location_id = $2`,
+			productID, *m.LocationID,
+		).Scan(&located)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+	}
+
+	newTotal, newAllocated := s.quantity+m.Delta, s.allocated
+	if m.Type == entity.MovementTransfer {
+		newTotal = s.quantity
+	}
+	if m.LocationID != nil {
+		located += m.Delta
+		newAllocated += m.Delta
+	}
+
+	if newTotal < 0 || located < 0 || newTotal < newAllocated || newTotal < s.reserved {
+		return 0, entity.ErrInsufficientStock
+	}
+
+	if newTotal != s.quantity {
+		if _, err := tx.ExecContext(ctx, `UPDATE products SET quantity = $1, version = version + 1 WHERE id = $2`, newTotal, productID); err != nil {
+			return 0, err
+		}
+	}
+
+	if m.LocationID != nil {
+		query := `
+			INSERT INTO product_stock (product_id, location_id, quantity)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (product_id, location_id) DO UPDATE SET quantity = EXCLUDED.quantity
+		`
+		if _, err := tx.ExecContext(ctx, query, productID, *m.LocationID, located); err != nil {
+			return 0, err
+		}
+	}
+
+	m.ProductID = productID
+	m.Balance = newTotal
+	if err := insertMovement(ctx, tx, m); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return m.ID, nil
+}
+
+// ChangeQuantity applies a receipt or issue under the product lock, like
+// every other stock change, so concurrent callers can never overwrite each
+// other's changes. An issue cannot take stock that is allocated to locations
+// or held by reservations.
+func (r *SQLRepository) ChangeQuantity(ctx context.Context, productID int64, m *entity.StockMovement) (err error) {
+	defer r.logError(ctx, "change_quantity", &err, "product_id", productID, "delta", m.Delta)
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(productID))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s, err := r.lockStock(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	quantity := s.quantity + m.Delta
+	if quantity < 0 || quantity < s.allocated || quantity < s.reserved {
+		return entity.ErrInsufficientStock
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET quantity = $1, version = version + 1 WHERE id = $2`, quantity, productID); err != nil {
+		return err
+	}
+
+	m.ProductID = productID
+	m.Balance = quantity
+	if err := insertMovement(ctx, tx, m); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLRepository) GetMovements(ctx context.Context, productID int64) (_ []*entity.StockMovement, err error) {
+	defer r.logError(ctx, "get_movements", &err, "product_id", productID)
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(productID))
+
+	exists, err := productExists(ctx, r.db, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, entity.ErrProductNotFound
+	}
+
+	query := `
+		SELECT id, product_id, location_id, type, delta, balance, reason, reference, created_at
+		FROM stock_movements
+		WHERE product_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []*entity.StockMovement{}
+
+	for rows.Next() {
+		var m entity.StockMovement
+		if err := rows.Scan(
+			&m.ID,
+			&m.ProductID,
+			&m.LocationID,
+			&m.Type,
+			&m.Delta,
+			&m.Balance,
+			&m.Reason,
+			&m.Reference,
+			&m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		movements = append(movements, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// replaceBarcodes makes codes the barcodes of the product, in that order.
+func (r *SQLRepository) replaceBarcodes(ctx context.Context, tx *db.Tx, productID int64, codes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_barcodes WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+
+	return r.insertBarcodes(ctx, tx, productID, codes)
+}
+
+func (r *SQLRepository) insertBarcodes(ctx context.Context, tx *db.Tx, productID int64, codes []string) error {
+	query := `INSERT INTO product_barcodes (gtin, code, product_id, position) VALUES ($1, $2, $3, $4)`
+	for i, code := range codes {
+		if _, err := tx.ExecContext(ctx, query, entity.GTIN14(code), code, productID, i); err != nil {
+			return r.uniqueCodeError(err)
+		}
+	}
+
+	return nil
+}
+
+func insertMovement(ctx context.Context, tx *db.Tx, m *entity.StockMovement) error {
+	query := `
+		INSERT INTO stock_movements (product_id, location_id, type, delta, balance, reason, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		m.ProductID,
+		m.LocationID,
+		m.Type,
+		m.Delta,
+		m.Balance,
+		m.Reason,
+		m.Reference,
+	).Scan(&m.ID, &m.CreatedAt)
+}
+
+func (r *SQLRepository) GetStock(ctx context.Context, productID int64, warehouseID *int64) (_ *entity.StockBreakdown, err error) {
+	defer r.logError(ctx, "get_stock", &err, "product_id", productID)
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(productID))
+
+	b := &entity.StockBreakdown{ProductID: productID, Locations: []*entity.StockLevel{}}
+
+	query := `
+		SELECT p.quantity, p.quantity - COALESCE(SUM(ps.quantity), 0), p.reserved
+		FROM products p
+		LEFT JOIN product_stock ps ON ps.product_id = p.id
+		WHERE p.id = $1
+		GROUP BY p.id
+	`
+
+	err = r.db.QueryRowContext(ctx, query, productID).Scan(&b.Quantity, &b.Unallocated, &b.Reserved)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrProductNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	b.Available = b.Quantity - b.Reserved
+
+	query = `
+		SELECT l.warehouse_id, l.id, l.code, ps.quantity
+		FROM product_stock ps
+		JOIN locations l ON l.id = ps.location_id
+		WHERE ps.product_id = $1 AND ps.quantity > 0 AND (CAST($2 AS BIGINT) IS NULL OR l.warehouse_id = $2)
+		ORDER BY l.warehouse_id, l.code
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, productID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l entity.StockLevel
+		if err := rows.Scan(&l.WarehouseID, &l.LocationID, &l.LocationCode, &l.Quantity); err != nil {
+			return nil, err
+		}
+		b.Locations = append(b.Locations, &l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// lockedStock is the state of a product that stock changes are checked
+// against.
+type lockedStock struct {
+	// quantity is the product quantity, allocated the part of it allocated
+	// to locations and reserved the part held by reservations.
+	quantity, allocated, reserved int
+	version                       int64
+}
+
+// lockStock locks the product row for the rest of the transaction and returns
+// its stock. All stock changes go through this lock, so they are serialized
+// per product. SQLite has no row locks; its transactions hold the write lock
+// from the start instead, see db.NewSQLiteDB.
+func (r *SQLRepository) lockStock(ctx context.Context, tx *db.Tx, productID int64) (lockedStock, error) {
+	var s lockedStock
+
+	query := `SELECT quantity, reserved, version FROM products WHERE id = $1` + r.db.Dialect().ForUpdate()
+	err := tx.QueryRowContext(ctx, query, productID).Scan(&s.quantity, &s.reserved, &s.version)
+
+	if err == sql.ErrNoRows {
+		return s, entity.ErrProductNotFound
+	}
+
+	if err != nil {
+		return s, err
+	}
+
+	query = `SELECT COALESCE(SUM(quantity), 0) FROM product_stock WHERE product_id = $1`
+	if err := tx.QueryRowContext(ctx, query, productID).Scan(&s.allocated); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// Reserve holds stock for r. Holds of the product that have expired by
+// r.CreatedAt are expired first, so they do not block the new one.
+func (r *SQLRepository) Reserve(ctx context.Context, productID int64, res *entity.Reservation) (err error) {
+	defer r.logError(ctx, "reserve", &err, "product_id", productID, "quantity", res.Quantity)
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(productID))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s, err := r.lockStock(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	_, stale, err := r.expireHolds(ctx, tx, productID, res.CreatedAt)
+	if err != nil {
+		return err
+	}
+	reserved := s.reserved - stale
+
+	if s.quantity-reserved < res.Quantity {
+		return entity.ErrInsufficientStock
+	}
+
+	query := `
+		INSERT INTO reservations (product_id, quantity, status, reference, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	d := r.db.Dialect()
+	res.ProductID = productID
+	res.Status = entity.ReservationActive
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		productID,
+		res.Quantity,
+		res.Status,
+		res.Reference,
+		d.Time(res.ExpiresAt),
+		d.Time(res.CreatedAt),
+	).Scan(&res.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET reserved = $1 WHERE id = $2`, reserved+res.Quantity, productID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLRepository) GetReservation(ctx context.Context, id int64) (_ *entity.Reservation, err error) {
+	defer r.logError(ctx, "get_reservation", &err, "reservation_id", id)
+
+	return getReservation(ctx, r.db, id)
+}
+
+// ConfirmReservation issues the reserved units, recording m as the issue.
+func (r *SQLRepository) ConfirmReservation(ctx context.Context, id int64, now time.Time, m *entity.StockMovement) (_ *entity.Reservation, err error) {
+	defer r.logError(ctx, "confirm_reservation", &err, "reservation_id", id)
+
+	return r.closeReservation(ctx, id, func(tx *db.Tx, res *entity.Reservation, s lockedStock) error {
+		if !now.Before(res.ExpiresAt) {
+			return entity.ErrReservationExpired
+		}
+
+		balance := s.quantity - res.Quantity
+		if balance < s.allocated {
+			return entity.ErrInsufficientStock
+		}
+
+		query := `UPDATE products SET quantity = $1, reserved = reserved - $2, version = version + 1 WHERE id = $3`
+		if _, err := tx.ExecContext(ctx, query, balance, res.Quantity, res.ProductID); err != nil {
+			return err
+		}
+
+		res.Status = entity.ReservationConfirmed
+
+		m.ProductID = res.ProductID
+		m.Delta = -res.Quantity
+		m.Balance = balance
+		m.Reference = res.Reference
+		return insertMovement(ctx, tx, m)
+	})
+}
+
+func (r *SQLRepository) ReleaseReservation(ctx context.Context, id int64) (_ *entity.Reservation, err error) {
+	defer r.logError(ctx, "release_reservation", &err, "reservation_id", id)
+
+	return r.closeReservation(ctx, id, func(tx *db.Tx, res *entity.Reservation, _ lockedStock) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE products SET reserved = reserved - $1 WHERE id = $2`, res.Quantity, res.ProductID); err != nil {
+			return err
+		}
+
+		res.Status = entity.ReservationReleased
+		return nil
+	})
+}
+
+// closeReservation runs close on an active reservation with its product
+// locked, then stores the status close set. Every change of a reservation
+// happens under the lock of its product, so the status read here is stable.
+func (r *SQLRepository) closeReservation(ctx context.Context, id int64, close func(tx *db.Tx, res *entity.Reservation, s lockedStock) error) (*entity.Reservation, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var productID int64
+	err = tx.QueryRowContext(ctx, `SELECT product_id FROM reservations WHERE id = $1`, id).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ctx = db.WithSpanAttributes(ctx, tracing.ProductIDKey.Int64(productID))
+
+	s, err := r.lockStock(ctx, tx, productID)
+	if errors.Is(err, entity.ErrProductNotFound) {
+		return nil, entity.ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := getReservation(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != entity.ReservationActive {
+		return nil, entity.ErrReservationClosed
+	}
+
+	if err := close(tx, res, s); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE reservations SET status = $1 WHERE id = $2`, res.Status, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ExpireReservations expires every hold past its expiry at now and returns
+// how many there were. Each product is handled in its own transaction under
+// its row lock, like every other change of its reservations.
+func (r *SQLRepository) ExpireReservations(ctx context.Context, now time.Time) (expired int, err error) {
+	defer r.logError(ctx, "expire_reservations", &err)
+
+	query := `SELECT DISTINCT product_id FROM reservations WHERE status = 'active' AND expires_at <= $1`
+	rows, err := r.db.QueryContext(ctx, query, r.db.Dialect().Time(now))
+	if err != nil {
+		return 0, err
+	}
+
+	var productIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		productIDs = append(productIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, productID := range productIDs {
+		n, err := r.expireProductHolds(ctx, productID, now)
+		if err != nil {
+			return expired, err
+		}
+		expired += n
+	}
+
+	return expired, nil
+}
+
+func (r *SQLRepository) expireProductHolds(ctx context.Context, productID int64, now time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := r.lockStock(ctx, tx, productID); errors.Is(err, entity.ErrProductNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	count, quantity, err := r.expireHolds(ctx, tx, productID, now)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET reserved = reserved - $1 WHERE id = $2`, quantity, productID); err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// expireHolds marks the holds of a locked product that have expired by now
+// and returns how many there were and how much stock they held. The caller
+// updates products.reserved.
+func (r *SQLRepository) expireHolds(ctx context.Context, tx *db.Tx, productID int64, now time.Time) (count, quantity int, err error) {
+	query := `
+		UPDATE reservations
+		SET status = 'expired'
+		WHERE product_id = $1 AND status = 'active' AND expires_at <= $2
+		RETURNING quantity
+	`
+
+	rows, err := tx.QueryContext(ctx, query, productID, r.db.Dialect().Time(now))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var q int
+		if err := rows.Scan(&q); err != nil {
+			return 0, 0, err
+		}
+		count++
+		quantity += q
+	}
+
+	return count, quantity, rows.Err()
+}
+
+// getReservation reads a reservation through the database or a transaction.
+func getReservation(ctx context.Context, q querier, id int64) (*entity.Reservation, error) {
+	query := `
+		SELECT id, product_id, quantity, status, reference, expires_at, created_at
+		FROM reservations
+		WHERE id = $1
+	`
+
+	var res entity.Reservation
+	err := q.QueryRowContext(ctx, query, id).Scan(
+		&res.ID,
+		&res.ProductID,
+		&res.Quantity,
+		&res.Status,
+		&res.Reference,
+		&res.ExpiresAt,
+		&res.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrReservationNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+var sortFields = map[string]bool{
+	"id":       true,
+	"name":     true,
+	"price":    true,
+	"quantity": true,
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetAll matches names regardless of case. On SQLite that holds for ASCII
+// letters only, see db.Dialect.ILike.
+func (r *SQLRepository) GetAll(ctx context.Context, q entity.ProductQuery) (_ *entity.ProductPage, err error) {
+	defer r.logError(ctx, "list", &err, "sort", q.SortField, "limit", q.Limit)
+
+	if !sortFields[q.SortField] {
+		return nil, entity.NewValidationError("sort", "unsupported", "unsupported sort field")
+	}
+	column := q.SortField
+	if column == "price" {
+		column = r.schema.price
+	}
+
+	var (
+		conds []string
+		args  []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	f := q.Filter
+	if f.NameContains != "" {
+		conds = append(conds, "name "+r.db.Dialect().ILike()+" '%' || "+arg(likeEscaper.Replace(f.NameContains))+` || '%' ESCAPE '\'`)
+	}
+	if f.MinPrice != nil {
+		conds = append(conds, r.schema.price+" >= "+arg(r.schema.priceArg(*f.MinPrice)))
+	}
+	if f.MaxPrice != nil {
+		conds = append(conds, r.schema.price+" <= "+arg(r.schema.priceArg(*f.MaxPrice)))
+	}
+	if f.MinQuantity != nil {
+		conds = append(conds, "quantity >= "+arg(*f.MinQuantity))
+	}
+	if f.MaxQuantity != nil {
+		conds = append(conds, "quantity <= "+arg(*f.MaxQuantity))
+	}
+	if f.WarehouseID != nil {
+		conds = append(conds, `EXISTS (
+			SELECT 1 FROM product_stock ps
+			JOIN locations l ON l.id = ps.location_id
+			WHERE ps.product_id = products.id AND ps.quantity > 0 AND l.warehouse_id = `+arg(*f.WarehouseID)+`)`)
+	}
+
+	page := &entity.ProductPage{Items: []*entity.Product{}}
+
+	if q.WithTotal {
+		var total int64
+		countQuery := "SELECT COUNT(*) FROM products" + where(conds)
+		if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	dir, cmp := "ASC", ">"
+	if q.SortDesc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if q.After != nil {
+		if column == "id" {
+			conds = append(conds, "id "+cmp+" "+arg(q.After.ID))
+		} else {
+			value, err := r.cursorValue(q.SortField, q.After.Value)
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, fmt.Sprintf("(%s, id) %s (%s, %s)", column, cmp, arg(value), arg(q.After.ID)))
+		}
+	}
+
+	order := "id " + dir
+	if column != "id" {
+		order = column + " " + dir + ", " + order
+	}
+
+	query := `
+		SELECT ` + r.columns + `
+		FROM products` + where(conds) + `
+		ORDER BY ` + order + `
+		LIMIT ` + arg(q.Limit+1)
+	if q.Offset > 0 {
+		query += " OFFSET " + arg(q.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := r.scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.Next = &entity.Cursor{
+			Sort:  q.SortField,
+			Desc:  q.SortDesc,
+			Value: sortValue(last, q.SortField),
+			ID:    last.ID,
+		}
+	}
+
+	return page, nil
+}
+
+// cursorValue converts the sort value of a cursor to the column type, so the
+// comparison does not depend on how the database coerces text.
+func (r *SQLRepository) cursorValue(field, value string) (interface{}, error) {
+	switch field {
+	case "price":
+		m, err := entity.ParseMoney(value)
+		if err != nil {
+			return nil, entity.ErrInvalidCursor
+		}
+		return r.schema.priceArg(m), nil
+	case "quantity":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, entity.ErrInvalidCursor
+		}
+		return n, nil
+	default:
+		return value, nil
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProduct reads r.columns. Barcodes come as a comma separated list, since
+// SQLite has no arrays and GTINs never contain commas.
+func (r *SQLRepository) scanProduct(row rowScanner) (*entity.Product, error) {
+	var (
+		p        entity.Product
+		barcodes sql.NullString
+	)
+
+	if err := row.Scan(
+		&p.ID,
+		&p.SKU,
+		&p.Name,
+		&p.Description,
+		r.schema.priceDest(&p.Price),
+		&p.Currency,
+		&p.Quantity,
+		&p.Version,
+		&barcodes,
+	); err != nil {
+		return nil, err
+	}
+
+	p.Barcodes = []string{}
+	if barcodes.Valid {
+		p.Barcodes = strings.Split(barcodes.String, ",")
+	}
+	return &p, nil
+}
+
+// uniqueCodeError maps a violation of the SKU or barcode uniqueness to its
+// domain error, so it is reported as a conflict. Both dialects name the
+// index or table in the message.
+func (r *SQLRepository) uniqueCodeError(err error) error {
+	if !r.db.Dialect().IsUniqueViolation(err) {
+		return err
+	}
+
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "products_sku_key"):
+		return entity.ErrSKUTaken
+	case strings.Contains(msg, "product_barcodes"):
+		return entity.ErrBarcodeTaken
+	}
+	return err
+}
+
+func productExists(ctx context.Context, q querier, id int64) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, id).Scan(&exists)
+	return exists, err
+}
+
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "\n\t\tWHERE " + strings.Join(conds, " AND ")
+}
+
+func sortValue(p *entity.Product, field string) string {
+	switch field {
+	case "name":
+		return p.Name
+	case "price":
+		return p.Price.String()
+	case "quantity":
+		return strconv.Itoa(p.Quantity)
+	default:
+		return strconv.FormatInt(p.ID, 10)
+	}
+}
@@ -0,0 +1,35 @@
+package product
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+)
+
+// NewSQLiteRepository stores products in a SQLite database opened with
+// db.NewSQLiteDB. Prices are kept in cents since SQLite has no exact decimal
+// type. Statements do not cross the network, so products in a batch are
+// inserted one at a time.
+func NewSQLiteRepository(database *sql.DB, opts ...Option) *SQLRepository {
+	return newSQLRepository(database, db.SQLite, sqliteSchema, opts)
+}
+
+var sqliteSchema = schema{
+	price:     "price_cents",
+	priceArg:  func(m entity.Money) interface{} { return int64(m) },
+	priceDest: func(m *entity.Money) interface{} { return (*cents)(m) },
+}
+
+// cents reads a price stored in whole cents.
+type cents entity.Money
+
+func (c *cents) Scan(src interface{}) error {
+	n, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into cents", src)
+	}
+	*c = cents(n)
+	return nil
+}
@@ -0,0 +1,20 @@
+package warehouse
+
+import (
+	"context"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+type Repository interface {
+	Create(ctx context.Context, warehouse *entity.Warehouse) (int64, error)
+	GetByID(ctx context.Context, id int64) (*entity.Warehouse, error)
+	Update(ctx context.Context, id int64, warehouse *entity.Warehouse) error
+	Delete(ctx context.Context, id int64) error
+	GetAll(ctx context.Context) ([]*entity.Warehouse, error)
+	CreateLocation(ctx context.Context, location *entity.Location) (int64, error)
+	GetLocation(ctx context.Context, id int64) (*entity.Location, error)
+	UpdateLocation(ctx context.Context, id int64, location *entity.Location) error
+	DeleteLocation(ctx context.Context, id int64) error
+	GetLocations(ctx context.Context, warehouseID int64) ([]*entity.Location, error)
+}
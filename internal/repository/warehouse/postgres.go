@@ -0,0 +1,11 @@
+package warehouse
+
+import (
+	"database/sql"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+)
+
+func NewPostgresRepository(database *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db.Wrap(database)}
+}
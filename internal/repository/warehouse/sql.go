@@ -0,0 +1,266 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+)
+
+// SQLRepository stores warehouses in a SQL database, see
+// NewPostgresRepository and NewSQLiteRepository. The statements are the
+// same for both; the db.Dialect tells their constraint errors apart.
+type SQLRepository struct {
+	db *db.DB
+}
+
+func (r *SQLRepository) Create(ctx context.Context, w *entity.Warehouse) (int64, error) {
+	query := `
+		INSERT INTO warehouses (code, name, address)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query, w.Code, w.Name, w.Address).Scan(&id)
+
+	if r.db.Dialect().IsUniqueViolation(err) {
+		return 0, entity.ErrWarehouseCodeTaken
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *SQLRepository) GetByID(ctx context.Context, id int64) (*entity.Warehouse, error) {
+	query := `
+		SELECT id, code, name, address
+		FROM warehouses
+		WHERE id = $1
+	`
+
+	var w entity.Warehouse
+
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&w.ID, &w.Code, &w.Name, &w.Address)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrWarehouseNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (r *SQLRepository) Update(ctx context.Context, id int64, w *entity.Warehouse) error {
+	query := `
+		UPDATE warehouses
+		SET code = $1, name = $2, address = $3
+		WHERE id = $4
+	`
+
+	res, err := r.db.ExecContext(ctx, query, w.Code, w.Name, w.Address, id)
+
+	if r.db.Dialect().IsUniqueViolation(err) {
+		return entity.ErrWarehouseCodeTaken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res, entity.ErrWarehouseNotFound)
+}
+
+func (r *SQLRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM warehouses WHERE id = $1`, id)
+
+	if r.db.Dialect().IsForeignKeyViolation(err) {
+		return entity.ErrWarehouseNotEmpty
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res, entity.ErrWarehouseNotFound)
+}
+
+func (r *SQLRepository) GetAll(ctx context.Context) ([]*entity.Warehouse, error) {
+	query := `
+		SELECT id, code, name, address
+		FROM warehouses
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := []*entity.Warehouse{}
+
+	for rows.Next() {
+		var w entity.Warehouse
+		if err := rows.Scan(&w.ID, &w.Code, &w.Name, &w.Address); err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, &w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return warehouses, nil
+}
+
+func (r *SQLRepository) CreateLocation(ctx context.Context, l *entity.Location) (int64, error) {
+	query := `
+		INSERT INTO locations (warehouse_id, code, aisle, bin)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query, l.WarehouseID, l.Code, l.Aisle, l.Bin).Scan(&id)
+
+	if r.db.Dialect().IsForeignKeyViolation(err) {
+		return 0, entity.ErrWarehouseNotFound
+	}
+
+	if r.db.Dialect().IsUniqueViolation(err) {
+		return 0, entity.ErrLocationCodeTaken
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *SQLRepository) GetLocation(ctx context.Context, id int64) (*entity.Location, error) {
+	query := `
+		SELECT id, warehouse_id, code, aisle, bin
+		FROM locations
+		WHERE id = $1
+	`
+
+	var l entity.Location
+
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&l.ID, &l.WarehouseID, &l.Code, &l.Aisle, &l.Bin)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrLocationNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+func (r *SQLRepository) UpdateLocation(ctx context.Context, id int64, l *entity.Location) error {
+	query := `
+		UPDATE locations
+		SET code = $1, aisle = $2, bin = $3
+		WHERE id = $4
+	`
+
+	res, err := r.db.ExecContext(ctx, query, l.Code, l.Aisle, l.Bin, id)
+
+	if r.db.Dialect().IsUniqueViolation(err) {
+		return entity.ErrLocationCodeTaken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return expectRow(res, entity.ErrLocationNotFound)
+}
+
+func (r *SQLRepository) DeleteLocation(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_stock WHERE location_id = $1 AND quantity = 0`, id); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM locations WHERE id = $1`, id)
+
+	if r.db.Dialect().IsForeignKeyViolation(err) {
+		return entity.ErrLocationNotEmpty
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := expectRow(res, entity.ErrLocationNotFound); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLRepository) GetLocations(ctx context.Context, warehouseID int64) ([]*entity.Location, error) {
+	if _, err := r.GetByID(ctx, warehouseID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, warehouse_id, code, aisle, bin
+		FROM locations
+		WHERE warehouse_id = $1
+		ORDER BY code
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []*entity.Location{}
+
+	for rows.Next() {
+		var l entity.Location
+		if err := rows.Scan(&l.ID, &l.WarehouseID, &l.Code, &l.Aisle, &l.Bin); err != nil {
+			return nil, err
+		}
+		locations = append(locations, &l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func expectRow(res sql.Result, notFound error) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return notFound
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package warehouse
+
+import (
+	"database/sql"
+
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
+)
+
+// NewSQLiteRepository stores warehouses in a SQLite database opened with
+// db.NewSQLiteDB.
+func NewSQLiteRepository(database *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db.Wrap(database, db.WithDialect(db.SQLite))}
+}
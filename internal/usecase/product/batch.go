@@ -0,0 +1,94 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Batch applies ops in order. An atomic batch applies all of them or none:
+// every operation is validated up front, and the first one that fails makes
+// Batch return its error, naming the operation. Otherwise each operation
+// that is invalid or fails only gets its Err set and the others are applied;
+// an error is then returned only when the batch could not run at all.
+func (s *Service) Batch(ctx context.Context, ops []*entity.BatchOperation, atomic bool) (err error) {
+	ctx, span := s.tracer.Start(ctx, "product.Service.Batch", trace.WithAttributes(
+		attribute.Int("batch.size", len(ops)),
+		attribute.Bool("batch.atomic", atomic),
+	))
+	defer tracing.End(span, &err)
+
+	switch {
+	case len(ops) == 0:
+		return entity.NewValidationError("operations", "required", "at least one operation is required")
+	case len(ops) > s.maxBatchSize:
+		return entity.NewValidationError("operations", "too_many", fmt.Sprintf("a batch can have at most %d operations", s.maxBatchSize))
+	}
+
+	var all entity.Violations
+	valid := make([]*entity.BatchOperation, 0, len(ops))
+	for i, op := range ops {
+		if op.Product != nil {
+			normalizeProduct(op.Product)
+		}
+
+		var v entity.Violations
+		validateBatchOperation(&v, op)
+		if op.Err = v.Err(); op.Err == nil {
+			valid = append(valid, op)
+			continue
+		}
+
+		var verr *entity.ValidationError
+		errors.As(op.Err, &verr)
+		for _, f := range verr.Fields {
+			all.Add(fmt.Sprintf("operations[%d].%s", i, f.Field), f.Code, f.Message)
+		}
+	}
+
+	if atomic {
+		if err := all.Err(); err != nil {
+			return err
+		}
+	}
+
+	if len(valid) > 0 {
+		if err := s.repo.Batch(ctx, valid, atomic); err != nil {
+			if atomic && entity.IsDomainError(err) {
+				for i, op := range ops {
+					if op.Err != nil {
+						return fmt.Errorf("operation %d: %w", i, err)
+					}
+				}
+			}
+			return err
+		}
+	}
+
+	counts := map[entity.BatchAction]int{}
+	failed := 0
+	for _, op := range ops {
+		if op.Err != nil {
+			failed++
+			continue
+		}
+		counts[op.Action]++
+		if op.Action == entity.BatchCreate {
+			s.recorder.ProductCreated()
+		}
+	}
+
+	s.logger.InfoContext(ctx, "batch applied",
+		"atomic", atomic,
+		"created", counts[entity.BatchCreate],
+		"updated", counts[entity.BatchUpdate],
+		"deleted", counts[entity.BatchDelete],
+		"failed", failed)
+	return nil
+}
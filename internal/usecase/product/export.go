@@ -0,0 +1,46 @@
+package product
+
+import (
+	"context"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/tracing"
+)
+
+// Export calls fn for every product matching q.Filter, in q's order. It
+// walks the listing a page at a time with the keyset cursor, so only one
+// page is held in memory however large the catalog is. The limit, offset and
+// cursor of q are ignored.
+func (s *Service) Export(ctx context.Context, q entity.ProductQuery, fn func(*entity.Product) error) (err error) {
+	ctx, span := s.tracer.Start(ctx, "product.Service.Export")
+	defer tracing.End(span, &err)
+
+	if q.SortField == "" {
+		q.SortField = "id"
+	}
+	q.Limit, q.Offset, q.After, q.WithTotal = MaxPageSize, 0, nil, false
+
+	var v entity.Violations
+	validateQuery(&v, q)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	for {
+		page, err := s.repo.GetAll(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range page.Items {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+
+		if page.Next == nil {
+			return nil
+		}
+		q.After = page.Next
+	}
+}
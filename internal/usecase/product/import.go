@@ -0,0 +1,287 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ImportFields are the product fields an import row can set, in the order
+// Export writes them.
+var ImportFields = []string{"id", "sku", "name", "description", "price", "currency", "quantity", "barcodes"}
+
+// BarcodeSeparator separates the barcodes of a product within one cell.
+const BarcodeSeparator = ";"
+
+// importChunkSize is how many rows are written per transaction.
+const importChunkSize = 500
+
+// ImportSource yields the rows of an import in file order, then io.EOF. Any
+// other error means the rest of the file cannot be read.
+type ImportSource interface {
+	Next() (*entity.ImportRow, error)
+}
+
+// Import creates or updates a product for every row of src. A row matches
+// an existing product by opts.Key: by SKU every row needs one, by id a row
+// without one is a new product and a row whose id matches no product fails
+// with ErrProductNotFound, since ids are never chosen by the file. Rows are
+// checked against the same rules as Create and Update, and a key may appear
+// only once per file. Rows are written in chunks, each in a transaction of
+// its own that also reads the products the chunk matches; a row that fails
+// is reported and does not hold up the rest of its chunk. If src fails, the
+// chunks written before stay.
+func (s *Service) Import(ctx context.Context, src ImportSource, opts entity.ImportOptions) (_ *entity.ImportReport, err error) {
+	ctx, span := s.tracer.Start(ctx, "product.Service.Import", trace.WithAttributes(
+		attribute.String("import.key", string(opts.Key)),
+		attribute.Bool("import.dry_run", opts.DryRun),
+	))
+	defer tracing.End(span, &err)
+
+	if !opts.Key.Valid() {
+		return nil, entity.NewValidationError("key", "unsupported", "key must be sku or id")
+	}
+
+	imp := &importer{Service: s, opts: opts, report: &entity.ImportReport{}, seen: map[string]int{}}
+	for {
+		row, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := imp.add(ctx, row); err != nil {
+			return nil, err
+		}
+	}
+	if err := imp.flush(ctx); err != nil {
+		return nil, err
+	}
+
+	r := imp.report
+	sort.SliceStable(r.Errors, func(i, j int) bool { return r.Errors[i].Row < r.Errors[j].Row })
+
+	s.logger.InfoContext(ctx, "products imported",
+		"dry_run", opts.DryRun,
+		"rows", r.Rows,
+		"created", r.Created,
+		"updated", r.Updated,
+		"failed", r.Failed)
+	return r, nil
+}
+
+type importer struct {
+	*Service
+	opts   entity.ImportOptions
+	report *entity.ImportReport
+	// seen maps the key of every row so far to the row it was in.
+	seen  map[string]int
+	chunk []importItem
+}
+
+type importItem struct {
+	row int
+	// key is the canonical key of the row, empty for a new product by id.
+	key    string
+	values map[string]string
+	op     *entity.BatchOperation
+	err    error
+}
+
+func (imp *importer) add(ctx context.Context, row *entity.ImportRow) error {
+	imp.report.Rows++
+	if row.Err != nil {
+		imp.report.Fail(row.Number, row.Err)
+		return nil
+	}
+
+	key, err := imp.key(row)
+	if err != nil {
+		imp.report.Fail(row.Number, err)
+		return nil
+	}
+
+	imp.chunk = append(imp.chunk, importItem{row: row.Number, key: key, values: row.Values})
+	if len(imp.chunk) == importChunkSize {
+		return imp.flush(ctx)
+	}
+	return nil
+}
+
+// key returns the key of row in canonical form and checks that no row
+// before had it.
+func (imp *importer) key(row *entity.ImportRow) (string, error) {
+	field := string(imp.opts.Key)
+	key := strings.TrimSpace(row.Values[field])
+
+	switch {
+	case imp.opts.Key == entity.ImportBySKU && key == "":
+		return "", entity.NewValidationError(field, "required", "sku is required to match products by sku")
+	case imp.opts.Key == entity.ImportByID && key != "":
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || id <= 0 {
+			return "", entity.NewValidationError(field, "invalid", "invalid id")
+		}
+		key = strconv.FormatInt(id, 10)
+	}
+
+	if key != "" {
+		// SKUs are unique regardless of case.
+		seenKey := strings.ToUpper(key)
+		if first, ok := imp.seen[seenKey]; ok {
+			return "", entity.NewValidationError(field, "duplicate", fmt.Sprintf("%s %s is already used in row %d", field, key, first))
+		}
+		imp.seen[seenKey] = row.Number
+	}
+
+	return key, nil
+}
+
+// productKey is the key p is matched with, in the form seen uses.
+func (imp *importer) productKey(p *entity.Product) string {
+	if imp.opts.Key == entity.ImportBySKU {
+		return strings.ToUpper(p.SKU)
+	}
+	return strconv.FormatInt(p.ID, 10)
+}
+
+// operation turns item into the create or update it asks for, given the
+// product its key matched. The update carries the version the product was
+// read at, and the product stays locked until the chunk is written.
+func (imp *importer) operation(item importItem, existing *entity.Product) (*entity.BatchOperation, error) {
+	if existing == nil && imp.opts.Key == entity.ImportByID && item.key != "" {
+		return nil, entity.ErrProductNotFound
+	}
+
+	p := &entity.Product{}
+	if existing != nil {
+		*p = *existing
+		p.Barcodes = append([]string(nil), existing.Barcodes...)
+	}
+
+	var v entity.Violations
+	setImportValues(&v, p, item.values)
+	normalizeProduct(p)
+	validateProduct(&v, p)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if existing == nil {
+		return &entity.BatchOperation{Action: entity.BatchCreate, Product: p}, nil
+	}
+	return &entity.BatchOperation{Action: entity.BatchUpdate, ID: existing.ID, Version: existing.Version, Product: p}, nil
+}
+
+// setImportValues copies the cells of a row onto p. A column in the file
+// always sets its field, even when the cell is empty; a price or quantity
+// that does not parse is reported and leaves the field as it was.
+func setImportValues(v *entity.Violations, p *entity.Product, values map[string]string) {
+	for _, field := range ImportFields {
+		raw, ok := values[field]
+		if !ok {
+			continue
+		}
+		value := strings.TrimSpace(raw)
+
+		switch field {
+		case "sku":
+			p.SKU = value
+		case "name":
+			p.Name = value
+		case "description":
+			p.Description = value
+		case "currency":
+			p.Currency = value
+		case "price":
+			price, err := entity.ParseMoney(value)
+			if err != nil {
+				v.Add("price", "invalid", "invalid price")
+				continue
+			}
+			p.Price = price
+		case "quantity":
+			quantity, err := strconv.Atoi(value)
+			if err != nil {
+				v.Add("quantity", "invalid", "invalid quantity")
+				continue
+			}
+			p.Quantity = quantity
+		case "barcodes":
+			p.Barcodes = nil
+			for _, code := range strings.Split(value, BarcodeSeparator) {
+				if code = strings.TrimSpace(code); code != "" {
+					p.Barcodes = append(p.Barcodes, code)
+				}
+			}
+		}
+	}
+}
+
+// flush writes the rows collected so far in one transaction. The products
+// they match are read with a single lookup inside it.
+func (imp *importer) flush(ctx context.Context) error {
+	if len(imp.chunk) == 0 {
+		return nil
+	}
+	defer func() { imp.chunk = imp.chunk[:0] }()
+
+	var keys []string
+	for _, item := range imp.chunk {
+		if item.key != "" {
+			keys = append(keys, item.key)
+		}
+	}
+
+	build := func(found []*entity.Product) []*entity.BatchOperation {
+		existing := make(map[string]*entity.Product, len(found))
+		for _, p := range found {
+			existing[imp.productKey(p)] = p
+		}
+
+		var ops []*entity.BatchOperation
+		for i := range imp.chunk {
+			item := &imp.chunk[i]
+			item.op, item.err = imp.operation(*item, existing[strings.ToUpper(item.key)])
+			if item.err == nil {
+				ops = append(ops, item.op)
+			}
+		}
+		if imp.opts.DryRun {
+			return nil
+		}
+		return ops
+	}
+	if err := imp.repo.Import(ctx, imp.opts.Key, keys, build); err != nil {
+		return err
+	}
+
+	for _, item := range imp.chunk {
+		switch {
+		case item.err != nil:
+			imp.report.Fail(item.row, item.err)
+		case item.op.Err != nil:
+			imp.report.Fail(item.row, item.op.Err)
+		case item.op.Action == entity.BatchCreate:
+			imp.report.Created++
+			if !imp.opts.DryRun {
+				imp.recorder.ProductCreated()
+			}
+		default:
+			imp.report.Updated++
+		}
+	}
+	return nil
+}
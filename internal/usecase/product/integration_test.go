@@ -1,29 +1,46 @@
 package product
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/imbafff/product-warehouse-api/internal/entity"
 	"github.com/imbafff/product-warehouse-api/internal/infrastructure/config"
 	"github.com/imbafff/product-warehouse-api/internal/infrastructure/db"
 	productRepo "github.com/imbafff/product-warehouse-api/internal/repository/product"
+	"github.com/imbafff/product-warehouse-api/migrations"
 )
 
 // Интеграционные тесты с реальной БД (если БД доступна)
 func getTestDB(t *testing.T) *sql.DB {
-	cfg := config.Load()
-	database, err := db.NewPostgresDB(cfg)
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Skipf("Skipping integration test: %v", err)
+	}
+
+	database, err := db.NewPostgresDB(context.Background(), cfg)
 	if err != nil {
 		t.Skipf("Skipping integration test: database not available: %v", err)
 	}
+
+	migrator, err := db.NewMigrator(database, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
 	return database
 }
 
 func cleanupTestTable(t *testing.T, database *sql.DB) {
 	// Очищаем таблицу products для чистоты тестов
-	_, err := database.Exec("TRUNCATE TABLE products")
+	_, err := database.Exec("TRUNCATE TABLE products CASCADE")
 	if err != nil {
 		t.Logf("Warning: could not truncate products table: %v", err)
 	}
@@ -38,16 +55,16 @@ func TestIntegration_CreateAndRetrieve(t *testing.T) {
 	product := &entity.Product{
 		Name:        "Integration Test Product",
 		Description: "Testing integration with real DB",
-		Price:       99.99,
+		Price:       9999,
 		Quantity:    100,
 	}
 
-	id, err := service.Create(product)
+	id, err := service.Create(context.Background(), product)
 	if err != nil {
 		t.Fatalf("Failed to create product: %v", err)
 	}
 
-	retrieved, err := service.GetByID(id)
+	retrieved, err := service.GetByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve product: %v", err)
 	}
@@ -56,8 +73,8 @@ func TestIntegration_CreateAndRetrieve(t *testing.T) {
 		t.Errorf("Expected name 'Integration Test Product', got %s", retrieved.Name)
 	}
 
-	if retrieved.Price != 99.99 {
-		t.Errorf("Expected price 99.99, got %f", retrieved.Price)
+	if retrieved.Price != 9999 {
+		t.Errorf("Expected price 99.99, got %s", retrieved.Price)
 	}
 
 	if retrieved.Quantity != 100 {
@@ -73,27 +90,27 @@ func TestIntegration_UpdateProduct(t *testing.T) {
 
 	product := &entity.Product{
 		Name:     "Original Name",
-		Price:    50.0,
+		Price:    5000,
 		Quantity: 10,
 	}
 
-	id, err := service.Create(product)
+	id, err := service.Create(context.Background(), product)
 	if err != nil {
 		t.Fatalf("Failed to create product: %v", err)
 	}
 
 	updated := &entity.Product{
 		Name:     "Updated Name",
-		Price:    75.0,
+		Price:    7500,
 		Quantity: 20,
 	}
 
-	err = service.Update(id, updated)
+	err = service.Update(context.Background(), id, updated)
 	if err != nil {
 		t.Fatalf("Failed to update product: %v", err)
 	}
 
-	retrieved, err := service.GetByID(id)
+	retrieved, err := service.GetByID(context.Background(), id)
 	if err != nil {
 		t.Fatalf("Failed to retrieve product: %v", err)
 	}
@@ -102,8 +119,8 @@ func TestIntegration_UpdateProduct(t *testing.T) {
 		t.Errorf("Expected name 'Updated Name', got %s", retrieved.Name)
 	}
 
-	if retrieved.Price != 75.0 {
-		t.Errorf("Expected price 75.0, got %f", retrieved.Price)
+	if retrieved.Price != 7500 {
+		t.Errorf("Expected price 75.0, got %s", retrieved.Price)
 	}
 }
 
@@ -115,21 +132,21 @@ func TestIntegration_DeleteProduct(t *testing.T) {
 
 	product := &entity.Product{
 		Name:     "To Delete",
-		Price:    25.0,
+		Price:    2500,
 		Quantity: 5,
 	}
 
-	id, err := service.Create(product)
+	id, err := service.Create(context.Background(), product)
 	if err != nil {
 		t.Fatalf("Failed to create product: %v", err)
 	}
 
-	err = service.Delete(id)
+	err = service.Delete(context.Background(), id, 0)
 	if err != nil {
 		t.Fatalf("Failed to delete product: %v", err)
 	}
 
-	_, err = service.GetByID(id)
+	_, err = service.GetByID(context.Background(), id)
 	if err == nil {
 		t.Error("Expected error when retrieving deleted product, got nil")
 	}
@@ -145,21 +162,137 @@ func TestIntegration_GetAll(t *testing.T) {
 	for i := 1; i <= 5; i++ {
 		product := &entity.Product{
 			Name:     fmt.Sprintf("Product %d", i),
-			Price:    float64(i*10) + 0.99,
+			Price:    entity.Money(i*1000 + 99),
 			Quantity: i * 10,
 		}
-		_, err := service.Create(product)
+		_, err := service.Create(context.Background(), product)
 		if err != nil {
 			t.Fatalf("Failed to create product %d: %v", i, err)
 		}
 	}
 
-	products, err := service.GetAll()
+	page, err := service.GetAll(context.Background(), entity.ProductQuery{Limit: 2, SortField: "price", SortDesc: true, WithTotal: true})
 	if err != nil {
 		t.Fatalf("Failed to get all products: %v", err)
 	}
 
-	if len(products) != 5 {
-		t.Errorf("Expected 5 products, got %d", len(products))
+	if page.Total == nil || *page.Total != 5 {
+		t.Errorf("Expected total 5, got %v", page.Total)
+	}
+
+	var names []string
+	for page != nil {
+		for _, p := range page.Items {
+			names = append(names, p.Name)
+		}
+		if page.Next == nil {
+			break
+		}
+		page, err = service.GetAll(context.Background(), entity.ProductQuery{Limit: 2, SortField: "price", SortDesc: true, After: page.Next})
+		if err != nil {
+			t.Fatalf("Failed to get next page: %v", err)
+		}
+	}
+
+	if len(names) != 5 {
+		t.Fatalf("Expected 5 products, got %d", len(names))
+	}
+
+	if names[0] != "Product 5" || names[4] != "Product 1" {
+		t.Errorf("Expected products in descending price order, got %v", names)
+	}
+}
+
+func TestIntegration_StockMovements(t *testing.T) {
+	database := getTestDB(t)
+	repo := productRepo.NewPostgresRepository(database)
+	service := New(repo)
+	defer cleanupTestTable(t, database)
+
+	id, err := service.Create(context.Background(), &entity.Product{Name: "Ledger", Price: 1000, Quantity: 10})
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	if _, err := service.AddMovement(context.Background(), id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -4, Reason: "sale"}); err != nil {
+		t.Fatalf("Failed to add movement: %v", err)
+	}
+
+	if _, err := service.AddMovement(context.Background(), id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -7, Reason: "sale"}); !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock, got %v", err)
+	}
+
+	movements, err := service.GetMovements(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to get movements: %v", err)
+	}
+
+	if len(movements) != 2 {
+		t.Fatalf("Expected 2 movements, got %d", len(movements))
+	}
+
+	if movements[0].Reason != "initial_stock" || movements[1].Balance != 6 {
+		t.Errorf("Unexpected ledger: %+v, %+v", movements[0], movements[1])
+	}
+
+	retrieved, err := service.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to retrieve product: %v", err)
+	}
+
+	if retrieved.Quantity != 6 {
+		t.Errorf("Expected quantity 6, got %d", retrieved.Quantity)
+	}
+}
+
+func TestIntegration_ConcurrentDecrease(t *testing.T) {
+	database := getTestDB(t)
+	repo := productRepo.NewPostgresRepository(database)
+	service := New(repo)
+	defer cleanupTestTable(t, database)
+
+	id, err := service.Create(context.Background(), &entity.Product{Name: "Contended", Price: 1000, Quantity: 10})
+	if err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		succeeded    int
+		insufficient int
+	)
+
+	for i := 0; i < 15; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.DecreaseStock(context.Background(), id, 1, "", "")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, entity.ErrInsufficientStock):
+				insufficient++
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 10 || insufficient != 5 {
+		t.Errorf("Expected 10 successful and 5 rejected decreases, got %d and %d", succeeded, insufficient)
+	}
+
+	retrieved, err := service.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to retrieve product: %v", err)
+	}
+
+	if retrieved.Quantity != 0 {
+		t.Errorf("Expected quantity 0, got %d", retrieved.Quantity)
 	}
 }
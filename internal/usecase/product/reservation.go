@@ -0,0 +1,141 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errInvalidReservationID = entity.NewValidationError("id", "invalid", "invalid reservation id")
+
+// Reserve holds quantity units of the product for ttl, typically while an
+// order is pending. The units stay on hand but no longer count as available
+// until the reservation is confirmed, released or expires.
+func (s *Service) Reserve(ctx context.Context, productID int64, quantity int, ttl time.Duration, reference string) (_ *entity.Reservation, err error) {
+	ctx, span := s.startSpan(ctx, "Reserve", productID)
+	defer tracing.End(span, &err)
+
+	var v entity.Violations
+	if productID <= 0 {
+		v.Add("id", "invalid", "invalid id")
+	}
+	validateReservation(&v, quantity, ttl)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	res := &entity.Reservation{
+		Quantity:  quantity,
+		Reference: reference,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	if err := s.repo.Reserve(ctx, productID, res); err != nil {
+		if errors.Is(err, entity.ErrInsufficientStock) {
+			s.logger.WarnContext(ctx, "reservation rejected", "product_id", productID, "quantity", quantity, "error", err)
+			s.recorder.StockRejected()
+		}
+		return nil, err
+	}
+
+	span.SetAttributes(tracing.ReservationIDKey.Int64(res.ID))
+	s.logger.InfoContext(ctx, "stock reserved",
+		"product_id", productID, "reservation_id", res.ID, "quantity", quantity, "expires_at", res.ExpiresAt)
+	return res, nil
+}
+
+func (s *Service) GetReservation(ctx context.Context, id int64) (_ *entity.Reservation, err error) {
+	ctx, span := s.startReservationSpan(ctx, "GetReservation", id)
+	defer tracing.End(span, &err)
+
+	if id <= 0 {
+		return nil, errInvalidReservationID
+	}
+
+	return s.repo.GetReservation(ctx, id)
+}
+
+// ConfirmReservation turns the reservation into an issue of its units. An
+// expired reservation cannot be confirmed, even before the sweeper got to it.
+func (s *Service) ConfirmReservation(ctx context.Context, id int64) (_ *entity.Reservation, err error) {
+	ctx, span := s.startReservationSpan(ctx, "ConfirmReservation", id)
+	defer tracing.End(span, &err)
+
+	if id <= 0 {
+		return nil, errInvalidReservationID
+	}
+
+	m := &entity.StockMovement{Type: entity.MovementIssue, Reason: "reservation_confirmed"}
+
+	res, err := s.repo.ConfirmReservation(ctx, id, s.now(), m)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logMovement(ctx, res.ProductID, m)
+	return res, nil
+}
+
+func (s *Service) ReleaseReservation(ctx context.Context, id int64) (_ *entity.Reservation, err error) {
+	ctx, span := s.startReservationSpan(ctx, "ReleaseReservation", id)
+	defer tracing.End(span, &err)
+
+	if id <= 0 {
+		return nil, errInvalidReservationID
+	}
+
+	res, err := s.repo.ReleaseReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "reservation released",
+		"product_id", res.ProductID, "reservation_id", id, "quantity", res.Quantity)
+	return res, nil
+}
+
+// ExpireReservations releases the stock of every reservation past its
+// expiry and returns how many there were.
+func (s *Service) ExpireReservations(ctx context.Context) (expired int, err error) {
+	ctx, span := s.tracer.Start(ctx, "product.Service.ExpireReservations")
+	defer tracing.End(span, &err)
+
+	expired, err = s.repo.ExpireReservations(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	if expired > 0 {
+		s.logger.InfoContext(ctx, "reservations expired", "count", expired)
+	}
+	return expired, nil
+}
+
+// SweepReservations calls ExpireReservations every interval until ctx is
+// done. Failures are logged and retried on the next tick.
+func (s *Service) SweepReservations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ExpireReservations(ctx); err != nil && ctx.Err() == nil {
+				s.logger.ErrorContext(ctx, "failed to expire reservations", "error", err)
+			}
+		}
+	}
+}
+
+func (s *Service) startReservationSpan(ctx context.Context, method string, id int64) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "product.Service."+method, trace.WithAttributes(tracing.ReservationIDKey.Int64(id)))
+}
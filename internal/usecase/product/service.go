@@ -1,66 +1,368 @@
 package product
 
 import (
+	"context"
 	"errors"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/logger"
+	"github.com/imbafff/product-warehouse-api/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const tracerName = "github.com/imbafff/product-warehouse-api/internal/usecase/product"
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 1000
+
+	DefaultMaxBatchSize = 1000
+)
+
+var sortFields = map[string]bool{
+	"id":       true,
+	"name":     true,
+	"price":    true,
+	"quantity": true,
+}
+
 type Service struct {
-	repo Repository
+	repo     Repository
+	logger   *slog.Logger
+	recorder Recorder
+	tracer   trace.Tracer
+	now      func() time.Time
+
+	maxBatchSize int
 }
 
-func New(repo Repository) *Service {
-	return &Service{repo: repo}
+// Recorder counts domain events, see metrics.Metrics.
+type Recorder interface {
+	ProductCreated()
+	StockMoved(movementType string)
+	StockRejected()
 }
 
-func (s *Service) Create(p *entity.Product) (int64, error) {
-	if p.Name == "" {
-		return 0, errors.New("name is required")
+type nopRecorder struct{}
+
+func (nopRecorder) ProductCreated()   {}
+func (nopRecorder) StockMoved(string) {}
+func (nopRecorder) StockRejected()    {}
+
+type Option func(*Service)
+
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Service) {
+		s.logger = l
 	}
-	if p.Price <= 0 {
-		return 0, errors.New("price must be greater than zero")
+}
+
+func WithRecorder(r Recorder) Option {
+	return func(s *Service) {
+		s.recorder = r
 	}
-	if p.Quantity < 0 {
-		return 0, errors.New("quantity must be non-negative")
+}
+
+// WithTracerProvider replaces the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Service) {
+		s.tracer = tp.Tracer(tracerName)
 	}
+}
 
-	return s.repo.Create(p)
+// WithClock replaces time.Now, which decides when reservations expire.
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
 }
 
-func (s *Service) GetByID(id int64) (*entity.Product, error) {
+// WithMaxBatchSize limits how many operations one Batch call takes.
+func WithMaxBatchSize(n int) Option {
+	return func(s *Service) {
+		s.maxBatchSize = n
+	}
+}
+
+func New(repo Repository, opts ...Option) *Service {
+	s := &Service{
+		repo:         repo,
+		logger:       logger.Discard(),
+		recorder:     nopRecorder{},
+		tracer:       otel.Tracer(tracerName),
+		now:          time.Now,
+		maxBatchSize: DefaultMaxBatchSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Service) Create(ctx context.Context, p *entity.Product) (id int64, err error) {
+	ctx, span := s.tracer.Start(ctx, "product.Service.Create")
+	defer tracing.End(span, &err)
+
+	normalizeProduct(p)
+
+	var v entity.Violations
+	validateProduct(&v, p)
+	if err := v.Err(); err != nil {
+		return 0, err
+	}
+
+	id, err = s.repo.Create(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	span.SetAttributes(tracing.ProductIDKey.Int64(id))
+
+	s.logger.InfoContext(ctx, "product created", "product_id", id, "quantity", p.Quantity)
+	s.recorder.ProductCreated()
+	return id, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id int64) (_ *entity.Product, err error) {
+	ctx, span := s.startSpan(ctx, "GetByID", id)
+	defer tracing.End(span, &err)
+
 	if id <= 0 {
-		return nil, errors.New("invalid id")
+		return nil, errInvalidID
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *Service) GetBySKU(ctx context.Context, sku string) (_ *entity.Product, err error) {
+	ctx, span := s.tracer.Start(ctx, "product.Service.GetBySKU")
+	defer tracing.End(span, &err)
+
+	if !skuCode.MatchString(sku) {
+		return nil, errInvalidSKU
+	}
+
+	return s.repo.GetBySKU(ctx, sku)
+}
+
+// GetByBarcode finds the product with the barcode code. UPC-A, EAN-13 and
+// GTIN-14 forms of the same article all find it.
+func (s *Service) GetByBarcode(ctx context.Context, code string) (_ *entity.Product, err error) {
+	ctx, span := s.tracer.Start(ctx, "product.Service.GetByBarcode")
+	defer tracing.End(span, &err)
+
+	if !entity.ValidBarcode(code) {
+		return nil, errInvalidBarcode
 	}
 
-	return s.repo.GetByID(id)
+	return s.repo.GetByBarcode(ctx, code)
 }
 
-func (s *Service) Update(id int64, p *entity.Product) error {
+func (s *Service) Update(ctx context.Context, id int64, p *entity.Product) (err error) {
+	ctx, span := s.startSpan(ctx, "Update", id)
+	defer tracing.End(span, &err)
+
+	normalizeProduct(p)
+
+	var v entity.Violations
 	if id <= 0 {
-		return errors.New("invalid id")
+		v.Add("id", "invalid", "invalid id")
 	}
-	if p.Name == "" {
-		return errors.New("name is required")
+	validateProduct(&v, p)
+	if p.Version < 0 {
+		v.Add("version", "invalid", "invalid version")
 	}
-	if p.Price <= 0 {
-		return errors.New("price must be greater than zero")
+	if err := v.Err(); err != nil {
+		return err
 	}
-	if p.Quantity < 0 {
-		return errors.New("quantity must be non-negative")
+
+	if err := s.repo.Update(ctx, id, p); err != nil {
+		return err
 	}
 
-	return s.repo.Update(id, p)
+	s.logger.InfoContext(ctx, "product updated", "product_id", id, "version", p.Version)
+	return nil
 }
 
-func (s *Service) Delete(id int64) error {
+func (s *Service) Delete(ctx context.Context, id int64, version int64) (err error) {
+	ctx, span := s.startSpan(ctx, "Delete", id)
+	defer tracing.End(span, &err)
+
 	if id <= 0 {
-		return errors.New("invalid id")
+		return errInvalidID
+	}
+	if version < 0 {
+		return errInvalidVersion
+	}
+
+	if err := s.repo.Delete(ctx, id, version); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "product deleted", "product_id", id)
+	return nil
+}
+
+func (s *Service) GetAll(ctx context.Context, q entity.ProductQuery) (_ *entity.ProductPage, err error) {
+	ctx, span := s.tracer.Start(ctx, "product.Service.GetAll")
+	defer tracing.End(span, &err)
+
+	if q.SortField == "" {
+		q.SortField = "id"
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+
+	var v entity.Violations
+	validateQuery(&v, q)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAll(ctx, q)
+}
+
+func (s *Service) AddMovement(ctx context.Context, productID int64, m *entity.StockMovement) (_ int64, err error) {
+	ctx, span := s.startSpan(ctx, "AddMovement", productID)
+	defer tracing.End(span, &err)
+
+	var v entity.Violations
+	if productID <= 0 {
+		v.Add("id", "invalid", "invalid id")
+	}
+	validateMovement(&v, m)
+	if err := v.Err(); err != nil {
+		return 0, err
 	}
 
-	return s.repo.Delete(id)
+	id, err := s.repo.AddMovement(ctx, productID, m)
+	if err != nil {
+		s.logRejected(ctx, productID, m, err)
+		return 0, err
+	}
+
+	s.logMovement(ctx, productID, m)
+	return id, nil
 }
 
-func (s *Service) GetAll() ([]*entity.Product, error) {
-	return s.repo.GetAll()
+func (s *Service) IncreaseStock(ctx context.Context, productID int64, quantity int, reason, reference string) (_ *entity.StockMovement, err error) {
+	ctx, span := s.startSpan(ctx, "IncreaseStock", productID)
+	defer tracing.End(span, &err)
+
+	if reason == "" {
+		reason = "stock_increase"
+	}
+	return s.changeStock(ctx, productID, entity.MovementReceipt, quantity, reason, reference)
+}
+
+func (s *Service) DecreaseStock(ctx context.Context, productID int64, quantity int, reason, reference string) (_ *entity.StockMovement, err error) {
+	ctx, span := s.startSpan(ctx, "DecreaseStock", productID)
+	defer tracing.End(span, &err)
+
+	if reason == "" {
+		reason = "stock_decrease"
+	}
+	return s.changeStock(ctx, productID, entity.MovementIssue, -quantity, reason, reference)
+}
+
+func (s *Service) changeStock(ctx context.Context, productID int64, t entity.MovementType, delta int, reason, reference string) (*entity.StockMovement, error) {
+	var v entity.Violations
+	if productID <= 0 {
+		v.Add("id", "invalid", "invalid id")
+	}
+	if delta == 0 || (t == entity.MovementReceipt) != (delta > 0) {
+		v.Add("quantity", "must_be_positive", "quantity must be greater than zero")
+	}
+	if !reasonCode.MatchString(reason) {
+		v.Add("reason", "invalid_format", "reason must be a lowercase code")
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	m := &entity.StockMovement{
+		Type:      t,
+		Delta:     delta,
+		Reason:    reason,
+		Reference: reference,
+	}
+
+	if err := s.repo.ChangeQuantity(ctx, productID, m); err != nil {
+		s.logRejected(ctx, productID, m, err)
+		return nil, err
+	}
+
+	s.logMovement(ctx, productID, m)
+	return m, nil
+}
+
+// normalizeProduct fills in defaults and trims the codes of p before it is
+// validated.
+func normalizeProduct(p *entity.Product) {
+	if p.Currency == "" {
+		p.Currency = entity.DefaultCurrency
+	}
+
+	p.SKU = strings.TrimSpace(p.SKU)
+	for i, code := range p.Barcodes {
+		p.Barcodes[i] = strings.TrimSpace(code)
+	}
+}
+
+// startSpan starts the span of a method that works on one product.
+func (s *Service) startSpan(ctx context.Context, method string, productID int64) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "product.Service."+method, trace.WithAttributes(tracing.ProductIDKey.Int64(productID)))
+}
+
+func (s *Service) logMovement(ctx context.Context, productID int64, m *entity.StockMovement) {
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("movement.type", string(m.Type)),
+		attribute.Int("movement.delta", m.Delta),
+		attribute.Int("movement.balance", m.Balance),
+	)
+	s.logger.InfoContext(ctx, "stock changed",
+		"product_id", productID, "type", m.Type, "delta", m.Delta, "balance", m.Balance, "reason", m.Reason)
+	s.recorder.StockMoved(string(m.Type))
+}
+
+// logRejected records stock changes refused for lack of stock, which is what
+// gets asked about when counts disagree. Other failures are logged where
+// they happen.
+func (s *Service) logRejected(ctx context.Context, productID int64, m *entity.StockMovement, err error) {
+	if errors.Is(err, entity.ErrInsufficientStock) {
+		s.logger.WarnContext(ctx, "stock change rejected",
+			"product_id", productID, "type", m.Type, "delta", m.Delta, "reason", m.Reason, "error", err)
+		s.recorder.StockRejected()
+	}
+}
+
+func (s *Service) GetMovements(ctx context.Context, productID int64) (_ []*entity.StockMovement, err error) {
+	ctx, span := s.startSpan(ctx, "GetMovements", productID)
+	defer tracing.End(span, &err)
+
+	if productID <= 0 {
+		return nil, errInvalidID
+	}
+
+	return s.repo.GetMovements(ctx, productID)
+}
+
+func (s *Service) GetStock(ctx context.Context, productID int64, warehouseID *int64) (_ *entity.StockBreakdown, err error) {
+	ctx, span := s.startSpan(ctx, "GetStock", productID)
+	defer tracing.End(span, &err)
+
+	if productID <= 0 {
+		return nil, errInvalidID
+	}
+	if warehouseID != nil && *warehouseID <= 0 {
+		return nil, entity.NewValidationError("warehouse_id", "invalid", "invalid warehouse id")
+	}
+
+	return s.repo.GetStock(ctx, productID, warehouseID)
 }
@@ -1,64 +1,266 @@
 package product
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/imbafff/product-warehouse-api/internal/entity"
+	"github.com/imbafff/product-warehouse-api/internal/repository/product/memory"
 )
 
 // Mock Repository для тестирования
 type MockRepository struct {
-	products map[int64]*entity.Product
-	nextID   int64
+	products  map[int64]*entity.Product
+	nextID    int64
+	lastQuery entity.ProductQuery
+	movements []*entity.StockMovement
+
+	reservations map[int64]*entity.Reservation
 }
 
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
-		products: make(map[int64]*entity.Product),
-		nextID:   1,
+		products:     make(map[int64]*entity.Product),
+		nextID:       1,
+		reservations: make(map[int64]*entity.Reservation),
 	}
 }
 
-func (m *MockRepository) Create(p *entity.Product) (int64, error) {
+func (m *MockRepository) Create(ctx context.Context, p *entity.Product) (int64, error) {
 	id := m.nextID
 	p.ID = id
+	p.Version = 1
 	m.products[id] = p
 	m.nextID++
 	return id, nil
 }
 
-func (m *MockRepository) GetByID(id int64) (*entity.Product, error) {
+func (m *MockRepository) GetByID(ctx context.Context, id int64) (*entity.Product, error) {
 	if p, exists := m.products[id]; exists {
 		return p, nil
 	}
-	return nil, errors.New("product not found")
+	return nil, entity.ErrProductNotFound
+}
+
+func (m *MockRepository) GetBySKU(ctx context.Context, sku string) (*entity.Product, error) {
+	for _, p := range m.products {
+		if strings.EqualFold(p.SKU, sku) {
+			return p, nil
+		}
+	}
+	return nil, entity.ErrProductNotFound
+}
+
+func (m *MockRepository) GetByBarcode(ctx context.Context, code string) (*entity.Product, error) {
+	for _, p := range m.products {
+		for _, c := range p.Barcodes {
+			if entity.GTIN14(c) == entity.GTIN14(code) {
+				return p, nil
+			}
+		}
+	}
+	return nil, entity.ErrProductNotFound
 }
 
-func (m *MockRepository) Update(id int64, p *entity.Product) error {
-	if _, exists := m.products[id]; !exists {
-		return errors.New("product not found")
+func (m *MockRepository) Update(ctx context.Context, id int64, p *entity.Product) error {
+	current, exists := m.products[id]
+	if !exists {
+		return entity.ErrProductNotFound
+	}
+	if p.Version != 0 && p.Version != current.Version {
+		return entity.ErrVersionConflict
 	}
 	p.ID = id
+	p.Version = current.Version + 1
 	m.products[id] = p
 	return nil
 }
 
-func (m *MockRepository) Delete(id int64) error {
-	if _, exists := m.products[id]; !exists {
-		return errors.New("product not found")
+func (m *MockRepository) Delete(ctx context.Context, id int64, version int64) error {
+	current, exists := m.products[id]
+	if !exists {
+		return entity.ErrProductNotFound
+	}
+	if version != 0 && version != current.Version {
+		return entity.ErrVersionConflict
 	}
 	delete(m.products, id)
 	return nil
 }
 
-func (m *MockRepository) GetAll() ([]*entity.Product, error) {
+func (m *MockRepository) Batch(ctx context.Context, ops []*entity.BatchOperation, atomic bool) error {
+	for _, op := range ops {
+		switch op.Action {
+		case entity.BatchCreate:
+			_, op.Err = m.Create(ctx, op.Product)
+		case entity.BatchUpdate:
+			op.Product.Version = op.Version
+			op.Err = m.Update(ctx, op.ID, op.Product)
+		case entity.BatchDelete:
+			op.Err = m.Delete(ctx, op.ID, op.Version)
+		}
+		if op.Err != nil && atomic {
+			return op.Err
+		}
+	}
+	return nil
+}
+
+func (m *MockRepository) Import(ctx context.Context, key entity.ImportKey, keys []string, build func([]*entity.Product) []*entity.BatchOperation) error {
+	var found []*entity.Product
+	for _, k := range keys {
+		var p *entity.Product
+		var err error
+		if key == entity.ImportBySKU {
+			p, err = m.GetBySKU(ctx, k)
+		} else {
+			id, _ := strconv.ParseInt(k, 10, 64)
+			p, err = m.GetByID(ctx, id)
+		}
+		if err == nil {
+			found = append(found, p)
+		}
+	}
+	return m.Batch(ctx, build(found), false)
+}
+
+func (m *MockRepository) GetAll(ctx context.Context, q entity.ProductQuery) (*entity.ProductPage, error) {
+	m.lastQuery = q
+
 	products := make([]*entity.Product, 0, len(m.products))
 	for _, p := range m.products {
 		products = append(products, p)
 	}
-	return products, nil
+	return &entity.ProductPage{Items: products}, nil
+}
+
+func (m *MockRepository) AddMovement(ctx context.Context, productID int64, mv *entity.StockMovement) (int64, error) {
+	p, exists := m.products[productID]
+	if !exists {
+		return 0, entity.ErrProductNotFound
+	}
+	if p.Quantity+mv.Delta < 0 {
+		return 0, entity.ErrInsufficientStock
+	}
+	p.Quantity += mv.Delta
+	mv.ID = int64(len(m.movements) + 1)
+	mv.ProductID = productID
+	mv.Balance = p.Quantity
+	m.movements = append(m.movements, mv)
+	return mv.ID, nil
+}
+
+func (m *MockRepository) ChangeQuantity(ctx context.Context, productID int64, mv *entity.StockMovement) error {
+	_, err := m.AddMovement(ctx, productID, mv)
+	return err
+}
+
+func (m *MockRepository) GetMovements(ctx context.Context, productID int64) ([]*entity.StockMovement, error) {
+	if _, exists := m.products[productID]; !exists {
+		return nil, entity.ErrProductNotFound
+	}
+	movements := []*entity.StockMovement{}
+	for _, mv := range m.movements {
+		if mv.ProductID == productID {
+			movements = append(movements, mv)
+		}
+	}
+	return movements, nil
+}
+
+func (m *MockRepository) GetStock(ctx context.Context, productID int64, warehouseID *int64) (*entity.StockBreakdown, error) {
+	p, exists := m.products[productID]
+	if !exists {
+		return nil, entity.ErrProductNotFound
+	}
+	return &entity.StockBreakdown{ProductID: productID, Quantity: p.Quantity, Unallocated: p.Quantity}, nil
+}
+
+func (m *MockRepository) Reserve(ctx context.Context, productID int64, r *entity.Reservation) error {
+	p, exists := m.products[productID]
+	if !exists {
+		return entity.ErrProductNotFound
+	}
+	reserved := 0
+	for _, res := range m.reservations {
+		if res.ProductID == productID && res.Status == entity.ReservationActive {
+			reserved += res.Quantity
+		}
+	}
+	if p.Quantity-reserved < r.Quantity {
+		return entity.ErrInsufficientStock
+	}
+	r.ID = int64(len(m.reservations) + 1)
+	r.ProductID = productID
+	r.Status = entity.ReservationActive
+	m.reservations[r.ID] = r
+	return nil
+}
+
+func (m *MockRepository) GetReservation(ctx context.Context, id int64) (*entity.Reservation, error) {
+	if r, exists := m.reservations[id]; exists {
+		return r, nil
+	}
+	return nil, entity.ErrReservationNotFound
+}
+
+func (m *MockRepository) ConfirmReservation(ctx context.Context, id int64, now time.Time, mv *entity.StockMovement) (*entity.Reservation, error) {
+	r, err := m.activeReservation(id)
+	if err != nil {
+		return nil, err
+	}
+	if !now.Before(r.ExpiresAt) {
+		return nil, entity.ErrReservationExpired
+	}
+	p := m.products[r.ProductID]
+	p.Quantity -= r.Quantity
+	r.Status = entity.ReservationConfirmed
+	mv.ProductID = r.ProductID
+	mv.Delta = -r.Quantity
+	mv.Balance = p.Quantity
+	mv.Reference = r.Reference
+	m.movements = append(m.movements, mv)
+	return r, nil
+}
+
+func (m *MockRepository) ReleaseReservation(ctx context.Context, id int64) (*entity.Reservation, error) {
+	r, err := m.activeReservation(id)
+	if err != nil {
+		return nil, err
+	}
+	r.Status = entity.ReservationReleased
+	return r, nil
+}
+
+func (m *MockRepository) ExpireReservations(ctx context.Context, now time.Time) (int, error) {
+	expired := 0
+	for _, r := range m.reservations {
+		if r.Status == entity.ReservationActive && !now.Before(r.ExpiresAt) {
+			r.Status = entity.ReservationExpired
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (m *MockRepository) activeReservation(id int64) (*entity.Reservation, error) {
+	r, exists := m.reservations[id]
+	if !exists {
+		return nil, entity.ErrReservationNotFound
+	}
+	if r.Status != entity.ReservationActive {
+		return nil, entity.ErrReservationClosed
+	}
+	return r, nil
 }
 
 // Тесты для Create
@@ -69,11 +271,11 @@ func TestCreate_Success(t *testing.T) {
 	product := &entity.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       10.99,
+		Price:       1099,
 		Quantity:    5,
 	}
 
-	id, err := service.Create(product)
+	id, err := service.Create(context.Background(), product)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -83,7 +285,7 @@ func TestCreate_Success(t *testing.T) {
 	}
 
 	// Проверяем, что продукт был создан
-	created, err := service.GetByID(id)
+	created, err := service.GetByID(context.Background(), id)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -99,11 +301,11 @@ func TestCreate_EmptyName(t *testing.T) {
 
 	product := &entity.Product{
 		Name:     "",
-		Price:    10.99,
+		Price:    1099,
 		Quantity: 5,
 	}
 
-	_, err := service.Create(product)
+	_, err := service.Create(context.Background(), product)
 	if err == nil {
 		t.Error("Expected error for empty name, got nil")
 	}
@@ -111,13 +313,17 @@ func TestCreate_EmptyName(t *testing.T) {
 	if err.Error() != "name is required" {
 		t.Errorf("Expected 'name is required', got %v", err)
 	}
+
+	if !errors.Is(err, entity.ErrValidation) {
+		t.Errorf("Expected a validation error, got %T", err)
+	}
 }
 
 func TestCreate_InvalidPrice(t *testing.T) {
 	repo := NewMockRepository()
 	service := New(repo)
 
-	testCases := []float64{0, -10.5, -0.01}
+	testCases := []entity.Money{0, -1050, -1}
 
 	for _, price := range testCases {
 		product := &entity.Product{
@@ -126,9 +332,9 @@ func TestCreate_InvalidPrice(t *testing.T) {
 			Quantity: 5,
 		}
 
-		_, err := service.Create(product)
+		_, err := service.Create(context.Background(), product)
 		if err == nil {
-			t.Errorf("Expected error for price %.2f, got nil", price)
+			t.Errorf("Expected error for price %s, got nil", price)
 		}
 
 		if err.Error() != "price must be greater than zero" {
@@ -143,11 +349,11 @@ func TestCreate_NegativeQuantity(t *testing.T) {
 
 	product := &entity.Product{
 		Name:     "Test",
-		Price:    10.99,
+		Price:    1099,
 		Quantity: -5,
 	}
 
-	_, err := service.Create(product)
+	_, err := service.Create(context.Background(), product)
 	if err == nil {
 		t.Error("Expected error for negative quantity, got nil")
 	}
@@ -165,13 +371,13 @@ func TestGetByID_Success(t *testing.T) {
 	product := &entity.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       10.99,
+		Price:       1099,
 		Quantity:    5,
 	}
 
-	id, _ := service.Create(product)
+	id, _ := service.Create(context.Background(), product)
 
-	retrieved, err := service.GetByID(id)
+	retrieved, err := service.GetByID(context.Background(), id)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -180,8 +386,8 @@ func TestGetByID_Success(t *testing.T) {
 		t.Errorf("Expected name 'Test Product', got %s", retrieved.Name)
 	}
 
-	if retrieved.Price != 10.99 {
-		t.Errorf("Expected price 10.99, got %f", retrieved.Price)
+	if retrieved.Price != 1099 {
+		t.Errorf("Expected price 10.99, got %s", retrieved.Price)
 	}
 }
 
@@ -189,13 +395,13 @@ func TestGetByID_NotFound(t *testing.T) {
 	repo := NewMockRepository()
 	service := New(repo)
 
-	_, err := service.GetByID(999)
+	_, err := service.GetByID(context.Background(), 999)
 	if err == nil {
 		t.Error("Expected error for non-existent product, got nil")
 	}
 
-	if err.Error() != "product not found" {
-		t.Errorf("Expected 'product not found', got %v", err)
+	if !errors.Is(err, entity.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
 	}
 }
 
@@ -206,7 +412,7 @@ func TestGetByID_InvalidID(t *testing.T) {
 	testCases := []int64{0, -1, -999}
 
 	for _, id := range testCases {
-		_, err := service.GetByID(id)
+		_, err := service.GetByID(context.Background(), id)
 		if err == nil {
 			t.Errorf("Expected error for id %d, got nil", id)
 		}
@@ -225,31 +431,31 @@ func TestUpdate_Success(t *testing.T) {
 	product := &entity.Product{
 		Name:        "Original",
 		Description: "Original Description",
-		Price:       10.99,
+		Price:       1099,
 		Quantity:    5,
 	}
 
-	id, _ := service.Create(product)
+	id, _ := service.Create(context.Background(), product)
 
 	updatedProduct := &entity.Product{
 		Name:        "Updated",
 		Description: "Updated Description",
-		Price:       20.99,
+		Price:       2099,
 		Quantity:    10,
 	}
 
-	err := service.Update(id, updatedProduct)
+	err := service.Update(context.Background(), id, updatedProduct)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
-	retrieved, _ := service.GetByID(id)
+	retrieved, _ := service.GetByID(context.Background(), id)
 	if retrieved.Name != "Updated" {
 		t.Errorf("Expected name 'Updated', got %s", retrieved.Name)
 	}
 
-	if retrieved.Price != 20.99 {
-		t.Errorf("Expected price 20.99, got %f", retrieved.Price)
+	if retrieved.Price != 2099 {
+		t.Errorf("Expected price 20.99, got %s", retrieved.Price)
 	}
 }
 
@@ -259,11 +465,11 @@ func TestUpdate_NotFound(t *testing.T) {
 
 	updatedProduct := &entity.Product{
 		Name:     "Updated",
-		Price:    20.99,
+		Price:    2099,
 		Quantity: 10,
 	}
 
-	err := service.Update(999, updatedProduct)
+	err := service.Update(context.Background(), 999, updatedProduct)
 	if err == nil {
 		t.Error("Expected error for non-existent product, got nil")
 	}
@@ -279,11 +485,11 @@ func TestUpdate_InvalidData(t *testing.T) {
 
 	product := &entity.Product{
 		Name:     "Test",
-		Price:    10.99,
+		Price:    1099,
 		Quantity: 5,
 	}
 
-	id, _ := service.Create(product)
+	id, _ := service.Create(context.Background(), product)
 
 	testCases := []struct {
 		name    string
@@ -294,7 +500,7 @@ func TestUpdate_InvalidData(t *testing.T) {
 			name: "empty name",
 			product: &entity.Product{
 				Name:     "",
-				Price:    10.99,
+				Price:    1099,
 				Quantity: 5,
 			},
 			errMsg: "name is required",
@@ -303,7 +509,7 @@ func TestUpdate_InvalidData(t *testing.T) {
 			name: "invalid price",
 			product: &entity.Product{
 				Name:     "Test",
-				Price:    -5.0,
+				Price:    -500,
 				Quantity: 5,
 			},
 			errMsg: "price must be greater than zero",
@@ -312,7 +518,7 @@ func TestUpdate_InvalidData(t *testing.T) {
 			name: "negative quantity",
 			product: &entity.Product{
 				Name:     "Test",
-				Price:    10.99,
+				Price:    1099,
 				Quantity: -5,
 			},
 			errMsg: "quantity must be non-negative",
@@ -321,7 +527,7 @@ func TestUpdate_InvalidData(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := service.Update(id, tc.product)
+			err := service.Update(context.Background(), id, tc.product)
 			if err == nil {
 				t.Error("Expected error, got nil")
 			}
@@ -340,18 +546,18 @@ func TestDelete_Success(t *testing.T) {
 
 	product := &entity.Product{
 		Name:     "Test",
-		Price:    10.99,
+		Price:    1099,
 		Quantity: 5,
 	}
 
-	id, _ := service.Create(product)
+	id, _ := service.Create(context.Background(), product)
 
-	err := service.Delete(id)
+	err := service.Delete(context.Background(), id, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
-	_, err = service.GetByID(id)
+	_, err = service.GetByID(context.Background(), id)
 	if err == nil {
 		t.Error("Expected error when getting deleted product, got nil")
 	}
@@ -361,7 +567,7 @@ func TestDelete_NotFound(t *testing.T) {
 	repo := NewMockRepository()
 	service := New(repo)
 
-	err := service.Delete(999)
+	err := service.Delete(context.Background(), 999, 0)
 	if err == nil {
 		t.Error("Expected error for non-existent product, got nil")
 	}
@@ -378,7 +584,7 @@ func TestDelete_InvalidID(t *testing.T) {
 	testCases := []int64{0, -1, -999}
 
 	for _, id := range testCases {
-		err := service.Delete(id)
+		err := service.Delete(context.Background(), id, 0)
 		if err == nil {
 			t.Errorf("Expected error for id %d, got nil", id)
 		}
@@ -394,13 +600,13 @@ func TestGetAll_Empty(t *testing.T) {
 	repo := NewMockRepository()
 	service := New(repo)
 
-	products, err := service.GetAll()
+	page, err := service.GetAll(context.Background(), entity.ProductQuery{})
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
-	if len(products) != 0 {
-		t.Errorf("Expected 0 products, got %d", len(products))
+	if len(page.Items) != 0 {
+		t.Errorf("Expected 0 products, got %d", len(page.Items))
 	}
 }
 
@@ -412,18 +618,822 @@ func TestGetAll_MultipleProducts(t *testing.T) {
 	for i := 1; i <= 3; i++ {
 		product := &entity.Product{
 			Name:     fmt.Sprintf("Product %d", i),
-			Price:    float64(10*i) + 0.99,
+			Price:    entity.Money(1000*i + 99),
 			Quantity: i * 5,
 		}
-		service.Create(product)
+		service.Create(context.Background(), product)
 	}
 
-	products, err := service.GetAll()
+	page, err := service.GetAll(context.Background(), entity.ProductQuery{})
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
-	if len(products) != 3 {
-		t.Errorf("Expected 3 products, got %d", len(products))
+	if len(page.Items) != 3 {
+		t.Errorf("Expected 3 products, got %d", len(page.Items))
+	}
+}
+
+func TestGetAll_Defaults(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	if _, err := service.GetAll(context.Background(), entity.ProductQuery{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if repo.lastQuery.Limit != DefaultPageSize {
+		t.Errorf("Expected limit %d, got %d", DefaultPageSize, repo.lastQuery.Limit)
+	}
+
+	if repo.lastQuery.SortField != "id" {
+		t.Errorf("Expected sort 'id', got %s", repo.lastQuery.SortField)
+	}
+}
+
+func TestGetAll_InvalidQuery(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	minPrice, maxPrice := entity.Money(2000), entity.Money(1000)
+
+	testCases := []struct {
+		name  string
+		query entity.ProductQuery
+	}{
+		{name: "unknown sort", query: entity.ProductQuery{SortField: "description"}},
+		{name: "limit too large", query: entity.ProductQuery{Limit: MaxPageSize + 1}},
+		{name: "negative offset", query: entity.ProductQuery{Offset: -1}},
+		{
+			name: "cursor with offset",
+			query: entity.ProductQuery{
+				Offset: 10,
+				After:  &entity.Cursor{Sort: "id", ID: 5},
+			},
+		},
+		{
+			name: "cursor for another sort",
+			query: entity.ProductQuery{
+				SortField: "price",
+				After:     &entity.Cursor{Sort: "name", Value: "a", ID: 5},
+			},
+		},
+		{
+			name: "inverted price range",
+			query: entity.ProductQuery{
+				Filter: entity.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := service.GetAll(context.Background(), tc.query); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+// Тесты для движений остатков
+func TestAddMovement_Success(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	movement := &entity.StockMovement{Type: entity.MovementIssue, Delta: -3, Reason: "sale", Reference: "SO-1"}
+	if _, err := service.AddMovement(context.Background(), id, movement); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if movement.Balance != 2 {
+		t.Errorf("Expected balance 2, got %d", movement.Balance)
+	}
+
+	movements, err := service.GetMovements(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(movements) != 1 || movements[0].Reference != "SO-1" {
+		t.Errorf("Expected the recorded movement, got %v", movements)
+	}
+}
+
+func TestAddMovement_InsufficientStock(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	_, err := service.AddMovement(context.Background(), id, &entity.StockMovement{Type: entity.MovementIssue, Delta: -6, Reason: "sale"})
+	if !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock, got %v", err)
+	}
+
+	p, _ := service.GetByID(context.Background(), id)
+	if p.Quantity != 5 {
+		t.Errorf("Expected quantity to stay 5, got %d", p.Quantity)
+	}
+}
+
+func TestAddMovement_InvalidData(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	testCases := []struct {
+		name     string
+		movement *entity.StockMovement
+		errMsg   string
+	}{
+		{
+			name:     "unknown type",
+			movement: &entity.StockMovement{Type: "theft", Delta: -1, Reason: "lost"},
+			errMsg:   "invalid movement type",
+		},
+		{
+			name:     "zero delta",
+			movement: &entity.StockMovement{Type: entity.MovementAdjustment, Reason: "count"},
+			errMsg:   "delta must not be zero",
+		},
+		{
+			name:     "negative receipt",
+			movement: &entity.StockMovement{Type: entity.MovementReceipt, Delta: -1, Reason: "purchase"},
+			errMsg:   "receipt delta must be positive",
+		},
+		{
+			name:     "positive issue",
+			movement: &entity.StockMovement{Type: entity.MovementIssue, Delta: 1, Reason: "sale"},
+			errMsg:   "issue delta must be negative",
+		},
+		{
+			name:     "transfer without location",
+			movement: &entity.StockMovement{Type: entity.MovementTransfer, Delta: 1, Reason: "put_away"},
+			errMsg:   "transfer requires a location",
+		},
+		{
+			name:     "free text reason",
+			movement: &entity.StockMovement{Type: entity.MovementAdjustment, Delta: 1, Reason: "Found it!"},
+			errMsg:   "reason must be a lowercase code",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.AddMovement(context.Background(), id, tc.movement)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			if err.Error() != tc.errMsg {
+				t.Errorf("Expected '%s', got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestGetStock_InvalidWarehouse(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+	warehouseID := int64(0)
+
+	_, err := service.GetStock(context.Background(), id, &warehouseID)
+	if err == nil || err.Error() != "invalid warehouse id" {
+		t.Errorf("Expected 'invalid warehouse id', got %v", err)
+	}
+}
+
+func TestIncreaseAndDecreaseStock(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	movement, err := service.IncreaseStock(context.Background(), id, 3, "", "PO-7")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if movement.Type != entity.MovementReceipt || movement.Reason != "stock_increase" || movement.Balance != 8 {
+		t.Errorf("Unexpected movement: %+v", movement)
+	}
+
+	movement, err = service.DecreaseStock(context.Background(), id, 8, "sale", "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if movement.Delta != -8 || movement.Balance != 0 {
+		t.Errorf("Unexpected movement: %+v", movement)
+	}
+
+	if _, err := service.DecreaseStock(context.Background(), id, 1, "", ""); !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock, got %v", err)
+	}
+}
+
+func TestChangeStock_InvalidQuantity(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	for _, quantity := range []int{0, -1} {
+		if _, err := service.IncreaseStock(context.Background(), id, quantity, "", ""); err == nil {
+			t.Errorf("Expected error for increase by %d, got nil", quantity)
+		}
+		if _, err := service.DecreaseStock(context.Background(), id, quantity, "", ""); err == nil {
+			t.Errorf("Expected error for decrease by %d, got nil", quantity)
+		}
+	}
+}
+
+func TestUpdate_VersionConflict(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	first := &entity.Product{Name: "First", Price: 1099, Quantity: 5, Version: 1}
+	if err := service.Update(context.Background(), id, first); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if first.Version != 2 {
+		t.Errorf("Expected version 2 after update, got %d", first.Version)
+	}
+
+	second := &entity.Product{Name: "Second", Price: 1099, Quantity: 5, Version: 1}
+	if err := service.Update(context.Background(), id, second); !errors.Is(err, entity.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	if err := service.Delete(context.Background(), id, 1); !errors.Is(err, entity.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	if err := service.Delete(context.Background(), id, 2); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+// Тесты для валидации
+func TestCreate_ReportsAllViolations(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	_, err := service.Create(context.Background(), &entity.Product{Name: "", Price: -1, Quantity: -1})
+
+	var verr *entity.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *entity.ValidationError, got %v", err)
+	}
+
+	expected := []entity.FieldError{
+		{Field: "name", Code: "required"},
+		{Field: "price", Code: "must_be_positive"},
+		{Field: "quantity", Code: "must_be_non_negative"},
+	}
+
+	if len(verr.Fields) != len(expected) {
+		t.Fatalf("Expected %d violations, got %+v", len(expected), verr.Fields)
+	}
+
+	for i, fe := range verr.Fields {
+		if fe.Field != expected[i].Field || fe.Code != expected[i].Code {
+			t.Errorf("Expected %s/%s, got %s/%s", expected[i].Field, expected[i].Code, fe.Field, fe.Code)
+		}
+	}
+}
+
+func TestCreate_FieldLimits(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	testCases := []struct {
+		name    string
+		product *entity.Product
+		field   string
+		code    string
+	}{
+		{
+			name:    "name too long",
+			product: &entity.Product{Name: strings.Repeat("я", MaxNameLength+1), Price: 100, Quantity: 1},
+			field:   "name",
+			code:    "too_long",
+		},
+		{
+			name:    "blank name",
+			product: &entity.Product{Name: "   ", Price: 100, Quantity: 1},
+			field:   "name",
+			code:    "required",
+		},
+		{
+			name:    "description too long",
+			product: &entity.Product{Name: "Test", Description: strings.Repeat("a", MaxDescriptionLength+1), Price: 100, Quantity: 1},
+			field:   "description",
+			code:    "too_long",
+		},
+		{
+			name:    "unknown currency format",
+			product: &entity.Product{Name: "Test", Price: 100, Currency: "usd", Quantity: 1},
+			field:   "currency",
+			code:    "invalid",
+		},
+		{
+			name:    "unknown currency",
+			product: &entity.Product{Name: "Test", Price: 100, Currency: "ABC", Quantity: 1},
+			field:   "currency",
+			code:    "invalid",
+		},
+		{
+			name:    "currency without cents",
+			product: &entity.Product{Name: "Test", Price: 100, Currency: "JPY", Quantity: 1},
+			field:   "currency",
+			code:    "unsupported",
+		},
+		{
+			name:    "sku with a slash",
+			product: &entity.Product{SKU: "MUG/01", Name: "Test", Price: 100, Quantity: 1},
+			field:   "sku",
+			code:    "invalid_format",
+		},
+		{
+			name:    "barcode with a wrong check digit",
+			product: &entity.Product{Name: "Test", Price: 100, Quantity: 1, Barcodes: []string{"4006381333932"}},
+			field:   "barcodes[0]",
+			code:    "invalid",
+		},
+		{
+			name:    "same barcode as UPC-A and EAN-13",
+			product: &entity.Product{Name: "Test", Price: 100, Quantity: 1, Barcodes: []string{"036000291452", "0036000291452"}},
+			field:   "barcodes[1]",
+			code:    "duplicate",
+		},
+		{
+			name:    "price overflows NUMERIC(10,2)",
+			product: &entity.Product{Name: "Test", Price: MaxPrice + 1, Quantity: 1},
+			field:   "price",
+			code:    "too_large",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.Create(context.Background(), tc.product)
+
+			var verr *entity.ValidationError
+			if !errors.As(err, &verr) || len(verr.Fields) != 1 {
+				t.Fatalf("Expected a single violation, got %v", err)
+			}
+
+			if verr.Fields[0].Field != tc.field || verr.Fields[0].Code != tc.code {
+				t.Errorf("Expected %s/%s, got %+v", tc.field, tc.code, verr.Fields[0])
+			}
+		})
+	}
+}
+
+func TestCreate_BoundaryValues(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	for _, price := range []entity.Money{1, 1999, MaxPrice} {
+		p := &entity.Product{Name: strings.Repeat("a", MaxNameLength), Price: price, Quantity: 0}
+		if _, err := service.Create(context.Background(), p); err != nil {
+			t.Errorf("Expected price %s to be valid, got %v", price, err)
+		}
+	}
+}
+
+func TestCreate_DefaultCurrency(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 1})
+	created, _ := service.GetByID(context.Background(), id)
+
+	if created.Currency != entity.DefaultCurrency {
+		t.Errorf("Expected currency %s, got %q", entity.DefaultCurrency, created.Currency)
+	}
+}
+
+// Тесты поиска по SKU и штрихкоду
+func TestGetBySKUAndBarcode(t *testing.T) {
+	repo := NewMockRepository()
+	service := New(repo)
+	ctx := context.Background()
+
+	id, err := service.Create(ctx, &entity.Product{SKU: " MUG-01 ", Name: "Mug", Price: 100, Barcodes: []string{" 4006381333931"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if p, err := service.GetBySKU(ctx, "mug-01"); err != nil || p.ID != id {
+		t.Errorf("Expected the trimmed SKU to be found, got %+v, %v", p, err)
+	}
+	if p, err := service.GetByBarcode(ctx, "4006381333931"); err != nil || p.ID != id {
+		t.Errorf("Expected the trimmed barcode to be found, got %+v, %v", p, err)
+	}
+
+	if _, err := service.GetBySKU(ctx, "MUG/01"); !errors.Is(err, entity.ErrValidation) {
+		t.Errorf("Expected validation error for a malformed SKU, got %v", err)
+	}
+	if _, err := service.GetByBarcode(ctx, "4006381333932"); !errors.Is(err, entity.ErrValidation) {
+		t.Errorf("Expected validation error for a wrong check digit, got %v", err)
+	}
+	if _, err := service.GetByBarcode(ctx, "036000291452"); !errors.Is(err, entity.ErrProductNotFound) {
+		t.Errorf("Expected not found for an unknown barcode, got %v", err)
+	}
+}
+
+// Тесты для логирования
+func TestDecreaseStock_LogsRejection(t *testing.T) {
+	var buf bytes.Buffer
+	repo := NewMockRepository()
+	service := New(repo, WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 2})
+	buf.Reset()
+
+	if _, err := service.DecreaseStock(context.Background(), id, 5, "", ""); !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Fatalf("Expected ErrInsufficientStock, got %v", err)
+	}
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected one JSON log line, got %q", buf.String())
+	}
+
+	if line["msg"] != "stock change rejected" || line["level"] != "WARN" || line["product_id"] != float64(id) {
+		t.Errorf("Unexpected log line: %v", line)
+	}
+}
+
+// Тесты для резервов
+func TestReserve_InvalidData(t *testing.T) {
+	service := New(NewMockRepository())
+	id, _ := service.Create(context.Background(), &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	tests := []struct {
+		name      string
+		productID int64
+		quantity  int
+		ttl       time.Duration
+		field     string
+	}{
+		{"invalid id", 0, 1, time.Minute, "id"},
+		{"zero quantity", id, 0, time.Minute, "quantity"},
+		{"negative quantity", id, -1, time.Minute, "quantity"},
+		{"zero ttl", id, 1, 0, "ttl"},
+		{"ttl too long", id, 1, MaxReservationTTL + time.Second, "ttl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Reserve(context.Background(), tt.productID, tt.quantity, tt.ttl, "")
+
+			var verr *entity.ValidationError
+			if !errors.As(err, &verr) || verr.Fields[0].Field != tt.field {
+				t.Errorf("Expected validation error on %s, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestReservation_Lifecycle(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rec := &countingRecorder{moved: map[string]int{}}
+	service := New(NewMockRepository(), WithRecorder(rec), WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	id, _ := service.Create(ctx, &entity.Product{Name: "Test", Price: 1099, Quantity: 5})
+
+	first, err := service.Reserve(ctx, id, 3, time.Minute, "SO-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first.Status != entity.ReservationActive || !first.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("Unexpected reservation: %+v", first)
+	}
+
+	if _, err := service.Reserve(ctx, id, 3, time.Minute, "SO-2"); !errors.Is(err, entity.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock, got %v", err)
+	}
+
+	confirmed, err := service.ConfirmReservation(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if confirmed.Status != entity.ReservationConfirmed || rec.moved["issue"] != 1 {
+		t.Errorf("Expected a confirmed reservation and one issue, got %+v, %v", confirmed, rec.moved)
+	}
+
+	if _, err := service.ReleaseReservation(ctx, first.ID); !errors.Is(err, entity.ErrReservationClosed) {
+		t.Errorf("Expected ErrReservationClosed, got %v", err)
+	}
+
+	second, _ := service.Reserve(ctx, id, 2, time.Minute, "SO-3")
+	now = now.Add(time.Minute)
+
+	if _, err := service.ConfirmReservation(ctx, second.ID); !errors.Is(err, entity.ErrReservationExpired) {
+		t.Errorf("Expected ErrReservationExpired, got %v", err)
+	}
+
+	expired, err := service.ExpireReservations(ctx)
+	if err != nil || expired != 1 {
+		t.Errorf("Expected 1 expired reservation, got %d, %v", expired, err)
+	}
+
+	if _, err := service.GetReservation(ctx, 0); err == nil {
+		t.Error("Expected error for invalid id, got nil")
+	}
+}
+
+// Тесты для пакетных операций
+func TestBatch_InvalidData(t *testing.T) {
+	valid := func() *entity.BatchOperation {
+		return &entity.BatchOperation{Action: entity.BatchCreate, Product: &entity.Product{Name: "Test", Price: 1099}}
+	}
+
+	tests := []struct {
+		name  string
+		ops   []*entity.BatchOperation
+		field string
+	}{
+		{"empty", nil, "operations"},
+		{"too many", []*entity.BatchOperation{valid(), valid(), valid()}, "operations"},
+		{"unknown op", []*entity.BatchOperation{valid(), {Action: "upsert"}}, "operations[1].op"},
+		{"create without product", []*entity.BatchOperation{{Action: entity.BatchCreate}}, "operations[0].product"},
+		{"invalid product", []*entity.BatchOperation{{Action: entity.BatchCreate, Product: &entity.Product{Price: 1099}}}, "operations[0].name"},
+		{"update without id", []*entity.BatchOperation{{Action: entity.BatchUpdate, Product: &entity.Product{Name: "Test", Price: 1099}}}, "operations[0].id"},
+		{"negative version", []*entity.BatchOperation{{Action: entity.BatchDelete, ID: 1, Version: -1}}, "operations[0].version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMockRepository()
+			service := New(repo, WithMaxBatchSize(2))
+
+			err := service.Batch(context.Background(), tt.ops, true)
+
+			var verr *entity.ValidationError
+			if !errors.As(err, &verr) || verr.Fields[0].Field != tt.field {
+				t.Errorf("Expected validation error on %s, got %v", tt.field, err)
+			}
+			if len(repo.products) != 0 {
+				t.Errorf("Expected nothing to be stored, got %d products", len(repo.products))
+			}
+		})
+	}
+}
+
+func TestBatch_Modes(t *testing.T) {
+	rec := &countingRecorder{moved: map[string]int{}}
+	repo := NewMockRepository()
+	service := New(repo, WithRecorder(rec))
+	ctx := context.Background()
+
+	ops := []*entity.BatchOperation{
+		{Action: entity.BatchCreate, Product: &entity.Product{SKU: " SKU-1 ", Name: "Test", Price: 1099}},
+		{Action: entity.BatchCreate, Product: &entity.Product{Price: 1099}},
+		{Action: entity.BatchDelete, ID: 42},
+	}
+	if err := service.Batch(ctx, ops, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if ops[0].Err != nil || ops[0].Product.ID == 0 || ops[0].Product.SKU != "SKU-1" {
+		t.Errorf("Expected the first product to be created normalized, got %+v, %v", ops[0].Product, ops[0].Err)
+	}
+	var verr *entity.ValidationError
+	if !errors.As(ops[1].Err, &verr) || verr.Fields[0].Field != "name" {
+		t.Errorf("Expected validation error on name, got %v", ops[1].Err)
+	}
+	if !errors.Is(ops[2].Err, entity.ErrProductNotFound) {
+		t.Errorf("Expected ErrProductNotFound, got %v", ops[2].Err)
+	}
+	if rec.created != 1 {
+		t.Errorf("Expected 1 product created, got %d", rec.created)
+	}
+
+	ops = []*entity.BatchOperation{
+		{Action: entity.BatchCreate, Product: &entity.Product{Name: "Other", Price: 1099}},
+		{Action: entity.BatchDelete, ID: 42},
+	}
+	err := service.Batch(ctx, ops, true)
+	if !errors.Is(err, entity.ErrProductNotFound) || !strings.Contains(err.Error(), "operation 1") {
+		t.Errorf("Expected ErrProductNotFound for operation 1, got %v", err)
+	}
+	if rec.created != 1 {
+		t.Errorf("Expected no products recorded for a failed batch, got %d", rec.created)
+	}
+}
+
+// Тесты для экспорта и импорта
+func TestExport_WalksAllPages(t *testing.T) {
+	repo, _ := memory.New()
+	service := New(repo)
+	ctx := context.Background()
+
+	for i := 0; i < MaxPageSize+5; i++ {
+		service.Create(ctx, &entity.Product{Name: fmt.Sprintf("P%d", i), Price: 100})
+	}
+
+	var got []int64
+	q := entity.ProductQuery{Limit: 5, Offset: 3}
+	err := service.Export(ctx, q, func(p *entity.Product) error {
+		got = append(got, p.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(got) != MaxPageSize+5 || got[0] != 1 || got[len(got)-1] != int64(MaxPageSize+5) {
+		t.Errorf("Expected every product in id order, got %d starting at %v", len(got), got[:1])
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = service.Export(ctx, q, func(*entity.Product) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected the export to stop at the first error, got %v after %d calls", err, calls)
+	}
+
+	err = service.Export(ctx, entity.ProductQuery{SortField: "color"}, func(*entity.Product) error { return nil })
+	var verr *entity.ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+}
+
+// sliceSource отдаёт строки импорта из среза
+type sliceSource struct {
+	rows []*entity.ImportRow
+}
+
+func (s *sliceSource) Next() (*entity.ImportRow, error) {
+	if len(s.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+func importRows(rows ...map[string]string) *sliceSource {
+	src := &sliceSource{}
+	for i, values := range rows {
+		src.rows = append(src.rows, &entity.ImportRow{Number: i + 2, Values: values})
+	}
+	return src
+}
+
+func TestImport_BySKU(t *testing.T) {
+	repo, _ := memory.New()
+	service := New(repo)
+	ctx := context.Background()
+
+	id, _ := service.Create(ctx, &entity.Product{SKU: "MUG-01", Name: "Mug", Description: "kept", Price: 500, Quantity: 3})
+
+	src := func() *sliceSource {
+		s := importRows(
+			map[string]string{"sku": "mug-01", "price": "7.50"},
+			map[string]string{"sku": "NEW-1", "name": "New", "price": "1", "barcodes": "4006381333931; 036000291452"},
+			map[string]string{"sku": "NEW-2", "name": "", "price": "1"},
+			map[string]string{"sku": "new-1", "name": "Again", "price": "1"},
+			map[string]string{"sku": " ", "name": "No SKU", "price": "1"},
+			map[string]string{"sku": "NEW-3", "name": "Bad", "price": "abc"},
+		)
+		s.rows = append(s.rows, &entity.ImportRow{Number: 8, Err: entity.NewValidationError("row", "field_count", "bad row")})
+		return s
+	}
+	wantErrors := []struct {
+		row   int
+		field string
+	}{{4, "name"}, {5, "sku"}, {6, "sku"}, {7, "price"}, {8, "row"}}
+
+	for _, dryRun := range []bool{true, false} {
+		report, err := service.Import(ctx, src(), entity.ImportOptions{Key: entity.ImportBySKU, DryRun: dryRun})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if report.Rows != 7 || report.Created != 1 || report.Updated != 1 || report.Failed != 5 {
+			t.Errorf("Unexpected report (dry run %t): %+v", dryRun, report)
+		}
+		for i, want := range wantErrors {
+			var verr *entity.ValidationError
+			if i >= len(report.Errors) || report.Errors[i].Row != want.row || !errors.As(report.Errors[i].Err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != want.field {
+				t.Errorf("Expected row %d to fail on %s, got %+v", want.row, want.field, report.Errors)
+				break
+			}
+		}
+
+		_, err = service.GetBySKU(ctx, "NEW-1")
+		if dryRun && !errors.Is(err, entity.ErrProductNotFound) {
+			t.Errorf("Expected a dry run to write nothing, got %v", err)
+		}
+	}
+
+	updated, _ := service.GetByID(ctx, id)
+	if updated.Price != 750 || updated.Name != "Mug" || updated.Description != "kept" || updated.Quantity != 3 {
+		t.Errorf("Expected only the price to change, got %+v", updated)
+	}
+	created, err := service.GetBySKU(ctx, "NEW-1")
+	if err != nil || len(created.Barcodes) != 2 {
+		t.Errorf("Expected the new product with two barcodes, got %+v, %v", created, err)
+	}
+}
+
+func TestImport_ByID(t *testing.T) {
+	repo, _ := memory.New()
+	service := New(repo)
+	ctx := context.Background()
+
+	id, _ := service.Create(ctx, &entity.Product{Name: "Old", Price: 500})
+
+	report, err := service.Import(ctx, importRows(
+		map[string]string{"id": fmt.Sprint(id), "name": "Renamed"},
+		map[string]string{"id": "", "name": "Fresh", "price": "2"},
+		map[string]string{"id": "999", "name": "Ghost", "price": "2"},
+		map[string]string{"id": "abc", "name": "Bad", "price": "2"},
+	), entity.ImportOptions{Key: entity.ImportByID})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if report.Created != 1 || report.Updated != 1 || report.Failed != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(report.Errors) != 2 || !errors.Is(report.Errors[0].Err, entity.ErrProductNotFound) {
+		t.Errorf("Expected row 4 not to be found, got %+v", report.Errors)
+	}
+	if p, _ := service.GetByID(ctx, id); p.Name != "Renamed" || p.Price != 500 {
+		t.Errorf("Expected the product renamed, got %+v", p)
+	}
+
+	if _, err := service.Import(ctx, importRows(), entity.ImportOptions{Key: "name"}); err == nil {
+		t.Error("Expected error for an unsupported key, got nil")
+	}
+}
+
+func TestImport_Chunks(t *testing.T) {
+	repo, _ := memory.New()
+	rec := &countingRecorder{moved: map[string]int{}}
+	service := New(repo, WithRecorder(rec))
+
+	rows := make([]map[string]string, importChunkSize*2+1)
+	for i := range rows {
+		rows[i] = map[string]string{"sku": fmt.Sprintf("SKU-%d", i), "name": "P", "price": "1"}
+	}
+
+	report, err := service.Import(context.Background(), importRows(rows...), entity.ImportOptions{Key: entity.ImportBySKU})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Created != len(rows) || report.Failed != 0 || rec.created != len(rows) {
+		t.Errorf("Expected %d products created, got %+v and %d recorded", len(rows), report, rec.created)
+	}
+}
+
+// Тесты для метрик
+type countingRecorder struct {
+	created  int
+	moved    map[string]int
+	rejected int
+}
+
+func (r *countingRecorder) ProductCreated()                { r.created++ }
+func (r *countingRecorder) StockMoved(movementType string) { r.moved[movementType]++ }
+func (r *countingRecorder) StockRejected()                 { r.rejected++ }
+
+func TestService_RecordsDomainEvents(t *testing.T) {
+	rec := &countingRecorder{moved: map[string]int{}}
+	service := New(NewMockRepository(), WithRecorder(rec))
+	ctx := context.Background()
+
+	id, _ := service.Create(ctx, &entity.Product{Name: "Test", Price: 1099, Quantity: 2})
+	service.IncreaseStock(ctx, id, 3, "", "")
+	service.DecreaseStock(ctx, id, 1, "", "")
+	service.DecreaseStock(ctx, id, 100, "", "")
+
+	if rec.created != 1 {
+		t.Errorf("Expected 1 created product, got %d", rec.created)
+	}
+	if rec.moved["receipt"] != 1 || rec.moved["issue"] != 1 {
+		t.Errorf("Expected one receipt and one issue, got %v", rec.moved)
+	}
+	if rec.rejected != 1 {
+		t.Errorf("Expected 1 rejection, got %d", rec.rejected)
 	}
 }
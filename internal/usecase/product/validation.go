@@ -0,0 +1,190 @@
+package product
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+// Limits of the products table. MaxPrice is 99999999.99, the largest value
+// NUMERIC(10,2) can hold.
+const (
+	MaxNameLength        = 255
+	MaxDescriptionLength = 2000
+	MaxBarcodes          = 32
+
+	MaxPrice entity.Money = 9999999999
+
+	// MaxReservationTTL bounds how long stock can be held for an order.
+	MaxReservationTTL = 24 * time.Hour
+)
+
+var (
+	reasonCode = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	// skuCode leaves out the slash so a SKU always fits in one path segment.
+	skuCode = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+)
+
+var (
+	errInvalidID      = entity.NewValidationError("id", "invalid", "invalid id")
+	errInvalidVersion = entity.NewValidationError("version", "invalid", "invalid version")
+	errInvalidSKU     = entity.NewValidationError("sku", "invalid_format", "sku must be 1 to 64 letters, digits, dots, dashes or underscores")
+	errInvalidBarcode = entity.NewValidationError("barcode", "invalid", "barcode must be a UPC-A, EAN-13 or GTIN-14 with a valid check digit")
+)
+
+// validateProduct records every rule p violates. Create, Update and the bulk
+// endpoints all go through it, so the rules cannot drift apart.
+func validateProduct(v *entity.Violations, p *entity.Product) {
+	if p.SKU != "" && !skuCode.MatchString(p.SKU) {
+		v.Add("sku", "invalid_format", errInvalidSKU.Error())
+	}
+
+	if len(p.Barcodes) > MaxBarcodes {
+		v.Add("barcodes", "too_many", fmt.Sprintf("a product can have at most %d barcodes", MaxBarcodes))
+	}
+	seen := make(map[string]bool, len(p.Barcodes))
+	for i, code := range p.Barcodes {
+		field := fmt.Sprintf("barcodes[%d]", i)
+		switch gtin := entity.GTIN14(code); {
+		case !entity.ValidBarcode(code):
+			v.Add(field, "invalid", errInvalidBarcode.Error())
+		case seen[gtin]:
+			v.Add(field, "duplicate", "barcode is listed twice")
+		default:
+			seen[gtin] = true
+		}
+	}
+
+	switch {
+	case strings.TrimSpace(p.Name) == "":
+		v.Add("name", "required", "name is required")
+	case utf8.RuneCountInString(p.Name) > MaxNameLength:
+		v.Add("name", "too_long", fmt.Sprintf("name must be at most %d characters", MaxNameLength))
+	}
+
+	if utf8.RuneCountInString(p.Description) > MaxDescriptionLength {
+		v.Add("description", "too_long", fmt.Sprintf("description must be at most %d characters", MaxDescriptionLength))
+	}
+
+	// A price or quantity that could not be parsed is reported once, not
+	// range-checked as well.
+	switch {
+	case v.Has("price"):
+	case p.Price <= 0:
+		v.Add("price", "must_be_positive", "price must be greater than zero")
+	case p.Price > MaxPrice:
+		v.Add("price", "too_large", "price must not exceed "+MaxPrice.String())
+	}
+
+	// Prices are parsed and stored with MoneyDigits decimals whatever the
+	// currency, which would misprice JPY or KWD.
+	switch digits, ok := entity.CurrencyDigits(p.Currency); {
+	case !ok:
+		v.Add("currency", "invalid", "currency must be an ISO 4217 code")
+	case digits != entity.MoneyDigits:
+		v.Add("currency", "unsupported", fmt.Sprintf("currency %s has %d minor digits, only currencies with %d are supported", p.Currency, digits, entity.MoneyDigits))
+	}
+
+	if p.Quantity < 0 && !v.Has("quantity") {
+		v.Add("quantity", "must_be_non_negative", "quantity must be non-negative")
+	}
+}
+
+func validateBatchOperation(v *entity.Violations, op *entity.BatchOperation) {
+	if !op.Action.Valid() {
+		v.Add("op", "unsupported", "op must be create, update or delete")
+		return
+	}
+
+	if op.Action != entity.BatchCreate {
+		if op.ID <= 0 {
+			v.Add("id", "invalid", "invalid id")
+		}
+		if op.Version < 0 {
+			v.Add("version", "invalid", "invalid version")
+		}
+	}
+
+	if op.Action == entity.BatchDelete {
+		return
+	}
+
+	if op.Product == nil {
+		v.Add("product", "required", "product is required")
+		return
+	}
+	validateProduct(v, op.Product)
+}
+
+func validateMovement(v *entity.Violations, m *entity.StockMovement) {
+	if !m.Type.Valid() {
+		v.Add("type", "unsupported", "invalid movement type")
+	}
+
+	switch {
+	case m.Delta == 0:
+		v.Add("delta", "must_not_be_zero", "delta must not be zero")
+	case m.Type == entity.MovementReceipt && m.Delta < 0:
+		v.Add("delta", "must_be_positive", "receipt delta must be positive")
+	case m.Type == entity.MovementIssue && m.Delta > 0:
+		v.Add("delta", "must_be_negative", "issue delta must be negative")
+	}
+
+	switch {
+	case m.Type == entity.MovementTransfer && m.LocationID == nil:
+		v.Add("location_id", "required", "transfer requires a location")
+	case m.LocationID != nil && *m.LocationID <= 0:
+		v.Add("location_id", "invalid", "invalid location id")
+	}
+
+	if !reasonCode.MatchString(m.Reason) {
+		v.Add("reason", "invalid_format", "reason must be a lowercase code")
+	}
+}
+
+func validateReservation(v *entity.Violations, quantity int, ttl time.Duration) {
+	if quantity <= 0 {
+		v.Add("quantity", "must_be_positive", "quantity must be greater than zero")
+	}
+
+	if ttl <= 0 || ttl > MaxReservationTTL {
+		v.Add("ttl", "out_of_range", fmt.Sprintf("ttl must be greater than zero and at most %s", MaxReservationTTL))
+	}
+}
+
+func validateQuery(v *entity.Violations, q entity.ProductQuery) {
+	if !sortFields[q.SortField] {
+		v.Add("sort", "unsupported", "unsupported sort field")
+	}
+
+	if q.Limit < 1 || q.Limit > MaxPageSize {
+		v.Add("limit", "out_of_range", fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+	}
+	if q.Offset < 0 {
+		v.Add("offset", "must_be_non_negative", "offset must be non-negative")
+	}
+
+	if q.After != nil {
+		switch {
+		case q.Offset > 0:
+			v.Add("cursor", "conflicts_with_offset", "cursor and offset cannot be combined")
+		case q.After.Sort != q.SortField || q.After.Desc != q.SortDesc:
+			v.Add("cursor", "sort_mismatch", "cursor does not match sort order")
+		}
+	}
+
+	f := q.Filter
+	if f.WarehouseID != nil && *f.WarehouseID <= 0 {
+		v.Add("warehouse_id", "invalid", "invalid warehouse id")
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		v.Add("min_price", "invalid_range", "min_price must not exceed max_price")
+	}
+	if f.MinQuantity != nil && f.MaxQuantity != nil && *f.MinQuantity > *f.MaxQuantity {
+		v.Add("min_quantity", "invalid_range", "min_quantity must not exceed max_quantity")
+	}
+}
@@ -0,0 +1,125 @@
+package warehouse
+
+import (
+	"context"
+	"strings"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+var (
+	errInvalidID          = entity.NewValidationError("id", "invalid", "invalid id")
+	errInvalidWarehouseID = entity.NewValidationError("warehouse_id", "invalid", "invalid warehouse id")
+)
+
+type Service struct {
+	repo Repository
+}
+
+func New(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(ctx context.Context, w *entity.Warehouse) (int64, error) {
+	if err := validateWarehouse(w); err != nil {
+		return 0, err
+	}
+
+	return s.repo.Create(ctx, w)
+}
+
+func (s *Service) GetByID(ctx context.Context, id int64) (*entity.Warehouse, error) {
+	if id <= 0 {
+		return nil, errInvalidID
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *Service) Update(ctx context.Context, id int64, w *entity.Warehouse) error {
+	if id <= 0 {
+		return errInvalidID
+	}
+	if err := validateWarehouse(w); err != nil {
+		return err
+	}
+
+	return s.repo.Update(ctx, id, w)
+}
+
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return errInvalidID
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]*entity.Warehouse, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *Service) CreateLocation(ctx context.Context, l *entity.Location) (int64, error) {
+	if l.WarehouseID <= 0 {
+		return 0, errInvalidWarehouseID
+	}
+	if err := validateLocation(l); err != nil {
+		return 0, err
+	}
+
+	return s.repo.CreateLocation(ctx, l)
+}
+
+func (s *Service) GetLocation(ctx context.Context, id int64) (*entity.Location, error) {
+	if id <= 0 {
+		return nil, errInvalidID
+	}
+
+	return s.repo.GetLocation(ctx, id)
+}
+
+func (s *Service) UpdateLocation(ctx context.Context, id int64, l *entity.Location) error {
+	if id <= 0 {
+		return errInvalidID
+	}
+	if err := validateLocation(l); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateLocation(ctx, id, l)
+}
+
+func (s *Service) DeleteLocation(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return errInvalidID
+	}
+
+	return s.repo.DeleteLocation(ctx, id)
+}
+
+func (s *Service) GetLocations(ctx context.Context, warehouseID int64) ([]*entity.Location, error) {
+	if warehouseID <= 0 {
+		return nil, errInvalidWarehouseID
+	}
+
+	return s.repo.GetLocations(ctx, warehouseID)
+}
+
+func validateWarehouse(w *entity.Warehouse) error {
+	var v entity.Violations
+	if strings.TrimSpace(w.Code) == "" {
+		v.Add("code", "required", "code is required")
+	}
+	if strings.TrimSpace(w.Name) == "" {
+		v.Add("name", "required", "name is required")
+	}
+	return v.Err()
+}
+
+func validateLocation(l *entity.Location) error {
+	var v entity.Violations
+	if strings.TrimSpace(l.Code) == "" {
+		v.Add("code", "required", "code is required")
+	}
+	return v.Err()
+}
@@ -0,0 +1,210 @@
+package warehouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/imbafff/product-warehouse-api/internal/entity"
+)
+
+type MockRepository struct {
+	warehouses map[int64]*entity.Warehouse
+	locations  map[int64]*entity.Location
+	nextID     int64
+}
+
+func NewMockRepository() *MockRepository {
+	return &MockRepository{
+		warehouses: make(map[int64]*entity.Warehouse),
+		locations:  make(map[int64]*entity.Location),
+		nextID:     1,
+	}
+}
+
+func (m *MockRepository) Create(ctx context.Context, w *entity.Warehouse) (int64, error) {
+	w.ID = m.nextID
+	m.warehouses[w.ID] = w
+	m.nextID++
+	return w.ID, nil
+}
+
+func (m *MockRepository) GetByID(ctx context.Context, id int64) (*entity.Warehouse, error) {
+	if w, exists := m.warehouses[id]; exists {
+		return w, nil
+	}
+	return nil, entity.ErrWarehouseNotFound
+}
+
+func (m *MockRepository) Update(ctx context.Context, id int64, w *entity.Warehouse) error {
+	if _, exists := m.warehouses[id]; !exists {
+		return entity.ErrWarehouseNotFound
+	}
+	w.ID = id
+	m.warehouses[id] = w
+	return nil
+}
+
+func (m *MockRepository) Delete(ctx context.Context, id int64) error {
+	if _, exists := m.warehouses[id]; !exists {
+		return entity.ErrWarehouseNotFound
+	}
+	for _, l := range m.locations {
+		if l.WarehouseID == id {
+			return entity.ErrWarehouseNotEmpty
+		}
+	}
+	delete(m.warehouses, id)
+	return nil
+}
+
+func (m *MockRepository) GetAll(ctx context.Context) ([]*entity.Warehouse, error) {
+	warehouses := make([]*entity.Warehouse, 0, len(m.warehouses))
+	for _, w := range m.warehouses {
+		warehouses = append(warehouses, w)
+	}
+	return warehouses, nil
+}
+
+func (m *MockRepository) CreateLocation(ctx context.Context, l *entity.Location) (int64, error) {
+	if _, exists := m.warehouses[l.WarehouseID]; !exists {
+		return 0, entity.ErrWarehouseNotFound
+	}
+	l.ID = m.nextID
+	m.locations[l.ID] = l
+	m.nextID++
+	return l.ID, nil
+}
+
+func (m *MockRepository) GetLocation(ctx context.Context, id int64) (*entity.Location, error) {
+	if l, exists := m.locations[id]; exists {
+		return l, nil
+	}
+	return nil, entity.ErrLocationNotFound
+}
+
+func (m *MockRepository) UpdateLocation(ctx context.Context, id int64, l *entity.Location) error {
+	current, exists := m.locations[id]
+	if !exists {
+		return entity.ErrLocationNotFound
+	}
+	l.ID = id
+	l.WarehouseID = current.WarehouseID
+	m.locations[id] = l
+	return nil
+}
+
+func (m *MockRepository) DeleteLocation(ctx context.Context, id int64) error {
+	if _, exists := m.locations[id]; !exists {
+		return entity.ErrLocationNotFound
+	}
+	delete(m.locations, id)
+	return nil
+}
+
+func (m *MockRepository) GetLocations(ctx context.Context, warehouseID int64) ([]*entity.Location, error) {
+	if _, exists := m.warehouses[warehouseID]; !exists {
+		return nil, entity.ErrWarehouseNotFound
+	}
+	locations := []*entity.Location{}
+	for _, l := range m.locations {
+		if l.WarehouseID == warehouseID {
+			locations = append(locations, l)
+		}
+	}
+	return locations, nil
+}
+
+func TestCreate_Success(t *testing.T) {
+	service := New(NewMockRepository())
+
+	id, err := service.Create(context.Background(), &entity.Warehouse{Code: "WH-1", Name: "North"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	w, err := service.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if w.Code != "WH-1" {
+		t.Errorf("Expected code 'WH-1', got %s", w.Code)
+	}
+}
+
+func TestCreate_InvalidData(t *testing.T) {
+	service := New(NewMockRepository())
+
+	testCases := []struct {
+		name      string
+		warehouse *entity.Warehouse
+		errMsg    string
+	}{
+		{name: "empty code", warehouse: &entity.Warehouse{Code: " ", Name: "North"}, errMsg: "code is required"},
+		{name: "empty name", warehouse: &entity.Warehouse{Code: "WH-1"}, errMsg: "name is required"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.Create(context.Background(), tc.warehouse)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			if err.Error() != tc.errMsg {
+				t.Errorf("Expected '%s', got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestDelete_WithLocations(t *testing.T) {
+	service := New(NewMockRepository())
+
+	id, _ := service.Create(context.Background(), &entity.Warehouse{Code: "WH-1", Name: "North"})
+	service.CreateLocation(context.Background(), &entity.Location{WarehouseID: id, Code: "A-01-01"})
+
+	if err := service.Delete(context.Background(), id); err == nil {
+		t.Error("Expected error when deleting a warehouse with locations, got nil")
+	}
+}
+
+func TestCreateLocation_Success(t *testing.T) {
+	service := New(NewMockRepository())
+
+	warehouseID, _ := service.Create(context.Background(), &entity.Warehouse{Code: "WH-1", Name: "North"})
+
+	_, err := service.CreateLocation(context.Background(), &entity.Location{WarehouseID: warehouseID, Code: "A-01-01", Aisle: "A", Bin: "01"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	locations, err := service.GetLocations(context.Background(), warehouseID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(locations) != 1 || locations[0].Aisle != "A" {
+		t.Errorf("Expected the created location, got %v", locations)
+	}
+}
+
+func TestCreateLocation_InvalidData(t *testing.T) {
+	service := New(NewMockRepository())
+
+	if _, err := service.CreateLocation(context.Background(), &entity.Location{Code: "A-01-01"}); err == nil || err.Error() != "invalid warehouse id" {
+		t.Errorf("Expected 'invalid warehouse id', got %v", err)
+	}
+
+	if _, err := service.CreateLocation(context.Background(), &entity.Location{WarehouseID: 1}); err == nil || err.Error() != "code is required" {
+		t.Errorf("Expected 'code is required', got %v", err)
+	}
+}
+
+func TestGetLocations_WarehouseNotFound(t *testing.T) {
+	service := New(NewMockRepository())
+
+	if _, err := service.GetLocations(context.Background(), 999); err == nil {
+		t.Error("Expected error for non-existent warehouse, got nil")
+	}
+}
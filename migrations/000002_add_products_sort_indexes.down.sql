@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_products_quantity_id;
+DROP INDEX IF EXISTS idx_products_price_id;
+DROP INDEX IF EXISTS idx_products_name_id;
@@ -0,0 +1,2 @@
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_quantity_non_negative;
+DROP TABLE IF EXISTS stock_movements;
@@ -0,0 +1,19 @@
+CREATE TABLE stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('receipt', 'issue', 'adjustment', 'transfer')),
+    delta INT NOT NULL CHECK (delta <> 0),
+    balance INT NOT NULL CHECK (balance >= 0),
+    reason TEXT NOT NULL,
+    reference TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_stock_movements_product_id ON stock_movements (product_id, id);
+
+ALTER TABLE products ADD CONSTRAINT products_quantity_non_negative CHECK (quantity >= 0);
+
+INSERT INTO stock_movements (product_id, type, delta, balance, reason)
+SELECT id, 'adjustment', quantity, quantity, 'opening_balance'
+FROM products
+WHERE quantity <> 0;
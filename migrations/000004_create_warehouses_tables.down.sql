@@ -0,0 +1,4 @@
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS location_id;
+DROP TABLE IF EXISTS product_stock;
+DROP TABLE IF EXISTS locations;
+DROP TABLE IF EXISTS warehouses;
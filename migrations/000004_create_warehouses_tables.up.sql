@@ -0,0 +1,26 @@
+CREATE TABLE warehouses (
+    id SERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE locations (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INT NOT NULL REFERENCES warehouses(id),
+    code TEXT NOT NULL,
+    aisle TEXT NOT NULL DEFAULT '',
+    bin TEXT NOT NULL DEFAULT '',
+    UNIQUE (warehouse_id, code)
+);
+
+CREATE TABLE product_stock (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    location_id INT NOT NULL REFERENCES locations(id),
+    quantity INT NOT NULL CHECK (quantity >= 0),
+    PRIMARY KEY (product_id, location_id)
+);
+
+CREATE INDEX idx_product_stock_location_id ON product_stock (location_id);
+
+ALTER TABLE stock_movements ADD COLUMN location_id INT REFERENCES locations(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE products
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD'
+        CHECK (currency ~ '^[A-Z]{3}$');
@@ -0,0 +1,15 @@
+-- Existing products keep a NULL sku until one is assigned.
+ALTER TABLE products ADD COLUMN sku TEXT;
+
+CREATE UNIQUE INDEX products_sku_key ON products (lower(sku));
+
+-- gtin is the barcode padded to 14 digits, so a UPC-A and the equivalent
+-- EAN-13 collide; code is the barcode as it was entered.
+CREATE TABLE product_barcodes (
+    gtin CHAR(14) CONSTRAINT product_barcodes_pkey PRIMARY KEY,
+    code TEXT NOT NULL,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INT NOT NULL
+);
+
+CREATE INDEX idx_product_barcodes_product_id ON product_barcodes (product_id, position);
@@ -0,0 +1,19 @@
+-- reserved is the sum of the active reservations of the product. It is kept
+-- next to quantity so that a single row lock guards both, and the CHECK makes
+-- overselling impossible even for a buggy writer.
+ALTER TABLE products
+    ADD COLUMN reserved INT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT products_reserved_check CHECK (reserved >= 0 AND reserved <= quantity);
+
+CREATE TABLE reservations (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'confirmed', 'released', 'expired')),
+    reference TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_reservations_active_expires_at ON reservations (expires_at) WHERE status = 'active';
+CREATE INDEX idx_reservations_active_product_id ON reservations (product_id) WHERE status = 'active';
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so the binary can apply them
+// without the files being present on disk.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
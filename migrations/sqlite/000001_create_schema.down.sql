@@ -0,0 +1,5 @@
+DROP TABLE stock_movements;
+DROP TABLE product_stock;
+DROP TABLE locations;
+DROP TABLE warehouses;
+DROP TABLE products;
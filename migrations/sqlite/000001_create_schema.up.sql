@@ -0,0 +1,53 @@
+-- Prices are stored in cents: SQLite has no exact decimal type.
+CREATE TABLE products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    price_cents INTEGER NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'USD' CHECK (length(currency) = 3 AND currency = upper(currency)),
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_products_name_id ON products (name, id);
+CREATE INDEX idx_products_price_id ON products (price_cents, id);
+CREATE INDEX idx_products_quantity_id ON products (quantity, id);
+
+CREATE TABLE warehouses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE locations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses(id),
+    code TEXT NOT NULL,
+    aisle TEXT NOT NULL DEFAULT '',
+    bin TEXT NOT NULL DEFAULT '',
+    UNIQUE (warehouse_id, code)
+);
+
+CREATE TABLE product_stock (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    location_id INTEGER NOT NULL REFERENCES locations(id),
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    PRIMARY KEY (product_id, location_id)
+);
+
+CREATE INDEX idx_product_stock_location_id ON product_stock (location_id);
+
+CREATE TABLE stock_movements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL,
+    type TEXT NOT NULL CHECK (type IN ('receipt', 'issue', 'adjustment', 'transfer')),
+    delta INTEGER NOT NULL CHECK (delta <> 0),
+    balance INTEGER NOT NULL CHECK (balance >= 0),
+    reason TEXT NOT NULL,
+    reference TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX idx_stock_movements_product_id ON stock_movements (product_id, id);
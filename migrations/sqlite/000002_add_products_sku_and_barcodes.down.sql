@@ -0,0 +1,3 @@
+DROP TABLE product_barcodes;
+DROP INDEX products_sku_key;
+ALTER TABLE products DROP COLUMN sku;
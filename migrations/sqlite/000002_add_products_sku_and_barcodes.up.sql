@@ -0,0 +1,12 @@
+ALTER TABLE products ADD COLUMN sku TEXT;
+
+CREATE UNIQUE INDEX products_sku_key ON products (lower(sku));
+
+CREATE TABLE product_barcodes (
+    gtin TEXT PRIMARY KEY,
+    code TEXT NOT NULL,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL
+);
+
+CREATE INDEX idx_product_barcodes_product_id ON product_barcodes (product_id, position);
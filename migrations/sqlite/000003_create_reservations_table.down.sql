@@ -0,0 +1,2 @@
+DROP TABLE reservations;
+ALTER TABLE products DROP COLUMN reserved;
@@ -0,0 +1,16 @@
+ALTER TABLE products
+    ADD COLUMN reserved INTEGER NOT NULL DEFAULT 0 CHECK (reserved >= 0 AND reserved <= quantity);
+
+-- Times are stored as UTC text, which compares in time order.
+CREATE TABLE reservations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'confirmed', 'released', 'expired')),
+    reference TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_reservations_active_expires_at ON reservations (expires_at) WHERE status = 'active';
+CREATE INDEX idx_reservations_active_product_id ON reservations (product_id) WHERE status = 'active';
@@ -0,0 +1,10 @@
+// Package sqlite embeds the schema for the SQLite storage. It matches the
+// Postgres migrations in the parent directory but is versioned on its own:
+// 000001 creates the schema as Postgres had it at 000006, and every later
+// change gets a migration here as well.
+package sqlite
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS